@@ -0,0 +1,34 @@
+package app
+
+import "time"
+
+type AdminSetRoleRequest struct {
+	Role string `json:"role" valid:"required~role: role is required"`
+}
+
+// AdminUserUpdateRequest is what PUT /users/:userId changes on the account of
+// another user, the password stays the one of the user.
+type AdminUserUpdateRequest struct {
+	Username string `json:"username" valid:"required~username: username is required"`
+	Email    string `json:"email" valid:"email,required~email: email is required"`
+}
+
+type AdminUserRoleResponse struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+}
+
+type AdminAuditLogResponse struct {
+	ID         uint      `json:"id"`
+	ActorID    uint      `json:"actorId"`
+	Action     string    `json:"action"`
+	Resource   string    `json:"resource"`
+	ResourceID uint      `json:"resourceId"`
+	OwnerID    uint      `json:"ownerId"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Detail     string    `json:"detail"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
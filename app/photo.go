@@ -3,13 +3,14 @@ package app
 import "time"
 
 type PhotoGeneralResponse struct {
-	ID        uint      `json:"id"`
-	Title     string    `json:"title"`
-	Caption   string    `json:"caption"`
-	PhotoUrl  string    `json:"photoUrl"`
-	UserID    uint      `json:"userId"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID        uint       `json:"id"`
+	Title     string     `json:"title"`
+	Caption   string     `json:"caption"`
+	PhotoUrl  string     `json:"photoUrl"`
+	UserID    uint       `json:"userId"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
 }
 
 type FormPhotoCreationRequest struct {
@@ -34,3 +35,24 @@ type PhotoDetailGeneralReponse struct {
 	CreatedAt time.Time            `json:"createdAt"`
 	UpdatedAt time.Time            `json:"updatedAt"`
 }
+
+type PhotoGrantRequest struct {
+	UserID uint   `json:"userId" valid:"required~userId: user id is required"`
+	Access string `json:"access" valid:"required~access: access is required"`
+}
+
+type PhotoGrantResponse struct {
+	ID        uint                 `json:"id"`
+	PhotoID   uint                 `json:"photoId"`
+	User      *UserGeneralResponse `json:"user"`
+	Access    string               `json:"access"`
+	GrantedBy uint                 `json:"grantedBy"`
+	CreatedAt time.Time            `json:"createdAt"`
+	UpdatedAt time.Time            `json:"updatedAt"`
+}
+
+// PhotoSharedResponse is a photo of another user shared with the current user.
+type PhotoSharedResponse struct {
+	PhotoGeneralResponse
+	Access string `json:"access"`
+}
@@ -1,6 +1,9 @@
 package app
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type UserRegisterRequest struct {
 	Username        string `json:"username" valid:"required~username: username is required"`
@@ -23,12 +26,13 @@ type UserUpdateRequest struct {
 }
 
 type UserDetailGeneralResponse struct {
-	ID        uint                    `json:"id"`
-	Username  string                  `json:"username"`
-	Email     string                  `json:"email"`
-	Photos    *[]PhotoGeneralResponse `json:"photos"`
-	CreatedAt time.Time               `json:"createdAt"`
-	UpdatedAt time.Time               `json:"updatedAt"`
+	ID              uint                    `json:"id"`
+	Username        string                  `json:"username"`
+	Email           string                  `json:"email"`
+	EmailVerifiedAt *time.Time              `json:"emailVerifiedAt"`
+	Photos          *[]PhotoGeneralResponse `json:"photos"`
+	CreatedAt       time.Time               `json:"createdAt"`
+	UpdatedAt       time.Time               `json:"updatedAt"`
 }
 
 type UserGeneralResponse struct {
@@ -39,6 +43,127 @@ type UserGeneralResponse struct {
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
+type UserForgotPasswordRequest struct {
+	Email string `json:"email" valid:"email,required~email: email is required"`
+}
+
+type UserResetPasswordRequest struct {
+	Token           string `json:"token" valid:"required~token: token is required"`
+	NewPassword     string `json:"newPassword" valid:"required~newPassword: new password is required,minstringlength(6)~newPassword: new password must be at least 6 characters"`
+	ConfirmPassword string `json:"confirmPassword" valid:"required~confirmPassword: confirm password is required"`
+}
+
+type UserTokenRefreshRequest struct {
+	RefreshToken string `json:"refreshToken" valid:"required~refreshToken: refresh token is required"`
+}
+
 type UserAuthResponse struct {
-	AccessToken string `json:"accessToken"`
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+type UserLoginMFARequest struct {
+	MFAToken     string `json:"mfaToken" valid:"required~mfaToken: mfa token is required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+type UserMFAChallengeResponse struct {
+	MFARequired bool   `json:"mfaRequired"`
+	MFAToken    string `json:"mfaToken"`
+}
+
+type UserTOTPEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauthUri"`
+}
+
+type UserTOTPConfirmRequest struct {
+	Code string `json:"code" valid:"required~code: code is required"`
+}
+
+// UserReauthRequest asks for the password and a second factor again before
+// 2FA settings are changed.
+type UserReauthRequest struct {
+	Password     string `json:"password" valid:"required~password: password is required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+type UserRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// UserWebAuthnBeginResponse holds the options to pass to navigator.credentials
+// and the session token to send back when the ceremony is finished.
+type UserWebAuthnBeginResponse struct {
+	Options      interface{} `json:"options"`
+	SessionToken string      `json:"sessionToken"`
+}
+
+type UserWebAuthnRegisterRequest struct {
+	SessionToken string          `json:"sessionToken" valid:"required~sessionToken: session token is required"`
+	Name         string          `json:"name" valid:"maxstringlength(64)~name: name must be at most 64 characters"`
+	Credential   json.RawMessage `json:"credential"`
+}
+
+type UserWebAuthnLoginRequest struct {
+	SessionToken string          `json:"sessionToken" valid:"required~sessionToken: session token is required"`
+	Credential   json.RawMessage `json:"credential"`
+}
+
+type UserWebAuthnCredentialResponse struct {
+	ID             uint       `json:"id"`
+	Name           string     `json:"name"`
+	Transports     []string   `json:"transports"`
+	BackupEligible bool       `json:"backupEligible"`
+	BackupState    bool       `json:"backupState"`
+	LastUsedAt     *time.Time `json:"lastUsedAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+// UserOIDCBeginResponse holds the page of the identity provider to send the
+// user to and the state token to send back with the code it redirects with.
+type UserOIDCBeginResponse struct {
+	AuthorizationURL string `json:"authorizationUrl"`
+	StateToken       string `json:"stateToken"`
+}
+
+type UserOIDCFinishRequest struct {
+	StateToken string `json:"stateToken" valid:"required~stateToken: state token is required"`
+	State      string `json:"state" valid:"required~state: state is required"`
+	Code       string `json:"code" valid:"required~code: code is required"`
+}
+
+type UserExternalIdentityResponse struct {
+	ID         uint       `json:"id"`
+	Issuer     string     `json:"issuer"`
+	Email      string     `json:"email"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+type UserAPIKeyCreateRequest struct {
+	Name   string   `json:"name" valid:"required~name: name is required,maxstringlength(64)~name: name must be at most 64 characters"`
+	Scopes []string `json:"scopes"`
+	// ExpiresAt is optional, a key without it works until it's revoked.
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+type UserAPIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// UserAPIKeyCreatedResponse is the only response holding the key itself,
+// only its hash is stored.
+type UserAPIKeyCreatedResponse struct {
+	UserAPIKeyResponse
+	Key string `json:"key"`
 }
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+var checkConfigCommand = &command{
+	name:        "check-config",
+	usage:       "check-config [-ping]",
+	description: "validate and print the configuration, secrets are masked",
+	run:         runCheckConfig,
+}
+
+func runCheckConfig(env *Env, args []string) error {
+	flags := newFlagSet("check-config")
+	ping := flags.Bool("ping", false, "also connect to the database")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	masked := *env.Config
+	masked.Database.Password = mask(masked.Database.Password)
+	masked.JWT.Secret = mask(masked.JWT.Secret)
+	masked.Mail.SMTPPassword = mask(masked.Mail.SMTPPassword)
+	masked.OIDC.ClientSecret = mask(masked.OIDC.ClientSecret)
+	out, err := yaml.Marshal(&masked)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(env.Out, string(out))
+
+	if err := env.Config.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	keySet, err := env.Config.JWT.KeySet()
+	if err != nil {
+		return fmt.Errorf("invalid jwt keys: %w", err)
+	}
+	fmt.Fprintf(env.Out, "access tokens are signed with %s", keySet.Active().Method.Alg())
+	if keySet.Active().ID != "" {
+		fmt.Fprintf(env.Out, " by key %s", keySet.Active().ID)
+	}
+	fmt.Fprintln(env.Out)
+	fmt.Fprintln(env.Out, "configuration is valid")
+
+	if *ping {
+		db, err := connect(env.Config)
+		if err != nil {
+			return err
+		}
+		defer db.CloseDB()
+		fmt.Fprintln(env.Out, "database is reachable")
+	}
+	return nil
+}
+
+func mask(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "********"
+}
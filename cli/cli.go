@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/config"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/migrations"
+)
+
+// Env is what every command gets: the validated configuration and, for the
+// commands that need it, a connected database.
+type Env struct {
+	Config *config.Config
+	DB     database.IDatabase
+	Out    io.Writer
+}
+
+type command struct {
+	name        string
+	usage       string
+	description string
+	needsDB     bool
+	// autoMigrate applies the pending migrations first when
+	// DB_MIGRATE_ON_START is set.
+	autoMigrate bool
+	run         func(env *Env, args []string) error
+}
+
+var commands = []*command{
+	serveCommand,
+	migrateCommand,
+	seedCommand,
+	userCommand,
+	checkConfigCommand,
+}
+
+// Run parses the global flags, picks the subcommand (serve when none is
+// given) and runs it. It returns the process exit code.
+func Run(args []string) int {
+	cfg, rest, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		usage(os.Stderr)
+		return 0
+	}
+	if err != nil {
+		log.Printf("Error loading configuration: %s", err.Error())
+		return 1
+	}
+
+	name := "serve"
+	if len(rest) > 0 {
+		name, rest = rest[0], rest[1:]
+	}
+	if name == "help" {
+		usage(os.Stdout)
+		return 0
+	}
+
+	var cmd *command
+	for _, c := range commands {
+		if c.name == name {
+			cmd = c
+		}
+	}
+	if cmd == nil {
+		log.Printf("Unknown command %q", name)
+		usage(os.Stderr)
+		return 2
+	}
+
+	env := &Env{Config: cfg, Out: os.Stdout}
+	if cmd.needsDB {
+		if err := cfg.Validate(); err != nil {
+			log.Printf("Invalid configuration:\n%s", err.Error())
+			return 1
+		}
+		env.DB, err = connect(cfg)
+		if err != nil {
+			log.Printf("Error connecting to database: %s", err.Error())
+			return 1
+		}
+		defer env.DB.CloseDB()
+
+		if cmd.autoMigrate && cfg.Database.MigrateOnStart {
+			_, err := migrations.NewMigrator(env.DB).Up()
+			if err != nil {
+				log.Printf("Error applying migrations: %s", err.Error())
+				return 1
+			}
+		}
+	}
+
+	err = cmd.run(env, rest)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		log.Printf("%s: %s", cmd.name, err.Error())
+		return 1
+	}
+	return 0
+}
+
+func connect(cfg *config.Config) (database.IDatabase, error) {
+	dbConfig, err := cfg.Database.ToDatabaseConfig()
+	if err != nil {
+		return nil, err
+	}
+	db, err := database.NewDB(dbConfig)
+	if err != nil {
+		return nil, err
+	}
+	err = db.ConnectWithRetry(cfg.Database.RetryPolicy())
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func usage(output io.Writer) {
+	fmt.Fprintln(output, "Usage: app [flags] [command] [arguments]")
+	fmt.Fprintln(output, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(output, "  %-44s %s\n", c.usage, c.description)
+	}
+	fmt.Fprintln(output, "\nFlags:")
+	config.Usage(output)
+}
@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/app"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"gorm.io/gorm"
+)
+
+var seedCommand = &command{
+	name:        "seed",
+	usage:       "seed [-host host] [-password password]",
+	description: "create demo users with a few photos each",
+	needsDB:     true,
+	autoMigrate: true,
+	run:         runSeed,
+}
+
+type seedUser struct {
+	username string
+	email    string
+	photos   []seedPhoto
+}
+
+type seedPhoto struct {
+	title   string
+	caption string
+	color   color.RGBA
+}
+
+var seedUsers = []seedUser{
+	{
+		username: "alice",
+		email:    "alice@example.com",
+		photos: []seedPhoto{
+			{title: "Sunrise", caption: "Morning over the hills", color: color.RGBA{R: 240, G: 160, B: 60, A: 255}},
+			{title: "Sea", caption: "A calm day at the beach", color: color.RGBA{R: 40, G: 110, B: 200, A: 255}},
+		},
+	},
+	{
+		username: "bob",
+		email:    "bob@example.com",
+		photos: []seedPhoto{
+			{title: "Forest", caption: "Walking between the pines", color: color.RGBA{R: 40, G: 140, B: 70, A: 255}},
+		},
+	},
+}
+
+// runSeed creates the demo users that don't exist yet, running it twice
+// doesn't duplicate anything.
+func runSeed(env *Env, args []string) error {
+	flags := newFlagSet("seed")
+	host := flags.String("host", seedHost(env.Config.Server.Address), "host used in the photo urls")
+	password := flags.String("password", "password", "password of every demo user")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	hashedPassword, err := env.Config.Password.Hasher().HashString(*password)
+	if err != nil {
+		return err
+	}
+	storage := helpers.NewStorage(env.Config.Storage.PhotoDir)
+
+	for _, seed := range seedUsers {
+		primaryModel := models.NewUserModel(env.DB.Primary())
+		_, err := primaryModel.GetByEmail(seed.email, false)
+		if err == nil {
+			fmt.Fprintf(env.Out, "skipped %s, already exists\n", seed.email)
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		_, err = primaryModel.GetTrashedByEmail(seed.email)
+		if err == nil {
+			fmt.Fprintf(env.Out, "skipped %s, belongs to a deleted user\n", seed.email)
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		var savedFiles []string
+		err = env.DB.WithTransaction(func(tx database.IDatabase) error {
+			user, err := models.NewUserModel(tx).CreateUser(&app.UserRegisterRequest{
+				Username: seed.username,
+				Email:    seed.email,
+				Password: hashedPassword,
+			})
+			if err != nil {
+				return err
+			}
+			if _, err := models.NewUserModel(tx).MarkEmailVerified(user); err != nil {
+				return err
+			}
+
+			for _, photo := range seed.photos {
+				filename := fmt.Sprintf("photos_%d_%d_%s.png", user.ID, time.Now().Unix(), strings.ToLower(photo.title))
+				if err := storage.Write(filename, solidImage(photo.color)); err != nil {
+					return err
+				}
+				savedFiles = append(savedFiles, filename)
+
+				_, err := models.NewPhotoModel(tx).CreatePhoto(&app.FormPhotoCreationRequest{
+					Title:    photo.title,
+					Caption:  photo.caption,
+					PhotoUrl: fmt.Sprintf("http://%s/public/%s", *host, filename),
+					UserID:   user.ID,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			for _, filename := range savedFiles {
+				storage.Remove(filename)
+			}
+			return err
+		}
+		fmt.Fprintf(env.Out, "created %s with %d photos\n", seed.email, len(seed.photos))
+	}
+
+	fmt.Fprintf(env.Out, "demo users log in with the password %q\n", *password)
+	return nil
+}
+
+// seedHost turns the listen address into a host for the photo urls, e.g.
+// :8080 becomes localhost:8080.
+func seedHost(address string) string {
+	if strings.HasPrefix(address, ":") {
+		return "localhost" + address
+	}
+	return address
+}
+
+func solidImage(fill color.RGBA) *bytes.Buffer {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 64; y++ {
+			img.SetRGBA(x, y, fill)
+		}
+	}
+	buf := &bytes.Buffer{}
+	png.Encode(buf, img)
+	return buf
+}
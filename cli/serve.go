@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os/signal"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/jobs"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/router"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/stores"
+)
+
+var serveCommand = &command{
+	name:        "serve",
+	usage:       "serve",
+	description: "start the HTTP server (default)",
+	needsDB:     true,
+	autoMigrate: true,
+	run:         runServe,
+}
+
+// runServe runs the HTTP server until SIGINT or SIGTERM, then stops accepting
+// connections, waits up to the shutdown timeout for in-flight requests and
+// stops the background jobs. The database is closed by Run.
+func runServe(env *Env, args []string) error {
+	cfg, db := env.Config, env.DB
+
+	db.StartHealthCheck(cfg.Database.HealthCheckInterval)
+
+	purgeJob := jobs.NewPurgeJob(db, helpers.NewStorage(cfg.Storage.PhotoDir), cfg.Trash.Retention, cfg.JWT.ClockSkew)
+	purgeJob.Start(cfg.Trash.PurgeInterval)
+	defer purgeJob.Stop()
+
+	revocationStore := stores.NewRevocationStore(models.NewTokenRevocationModel(db.Primary()), cfg.JWT.AccessTokenLifetime(), cfg.JWT.ClockSkew)
+	if err := revocationStore.Load(); err != nil {
+		return err
+	}
+	revocationStore.Start(cfg.JWT.RevocationSyncInterval)
+	defer revocationStore.Stop()
+
+	loginThrottle := cfg.LoginThrottle.LoginThrottle(db.Primary())
+	loginThrottle.Start(cfg.LoginThrottle.Window)
+	defer loginThrottle.Stop()
+
+	keySet, err := cfg.JWT.KeySet()
+	if err != nil {
+		return err
+	}
+	webToken := helpers.NewWebToken(cfg.JWT.ExpirationMinutes, keySet, cfg.JWT.Issuer, cfg.JWT.Audience, cfg.JWT.ClockSkew)
+
+	verificationToken := helpers.NewEmailVerificationToken(keySet, cfg.JWT.Issuer, cfg.EmailVerification.TokenLifetime)
+	mfaChallenge := helpers.NewMFAChallengeToken(keySet, cfg.JWT.Issuer, cfg.MFA.ChallengeLifetime)
+	webAuthnSession := helpers.NewWebAuthnSessionToken(keySet, cfg.JWT.Issuer, cfg.WebAuthn.SessionLifetime)
+
+	relyingParty, err := cfg.WebAuthn.WebAuthn()
+	if err != nil {
+		return err
+	}
+
+	var oidcProvider helpers.IOIDCProvider
+	if cfg.OIDC.Enabled() {
+		oidcProvider = cfg.OIDC.Provider()
+	}
+	oidcState := helpers.NewOIDCStateToken(keySet, cfg.JWT.Issuer, cfg.OIDC.StateLifetime)
+
+	mailSender, err := cfg.Mail.Mailer()
+	if err != nil {
+		return err
+	}
+	resetQueue := jobs.NewPasswordResetQueue(db, helpers.NewOpaqueToken(cfg.Password.ResetTokenLifetime), mailSender, cfg.Password.ResetURL)
+	resetQueue.Start()
+	defer resetQueue.Stop()
+
+	app := gin.Default()
+	// Without trusted proxies the client address is the one of the connection, not X-Forwarded-For
+	if err := app.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return err
+	}
+	router.RouteApp(app, db, cfg, webToken, verificationToken, mfaChallenge, relyingParty, webAuthnSession, oidcProvider, oidcState, mailSender, resetQueue, revocationStore, loginThrottle)
+
+	server := &http.Server{
+		Addr:           cfg.Server.Address,
+		Handler:        app,
+		ReadTimeout:    cfg.Server.ReadTimeout,
+		WriteTimeout:   cfg.Server.WriteTimeout,
+		IdleTimeout:    cfg.Server.IdleTimeout,
+		MaxHeaderBytes: cfg.Server.MaxHeaderBytes,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Listening and serving HTTP on %s", server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		return err
+	case <-ctx.Done():
+		stop()
+	}
+
+	log.Printf("Shutting down, waiting up to %s for in-flight requests", cfg.Server.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	err = server.Shutdown(shutdownCtx)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("Error shutting down the server: %s", err.Error())
+	}
+	log.Print("Server stopped")
+	return nil
+}
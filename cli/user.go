@@ -0,0 +1,292 @@
+package cli
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/app"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/stores"
+	"gorm.io/gorm"
+)
+
+var userCommand = &command{
+	name:        "user",
+	usage:       "user create|disable|enable|set-role|unlock|reset-password|disable-2fa",
+	description: "manage user accounts, run user <command> -h for the flags",
+	needsDB:     true,
+	autoMigrate: true,
+	run:         runUser,
+}
+
+func runUser(env *Env, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: user create|disable|enable|set-role|unlock|reset-password|disable-2fa [flags]")
+	}
+
+	userModel := models.NewUserModel(env.DB.Primary())
+	switch args[0] {
+	case "create":
+		return runUserCreate(env, userModel, args[1:])
+	case "disable":
+		return runUserSetDisabled(env, userModel, args[1:], true)
+	case "enable":
+		return runUserSetDisabled(env, userModel, args[1:], false)
+	case "set-role":
+		return runUserSetRole(env, userModel, args[1:])
+	case "unlock":
+		return runUserUnlock(env, userModel, args[1:])
+	case "reset-password":
+		return runUserResetPassword(env, userModel, args[1:])
+	case "disable-2fa":
+		return runUserDisableTwoFactor(env, userModel, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q, expected create, disable, enable, set-role, unlock, reset-password or disable-2fa", args[0])
+	}
+}
+
+func runUserCreate(env *Env, userModel models.IUserModel, args []string) error {
+	flags := newFlagSet("user create")
+	username := flags.String("username", "", "username of the new user")
+	email := flags.String("email", "", "email of the new user")
+	password := flags.String("password", "", "password of the new user, a random one is generated and printed when empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	generated := *password == ""
+	if generated {
+		var err error
+		if *password, err = generatePassword(); err != nil {
+			return err
+		}
+	}
+
+	registerRequest := &app.UserRegisterRequest{
+		Username:        *username,
+		Email:           *email,
+		Password:        *password,
+		ConfirmPassword: *password,
+	}
+	if errorMessages, err := helpers.NewValidator().Validate(registerRequest); err != nil {
+		return fmt.Errorf("invalid user: %v", errorMessages)
+	}
+
+	_, err := userModel.GetByEmail(registerRequest.Email, false)
+	if err == nil {
+		return fmt.Errorf("email %s is already taken", registerRequest.Email)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	_, err = userModel.GetTrashedByEmail(registerRequest.Email)
+	if err == nil {
+		return fmt.Errorf("email %s belongs to a deleted user, restore it instead", registerRequest.Email)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	registerRequest.Password, err = env.Config.Password.Hasher().HashString(*password)
+	if err != nil {
+		return err
+	}
+	user, err := userModel.CreateUser(registerRequest)
+	if err != nil {
+		return err
+	}
+	// An operator creating the account vouches for the address
+	if _, err := userModel.MarkEmailVerified(user); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(env.Out, "created user %d (%s)\n", user.ID, user.Email)
+	if generated {
+		fmt.Fprintf(env.Out, "password: %s\n", *password)
+	}
+	return nil
+}
+
+func runUserSetDisabled(env *Env, userModel models.IUserModel, args []string, disabled bool) error {
+	name := "user enable"
+	if disabled {
+		name = "user disable"
+	}
+	flags := newFlagSet(name)
+	email := flags.String("email", "", "email of the user")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	user, err := findUser(userModel, *email)
+	if err != nil {
+		return err
+	}
+	if _, err := userModel.SetDisabled(user, disabled); err != nil {
+		return err
+	}
+
+	if disabled {
+		fmt.Fprintf(env.Out, "disabled user %d (%s)\n", user.ID, user.Email)
+	} else {
+		fmt.Fprintf(env.Out, "enabled user %d (%s)\n", user.ID, user.Email)
+	}
+	return nil
+}
+
+// runUserSetRole is how the first admin is made, later ones can be promoted
+// with PUT /admin/users/:userId/role.
+func runUserSetRole(env *Env, userModel models.IUserModel, args []string) error {
+	flags := newFlagSet("user set-role")
+	email := flags.String("email", "", "email of the user")
+	role := flags.String("role", "", fmt.Sprintf("the new role, one of %v", models.Roles()))
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if !models.IsRole(*role) {
+		return fmt.Errorf("unknown role %q, expected one of %v", *role, models.Roles())
+	}
+
+	user, err := findUser(userModel, *email)
+	if err != nil {
+		return err
+	}
+	if _, err := userModel.SetRole(user, *role); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(env.Out, "user %d (%s) is now %s\n", user.ID, user.Email, user.Role)
+	return nil
+}
+
+// runUserUnlock only reaches the servers sharing the failed logins through the
+// database, the memory store lives in each server.
+func runUserUnlock(env *Env, userModel models.IUserModel, args []string) error {
+	flags := newFlagSet("user unlock")
+	email := flags.String("email", "", "email of the user")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if env.Config.LoginThrottle.Store != stores.LoginStoreDatabase {
+		return fmt.Errorf("failed logins are kept in the memory of the servers, use POST /admin/users/:userId/unlock instead")
+	}
+
+	user, err := findUser(userModel, *email)
+	if err != nil {
+		return err
+	}
+	if err := env.Config.LoginThrottle.LoginThrottle(env.DB.Primary()).Unlock(user.ID, user.Email); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(env.Out, "login of user %d (%s) has been unlocked\n", user.ID, user.Email)
+	return nil
+}
+
+func runUserResetPassword(env *Env, userModel models.IUserModel, args []string) error {
+	flags := newFlagSet("user reset-password")
+	email := flags.String("email", "", "email of the user")
+	password := flags.String("password", "", "the new password, a random one is generated and printed when empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	generated := *password == ""
+	if generated {
+		var err error
+		if *password, err = generatePassword(); err != nil {
+			return err
+		}
+	}
+	if len(*password) < 6 {
+		return fmt.Errorf("password must be at least 6 characters")
+	}
+
+	user, err := findUser(userModel, *email)
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := env.Config.Password.Hasher().HashString(*password)
+	if err != nil {
+		return err
+	}
+	if _, err := userModel.UpdatePassword(user, hashedPassword); err != nil {
+		return err
+	}
+
+	// The running servers pick the revocation up on their next sync.
+	revocationStore := stores.NewRevocationStore(models.NewTokenRevocationModel(env.DB.Primary()), env.Config.JWT.AccessTokenLifetime(), env.Config.JWT.ClockSkew)
+	if err := revocationStore.RevokeAllForUser(user.ID); err != nil {
+		return err
+	}
+	if err := models.NewRefreshTokenModel(env.DB.Primary()).RevokeByUser(user.ID); err != nil {
+		return err
+	}
+	if env.Config.LoginThrottle.Store == stores.LoginStoreDatabase {
+		if err := env.Config.LoginThrottle.LoginThrottle(env.DB.Primary()).Unlock(user.ID, user.Email); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(env.Out, "password of user %d (%s) has been reset and their tokens revoked\n", user.ID, user.Email)
+	if generated {
+		fmt.Fprintf(env.Out, "password: %s\n", *password)
+	}
+	return nil
+}
+
+// runUserDisableTwoFactor is for users who lost both their authenticator and
+// their recovery codes, once their identity has been checked.
+func runUserDisableTwoFactor(env *Env, userModel models.IUserModel, args []string) error {
+	flags := newFlagSet("user disable-2fa")
+	email := flags.String("email", "", "email of the user")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	user, err := findUser(userModel, *email)
+	if err != nil {
+		return err
+	}
+	err = env.DB.WithTransaction(func(tx database.IDatabase) error {
+		if err := models.NewRecoveryCodeModel(tx).DeleteByUser(user.ID); err != nil {
+			return err
+		}
+		return models.NewTOTPModel(tx).DeleteByUser(user.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(env.Out, "two-factor authentication of user %d (%s) has been disabled\n", user.ID, user.Email)
+	return nil
+}
+
+func findUser(userModel models.IUserModel, email string) (*models.User, error) {
+	if email == "" {
+		return nil, fmt.Errorf("-email is required")
+	}
+	user, err := userModel.GetByEmail(email, false)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("user with email %s not found", email)
+	}
+	return user, err
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet(name, flag.ContinueOnError)
+}
+
+// generatePassword returns 16 random bytes encoded as URL safe base64.
+func generatePassword() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate password: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
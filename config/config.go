@@ -0,0 +1,627 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	netmail "net/mail"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/mailer"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/stores"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinJWTSecretLength is the shortest secret accepted for HS256, shorter
+// secrets can be brute forced from a single captured token.
+const MinJWTSecretLength = 32
+
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	JWT      JWTConfig      `yaml:"jwt"`
+	Storage  StorageConfig  `yaml:"storage"`
+	Trash    TrashConfig    `yaml:"trash"`
+	Mail     MailConfig     `yaml:"mail"`
+	Password PasswordConfig `yaml:"password"`
+
+	LoginThrottle LoginThrottleConfig `yaml:"loginThrottle"`
+
+	EmailVerification EmailVerificationConfig `yaml:"emailVerification"`
+
+	MFA MFAConfig `yaml:"mfa"`
+
+	WebAuthn WebAuthnConfig `yaml:"webauthn"`
+
+	OIDC OIDCConfig `yaml:"oidc"`
+}
+
+type ServerConfig struct {
+	Address         string        `yaml:"address"`
+	ReadTimeout     time.Duration `yaml:"readTimeout"`
+	WriteTimeout    time.Duration `yaml:"writeTimeout"`
+	IdleTimeout     time.Duration `yaml:"idleTimeout"`
+	MaxHeaderBytes  int           `yaml:"maxHeaderBytes"`
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+	// PublicURL is where clients reach the API, used in the links sent by email.
+	PublicURL string `yaml:"publicUrl"`
+	// TrustedProxies are the addresses or CIDR ranges of the reverse proxies
+	// whose X-Forwarded-For is believed. When empty the client address is the
+	// one of the connection, so clients can't pick the address logins are
+	// throttled by.
+	TrustedProxies []string `yaml:"trustedProxies"`
+}
+
+type DatabaseConfig struct {
+	Driver              string        `yaml:"driver"`
+	Host                string        `yaml:"host"`
+	Port                int           `yaml:"port"`
+	Username            string        `yaml:"username"`
+	Password            string        `yaml:"password"`
+	Name                string        `yaml:"name"`
+	Path                string        `yaml:"path"`
+	TLSMode             string        `yaml:"tlsMode"`
+	TimeZone            string        `yaml:"timeZone"`
+	MaxOpenConns        int           `yaml:"maxOpenConns"`
+	MaxIdleConns        int           `yaml:"maxIdleConns"`
+	ConnMaxLifetime     time.Duration `yaml:"connMaxLifetime"`
+	ReplicaHosts        []string      `yaml:"replicaHosts"`
+	ConnectAttempts     int           `yaml:"connectAttempts"`
+	ConnectBackoff      time.Duration `yaml:"connectBackoff"`
+	ConnectMaxBackoff   time.Duration `yaml:"connectMaxBackoff"`
+	HealthCheckInterval time.Duration `yaml:"healthCheckInterval"`
+	MigrateOnStart      bool          `yaml:"migrateOnStart"`
+}
+
+type JWTConfig struct {
+	Secret string `yaml:"secret"`
+	// SigningKeyFile is a PEM RSA or Ed25519 private key, tokens are signed
+	// with HS256 and Secret when it's empty.
+	SigningKeyFile string `yaml:"signingKeyFile"`
+	// VerifyKeyFiles are previous signing keys, private or public, their
+	// tokens keep being accepted until the keys are removed from the list.
+	VerifyKeyFiles []string `yaml:"verifyKeyFiles"`
+	// Issuer and Audience are set as iss and aud of the access tokens, tokens
+	// with other values are refused.
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+	// ClockSkew is the leeway given on exp, nbf and iat for tokens verified on
+	// a machine whose clock is slightly off.
+	ClockSkew         time.Duration `yaml:"clockSkew"`
+	ExpirationMinutes int           `yaml:"expirationMinutes"`
+	RefreshExpiration time.Duration `yaml:"refreshExpiration"`
+	// RevocationSyncInterval is how often revocations made by other instances
+	// or by the CLI are loaded into the in-memory revocation cache.
+	RevocationSyncInterval time.Duration `yaml:"revocationSyncInterval"`
+}
+
+type StorageConfig struct {
+	PhotoDir string `yaml:"photoDir"`
+}
+
+type TrashConfig struct {
+	Retention     time.Duration `yaml:"retention"`
+	PurgeInterval time.Duration `yaml:"purgeInterval"`
+}
+
+type MailConfig struct {
+	Driver       string `yaml:"driver"`
+	From         string `yaml:"from"`
+	FileDir      string `yaml:"fileDir"`
+	SMTPHost     string `yaml:"smtpHost"`
+	SMTPPort     int    `yaml:"smtpPort"`
+	SMTPUsername string `yaml:"smtpUsername"`
+	SMTPPassword string `yaml:"smtpPassword"`
+}
+
+type EmailVerificationConfig struct {
+	TokenLifetime time.Duration `yaml:"tokenLifetime"`
+}
+
+type MFAConfig struct {
+	// Issuer is the account name prefix shown by authenticator apps.
+	Issuer            string        `yaml:"issuer"`
+	ChallengeLifetime time.Duration `yaml:"challengeLifetime"`
+	// MaxAttempts is how many invalid codes in a row revoke the login
+	// challenge, the count goes on with the next challenge until a valid code.
+	// Codes are also throttled per user like the logins.
+	MaxAttempts int `yaml:"maxAttempts"`
+}
+
+type WebAuthnConfig struct {
+	// RPID is the domain passkeys are bound to, the host of the client without
+	// scheme and port.
+	RPID          string `yaml:"rpId"`
+	RPDisplayName string `yaml:"rpDisplayName"`
+	// RPOrigins are the origins of the clients allowed to run the ceremonies.
+	RPOrigins       []string      `yaml:"rpOrigins"`
+	SessionLifetime time.Duration `yaml:"sessionLifetime"`
+}
+
+// OIDCConfig is the OpenID Connect provider users can sign in with, the
+// login is disabled while IssuerURL is empty.
+type OIDCConfig struct {
+	IssuerURL    string `yaml:"issuerUrl"`
+	ClientID     string `yaml:"clientId"`
+	ClientSecret string `yaml:"clientSecret"`
+	// RedirectURL is the page of the client the provider sends the user back
+	// to with the code, it must be registered at the provider.
+	RedirectURL string   `yaml:"redirectUrl"`
+	Scopes      []string `yaml:"scopes"`
+	// Provision creates a user on the first login of an identity that isn't
+	// linked to one yet.
+	Provision     bool          `yaml:"provision"`
+	StateLifetime time.Duration `yaml:"stateLifetime"`
+}
+
+type PasswordConfig struct {
+	ResetTokenLifetime time.Duration `yaml:"resetTokenLifetime"`
+	// ResetURL is the page of the client where the user picks a new password,
+	// the reset email links to it with ?token=<token> when it's set.
+	ResetURL string `yaml:"resetUrl"`
+	// Hash is the algorithm new passwords are hashed with, argon2id or bcrypt.
+	// Hashes made with the other algorithm or other costs are upgraded when
+	// their user logs in.
+	Hash       string `yaml:"hash"`
+	BcryptCost int    `yaml:"bcryptCost"`
+	// Argon2Memory is in KiB, every login being checked holds that much memory.
+	Argon2Memory      int `yaml:"argon2Memory"`
+	Argon2Iterations  int `yaml:"argon2Iterations"`
+	Argon2Parallelism int `yaml:"argon2Parallelism"`
+}
+
+// LoginThrottleConfig slows down and locks out the emails and addresses
+// failing to log in.
+type LoginThrottleConfig struct {
+	// Store is memory for a single instance, or database to share the failed
+	// attempts between the instances.
+	Store           string        `yaml:"store"`
+	MaxAttempts     int           `yaml:"maxAttempts"`
+	IPMaxAttempts   int           `yaml:"ipMaxAttempts"`
+	LockoutDuration time.Duration `yaml:"lockoutDuration"`
+	BaseDelay       time.Duration `yaml:"baseDelay"`
+	MaxDelay        time.Duration `yaml:"maxDelay"`
+	// Window is how long failures are remembered after the last one.
+	Window time.Duration `yaml:"window"`
+	// ResetMaxRequests is how many password resets an email, or verification
+	// emails a user, can request within Window, ResetIPMaxRequests the same for
+	// an address.
+	ResetMaxRequests   int `yaml:"resetMaxRequests"`
+	ResetIPMaxRequests int `yaml:"resetIpMaxRequests"`
+}
+
+func Default() *Config {
+	retryPolicy := database.DefaultRetryPolicy()
+	return &Config{
+		Server: ServerConfig{
+			Address:         ":8080",
+			ReadTimeout:     30 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     2 * time.Minute,
+			MaxHeaderBytes:  1 << 20,
+			ShutdownTimeout: 30 * time.Second,
+			PublicURL:       "http://localhost:8080",
+		},
+		Database: DatabaseConfig{
+			Driver:              database.DriverMySQL,
+			ConnectAttempts:     retryPolicy.MaxAttempts,
+			ConnectBackoff:      retryPolicy.InitialBackoff,
+			ConnectMaxBackoff:   retryPolicy.MaxBackoff,
+			HealthCheckInterval: 15 * time.Second,
+		},
+		JWT: JWTConfig{
+			Issuer:                 "photo-api",
+			Audience:               "photo-api",
+			ClockSkew:              30 * time.Second,
+			RefreshExpiration:      30 * 24 * time.Hour,
+			RevocationSyncInterval: 30 * time.Second,
+		},
+		Storage: StorageConfig{
+			PhotoDir: "./static/photos",
+		},
+		Trash: TrashConfig{
+			Retention:     30 * 24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		Mail: MailConfig{
+			Driver:   mailer.DriverLog,
+			From:     "Photo API <no-reply@localhost>",
+			FileDir:  "./mails",
+			SMTPPort: 587,
+		},
+		Password: PasswordConfig{
+			ResetTokenLifetime: time.Hour,
+			Hash:               helpers.HashArgon2id,
+			BcryptCost:         12,
+			Argon2Memory:       64 * 1024,
+			Argon2Iterations:   3,
+			Argon2Parallelism:  4,
+		},
+		LoginThrottle: LoginThrottleConfig{
+			Store:              stores.LoginStoreMemory,
+			MaxAttempts:        5,
+			IPMaxAttempts:      50,
+			LockoutDuration:    15 * time.Minute,
+			BaseDelay:          time.Second,
+			MaxDelay:           30 * time.Second,
+			Window:             time.Hour,
+			ResetMaxRequests:   3,
+			ResetIPMaxRequests: 20,
+		},
+		EmailVerification: EmailVerificationConfig{
+			TokenLifetime: 24 * time.Hour,
+		},
+		MFA: MFAConfig{
+			Issuer:            "Photo API",
+			ChallengeLifetime: 5 * time.Minute,
+			MaxAttempts:       5,
+		},
+		WebAuthn: WebAuthnConfig{
+			RPID:            "localhost",
+			RPDisplayName:   "Photo API",
+			RPOrigins:       []string{"http://localhost:8080"},
+			SessionLifetime: 5 * time.Minute,
+		},
+		OIDC: OIDCConfig{
+			Scopes:        []string{"openid", "email", "profile"},
+			Provision:     true,
+			StateLifetime: 10 * time.Minute,
+		},
+	}
+}
+
+// Validate checks the whole configuration and reports every problem at once.
+func (config *Config) Validate() error {
+	errs := []error{}
+	invalid := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	server := config.Server
+	if server.Address == "" {
+		invalid("server.address (SERVER_ADDRESS) is required")
+	}
+	if server.ReadTimeout < 0 || server.WriteTimeout < 0 || server.IdleTimeout < 0 {
+		invalid("server timeouts can't be negative, use 0 to disable them")
+	}
+	if server.MaxHeaderBytes <= 0 {
+		invalid("server.maxHeaderBytes (SERVER_MAX_HEADER_BYTES) must be positive")
+	}
+	if server.ShutdownTimeout <= 0 {
+		invalid("server.shutdownTimeout (SERVER_SHUTDOWN_TIMEOUT) must be positive")
+	}
+	if publicURL, err := url.Parse(server.PublicURL); err != nil || (publicURL.Scheme != "http" && publicURL.Scheme != "https") || publicURL.Host == "" {
+		invalid("server.publicUrl (SERVER_PUBLIC_URL) must be an http or https url")
+	}
+	for _, proxy := range server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			invalid("server.trustedProxies (SERVER_TRUSTED_PROXIES) must be addresses or CIDR ranges, got %q", proxy)
+		}
+	}
+
+	db := config.Database
+	switch db.Driver {
+	case database.DriverMySQL, database.DriverPostgres:
+		if db.Name == "" {
+			invalid("database.name (DB_NAME) is required for %s", db.Driver)
+		}
+		if db.Username == "" {
+			invalid("database.username (DB_UNAME) is required for %s", db.Driver)
+		}
+	case database.DriverSQLite:
+		if len(db.ReplicaHosts) != 0 {
+			invalid("database.replicaHosts (DB_REPLICA_HOSTS) is not supported for sqlite")
+		}
+	default:
+		invalid("database.driver (DB_DRIVER) must be one of mysql, postgres or sqlite, got %q", db.Driver)
+	}
+	if db.Port < 0 || db.Port > 65535 {
+		invalid("database.port (DB_PORT) must be between 1 and 65535, or 0 for the default port of the driver")
+	}
+	if db.MaxOpenConns < 0 || db.MaxIdleConns < 0 || db.ConnMaxLifetime < 0 {
+		invalid("database connection pool limits can't be negative")
+	}
+	if db.ConnectAttempts < 0 {
+		invalid("database.connectAttempts (DB_CONNECT_ATTEMPTS) can't be negative")
+	}
+	if db.ConnectBackoff <= 0 || db.ConnectMaxBackoff <= 0 {
+		invalid("database.connectBackoff and database.connectMaxBackoff must be positive")
+	}
+	if db.HealthCheckInterval <= 0 {
+		invalid("database.healthCheckInterval (DB_HEALTH_CHECK_INTERVAL) must be positive")
+	}
+
+	if config.JWT.SigningKeyFile == "" && config.JWT.Secret == "" {
+		invalid("jwt.secret (JWT_SECRET) or jwt.signingKeyFile (JWT_SIGNING_KEY_FILE) is required")
+	}
+	if config.JWT.Secret != "" && len(config.JWT.Secret) < MinJWTSecretLength {
+		invalid("jwt.secret (JWT_SECRET) must be at least %d characters long", MinJWTSecretLength)
+	}
+	if config.JWT.Issuer == "" || config.JWT.Audience == "" {
+		invalid("jwt.issuer (JWT_ISSUER) and jwt.audience (JWT_AUDIENCE) are required")
+	}
+	if config.JWT.ClockSkew < 0 {
+		invalid("jwt.clockSkew (JWT_CLOCK_SKEW) can't be negative")
+	}
+	if config.JWT.ExpirationMinutes <= 0 {
+		invalid("jwt.expirationMinutes (JWT_EXPIRATION) must be a positive number of minutes")
+	}
+	if config.JWT.RefreshExpiration <= 0 {
+		invalid("jwt.refreshExpiration (JWT_REFRESH_EXPIRATION) must be positive")
+	}
+	if config.JWT.RevocationSyncInterval <= 0 {
+		invalid("jwt.revocationSyncInterval (JWT_REVOCATION_SYNC_INTERVAL) must be positive")
+	}
+
+	if config.Storage.PhotoDir == "" {
+		invalid("storage.photoDir (STORAGE_PHOTO_DIR) is required")
+	}
+
+	if config.Trash.Retention < 0 {
+		invalid("trash.retention (TRASH_RETENTION) can't be negative")
+	}
+	if config.Trash.PurgeInterval <= 0 {
+		invalid("trash.purgeInterval (TRASH_PURGE_INTERVAL) must be positive")
+	}
+
+	mail := config.Mail
+	if _, err := netmail.ParseAddress(mail.From); err != nil {
+		invalid("mail.from (MAIL_FROM) must be an email address: %s", err.Error())
+	}
+	switch mail.Driver {
+	case mailer.DriverLog:
+	case mailer.DriverFile:
+		if mail.FileDir == "" {
+			invalid("mail.fileDir (MAIL_FILE_DIR) is required for the file driver")
+		}
+	case mailer.DriverSMTP:
+		if mail.SMTPHost == "" {
+			invalid("mail.smtpHost (SMTP_HOST) is required for the smtp driver")
+		}
+		if mail.SMTPPort <= 0 || mail.SMTPPort > 65535 {
+			invalid("mail.smtpPort (SMTP_PORT) must be between 1 and 65535")
+		}
+	default:
+		invalid("mail.driver (MAIL_DRIVER) must be one of log, file or smtp, got %q", mail.Driver)
+	}
+
+	if config.Password.ResetTokenLifetime <= 0 {
+		invalid("password.resetTokenLifetime (PASSWORD_RESET_TOKEN_LIFETIME) must be positive")
+	}
+	password := config.Password
+	switch password.Hash {
+	case helpers.HashArgon2id:
+		if password.Argon2Iterations <= 0 || password.Argon2Parallelism <= 0 || password.Argon2Parallelism > 255 {
+			invalid("password.argon2Iterations (PASSWORD_ARGON2_ITERATIONS) must be positive and password.argon2Parallelism (PASSWORD_ARGON2_PARALLELISM) between 1 and 255")
+		} else if password.Argon2Memory < 8*password.Argon2Parallelism || int64(password.Argon2Memory) > math.MaxUint32 {
+			invalid("password.argon2Memory (PASSWORD_ARGON2_MEMORY) must be at least 8 KiB per thread of password.argon2Parallelism")
+		}
+	case helpers.HashBcrypt:
+		if password.BcryptCost < bcrypt.MinCost || password.BcryptCost > bcrypt.MaxCost {
+			invalid("password.bcryptCost (PASSWORD_BCRYPT_COST) must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	default:
+		invalid("password.hash (PASSWORD_HASH) must be argon2id or bcrypt, got %q", password.Hash)
+	}
+	throttle := config.LoginThrottle
+	if throttle.Store != stores.LoginStoreMemory && throttle.Store != stores.LoginStoreDatabase {
+		invalid("loginThrottle.store (LOGIN_THROTTLE_STORE) must be memory or database, got %q", throttle.Store)
+	}
+	if throttle.MaxAttempts <= 0 || throttle.IPMaxAttempts <= 0 {
+		invalid("loginThrottle.maxAttempts (LOGIN_MAX_ATTEMPTS) and loginThrottle.ipMaxAttempts (LOGIN_IP_MAX_ATTEMPTS) must be positive")
+	}
+	if throttle.LockoutDuration <= 0 || throttle.Window <= 0 {
+		invalid("loginThrottle.lockoutDuration (LOGIN_LOCKOUT_DURATION) and loginThrottle.window (LOGIN_ATTEMPT_WINDOW) must be positive")
+	}
+	if throttle.BaseDelay < 0 || throttle.MaxDelay < throttle.BaseDelay {
+		invalid("loginThrottle.baseDelay (LOGIN_BASE_DELAY) can't be negative or above loginThrottle.maxDelay (LOGIN_MAX_DELAY)")
+	}
+	if throttle.ResetMaxRequests <= 0 || throttle.ResetIPMaxRequests <= 0 {
+		invalid("loginThrottle.resetMaxRequests (LOGIN_RESET_MAX_REQUESTS) and loginThrottle.resetIpMaxRequests (LOGIN_RESET_IP_MAX_REQUESTS) must be positive")
+	}
+	if config.EmailVerification.TokenLifetime <= 0 {
+		invalid("emailVerification.tokenLifetime (EMAIL_VERIFICATION_TOKEN_LIFETIME) must be positive")
+	}
+	if config.MFA.Issuer == "" || strings.Contains(config.MFA.Issuer, ":") {
+		invalid("mfa.issuer (MFA_ISSUER) is required and can't contain ':'")
+	}
+	if config.MFA.ChallengeLifetime <= 0 {
+		invalid("mfa.challengeLifetime (MFA_CHALLENGE_LIFETIME) must be positive")
+	}
+	if config.MFA.MaxAttempts <= 0 {
+		invalid("mfa.maxAttempts (MFA_MAX_ATTEMPTS) must be positive")
+	}
+	if config.WebAuthn.RPID == "" || strings.ContainsAny(config.WebAuthn.RPID, ":/") {
+		invalid("webauthn.rpId (WEBAUTHN_RP_ID) must be a domain without scheme or port")
+	}
+	if config.WebAuthn.RPDisplayName == "" {
+		invalid("webauthn.rpDisplayName (WEBAUTHN_RP_DISPLAY_NAME) is required")
+	}
+	if len(config.WebAuthn.RPOrigins) == 0 {
+		invalid("webauthn.rpOrigins (WEBAUTHN_RP_ORIGINS) needs at least one origin")
+	}
+	for _, origin := range config.WebAuthn.RPOrigins {
+		if originURL, err := url.Parse(origin); err != nil || originURL.Scheme == "" || originURL.Host == "" || originURL.Path != "" {
+			invalid("webauthn.rpOrigins (WEBAUTHN_RP_ORIGINS) has an invalid origin %q", origin)
+		}
+	}
+	if config.WebAuthn.SessionLifetime <= 0 {
+		invalid("webauthn.sessionLifetime (WEBAUTHN_SESSION_LIFETIME) must be positive")
+	}
+	if config.OIDC.Enabled() {
+		if issuerURL, err := url.Parse(config.OIDC.IssuerURL); err != nil || (issuerURL.Scheme != "http" && issuerURL.Scheme != "https") || issuerURL.Host == "" {
+			invalid("oidc.issuerUrl (OIDC_ISSUER_URL) must be an http or https URL")
+		}
+		if config.OIDC.ClientID == "" {
+			invalid("oidc.clientId (OIDC_CLIENT_ID) is required with an issuer URL")
+		}
+		if redirectURL, err := url.Parse(config.OIDC.RedirectURL); err != nil || redirectURL.Scheme == "" || redirectURL.Host == "" {
+			invalid("oidc.redirectUrl (OIDC_REDIRECT_URL) must be an absolute URL")
+		}
+		if !slices.Contains(config.OIDC.Scopes, "openid") {
+			invalid("oidc.scopes (OIDC_SCOPES) must include openid")
+		}
+		if config.OIDC.StateLifetime <= 0 {
+			invalid("oidc.stateLifetime (OIDC_STATE_LIFETIME) must be positive")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// KeySet loads the keys used to sign and verify access tokens. With a signing
+// key file the secret, when set, only verifies the HS256 tokens issued before
+// switching to it.
+func (jwt *JWTConfig) KeySet() (*helpers.KeySet, error) {
+	if jwt.SigningKeyFile == "" {
+		return helpers.NewKeySet(helpers.NewHMACKey(jwt.Secret))
+	}
+
+	signingKey, err := helpers.LoadKeyFile(jwt.SigningKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	keySet, err := helpers.NewKeySet(signingKey)
+	if err != nil {
+		return nil, err
+	}
+	if jwt.Secret != "" {
+		if err := keySet.AddVerifyKey(helpers.NewHMACKey(jwt.Secret)); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range jwt.VerifyKeyFiles {
+		verifyKey, err := helpers.LoadKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := keySet.AddVerifyKey(verifyKey); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return keySet, nil
+}
+
+func (mail *MailConfig) Mailer() (mailer.IMailer, error) {
+	return mailer.New(&mailer.Config{
+		Driver:       mail.Driver,
+		From:         mail.From,
+		FileDir:      mail.FileDir,
+		SMTPHost:     mail.SMTPHost,
+		SMTPPort:     mail.SMTPPort,
+		SMTPUsername: mail.SMTPUsername,
+		SMTPPassword: mail.SMTPPassword,
+	})
+}
+
+// WebAuthn returns the relying party running the passkey ceremonies, it only
+// registers discoverable credentials so users can sign in without their email.
+// User verification is required so a passkey login is two factors on its own,
+// the device and its PIN or biometrics, like a password and a TOTP code.
+func (wa *WebAuthnConfig) WebAuthn() (*webauthn.WebAuthn, error) {
+	timeout := webauthn.TimeoutConfig{
+		Enforce:    true,
+		Timeout:    wa.SessionLifetime,
+		TimeoutUVD: wa.SessionLifetime,
+	}
+	return webauthn.New(&webauthn.Config{
+		RPID:          wa.RPID,
+		RPDisplayName: wa.RPDisplayName,
+		RPOrigins:     wa.RPOrigins,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			UserVerification:   protocol.VerificationRequired,
+		},
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        timeout,
+			Registration: timeout,
+		},
+	})
+}
+
+func (oidc *OIDCConfig) Enabled() bool {
+	return oidc.IssuerURL != ""
+}
+
+func (oidc *OIDCConfig) Provider() helpers.IOIDCProvider {
+	return helpers.NewOIDCProvider(oidc.IssuerURL, oidc.ClientID, oidc.ClientSecret, oidc.RedirectURL, oidc.Scopes)
+}
+
+func (password *PasswordConfig) Hasher() helpers.IHasher {
+	return helpers.NewHasher(helpers.HasherParams{
+		Algorithm:         password.Hash,
+		BcryptCost:        password.BcryptCost,
+		Argon2Memory:      uint32(password.Argon2Memory),
+		Argon2Iterations:  uint32(password.Argon2Iterations),
+		Argon2Parallelism: uint8(password.Argon2Parallelism),
+	})
+}
+
+// LoginThrottle keeps the failed attempts in db with the database store.
+func (throttle *LoginThrottleConfig) LoginThrottle(db database.IDatabase) stores.ILoginThrottle {
+	backend := stores.NewMemoryLoginAttempts()
+	if throttle.Store == stores.LoginStoreDatabase {
+		backend = models.NewLoginAttemptModel(db)
+	}
+	return stores.NewLoginThrottle(backend, stores.LoginThrottlePolicy{
+		MaxAttempts:        throttle.MaxAttempts,
+		IPMaxAttempts:      throttle.IPMaxAttempts,
+		LockoutDuration:    throttle.LockoutDuration,
+		BaseDelay:          throttle.BaseDelay,
+		MaxDelay:           throttle.MaxDelay,
+		Window:             throttle.Window,
+		ResetMaxRequests:   throttle.ResetMaxRequests,
+		ResetIPMaxRequests: throttle.ResetIPMaxRequests,
+	})
+}
+
+func (jwt *JWTConfig) AccessTokenLifetime() time.Duration {
+	return time.Duration(jwt.ExpirationMinutes) * time.Minute
+}
+
+func (db *DatabaseConfig) ToDatabaseConfig() (*database.Config, error) {
+	config := &database.Config{
+		Driver:          db.Driver,
+		Host:            db.Host,
+		Port:            db.Port,
+		Username:        db.Username,
+		Password:        db.Password,
+		Name:            db.Name,
+		Path:            db.Path,
+		TLSMode:         db.TLSMode,
+		TimeZone:        db.TimeZone,
+		MaxOpenConns:    db.MaxOpenConns,
+		MaxIdleConns:    db.MaxIdleConns,
+		ConnMaxLifetime: db.ConnMaxLifetime,
+	}
+	// Replicas are written as host[:port], they share the credentials and database name of the primary.
+	for _, address := range db.ReplicaHosts {
+		replica := &database.Config{}
+		host, port, err := splitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		replica.Host = host
+		replica.Port = port
+		config.Replicas = append(config.Replicas, replica)
+	}
+	return config, nil
+}
+
+func (db *DatabaseConfig) RetryPolicy() *database.RetryPolicy {
+	return &database.RetryPolicy{
+		MaxAttempts:    db.ConnectAttempts,
+		InitialBackoff: db.ConnectBackoff,
+		MaxBackoff:     db.ConnectMaxBackoff,
+	}
+}
@@ -0,0 +1,259 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// setting maps one configuration value to its environment variable and command line flag.
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	set   func(config *Config, value string) error
+}
+
+var settings = []setting{
+	// PORT is what gin used to listen on, SERVER_ADDRESS comes after it so it wins when both are set.
+	{"PORT", "port", "port to listen on, shorthand for SERVER_ADDRESS=:<port>", func(c *Config, value string) error {
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		c.Server.Address = ":" + value
+		return nil
+	}},
+	{"SERVER_ADDRESS", "server-address", "address to listen on, e.g. :8080", setString(func(c *Config) *string { return &c.Server.Address })},
+	{"SERVER_READ_TIMEOUT", "server-read-timeout", "max duration for reading a whole request, 0 disables it", setDuration(func(c *Config) *time.Duration { return &c.Server.ReadTimeout })},
+	{"SERVER_WRITE_TIMEOUT", "server-write-timeout", "max duration for writing a response, 0 disables it", setDuration(func(c *Config) *time.Duration { return &c.Server.WriteTimeout })},
+	{"SERVER_IDLE_TIMEOUT", "server-idle-timeout", "how long keep-alive connections are kept idle, 0 disables it", setDuration(func(c *Config) *time.Duration { return &c.Server.IdleTimeout })},
+	{"SERVER_MAX_HEADER_BYTES", "server-max-header-bytes", "max size of the request headers", setInt(func(c *Config) *int { return &c.Server.MaxHeaderBytes })},
+	{"SERVER_SHUTDOWN_TIMEOUT", "server-shutdown-timeout", "how long in-flight requests are given to finish on shutdown", setDuration(func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout })},
+	{"SERVER_TRUSTED_PROXIES", "server-trusted-proxies", "comma separated addresses or CIDR ranges of the proxies whose X-Forwarded-For is believed", setList(func(c *Config) *[]string { return &c.Server.TrustedProxies })},
+	{"SERVER_PUBLIC_URL", "server-public-url", "url clients reach the API at, used in the links sent by email", setString(func(c *Config) *string { return &c.Server.PublicURL })},
+	{"DB_DRIVER", "db-driver", "database driver: mysql, postgres or sqlite", setString(func(c *Config) *string { return &c.Database.Driver })},
+	{"DB_HOST", "db-host", "database host", setString(func(c *Config) *string { return &c.Database.Host })},
+	{"DB_PORT", "db-port", "database port", setInt(func(c *Config) *int { return &c.Database.Port })},
+	{"DB_UNAME", "db-username", "database username", setString(func(c *Config) *string { return &c.Database.Username })},
+	{"DB_PASSWD", "db-password", "database password", setString(func(c *Config) *string { return &c.Database.Password })},
+	{"DB_NAME", "db-name", "database name", setString(func(c *Config) *string { return &c.Database.Name })},
+	{"DB_PATH", "db-path", "sqlite database file, empty or :memory: for an in-memory database", setString(func(c *Config) *string { return &c.Database.Path })},
+	{"DB_TLS_MODE", "db-tls-mode", "tls param for mysql or sslmode for postgres", setString(func(c *Config) *string { return &c.Database.TLSMode })},
+	{"DB_TIMEZONE", "db-timezone", "timezone of the database connection", setString(func(c *Config) *string { return &c.Database.TimeZone })},
+	{"DB_MAX_OPEN_CONNS", "db-max-open-conns", "max open connections", setInt(func(c *Config) *int { return &c.Database.MaxOpenConns })},
+	{"DB_MAX_IDLE_CONNS", "db-max-idle-conns", "max idle connections", setInt(func(c *Config) *int { return &c.Database.MaxIdleConns })},
+	{"DB_CONN_MAX_LIFETIME", "db-conn-max-lifetime", "max connection lifetime", setDuration(func(c *Config) *time.Duration { return &c.Database.ConnMaxLifetime })},
+	{"DB_REPLICA_HOSTS", "db-replica-hosts", "comma separated read replicas as host[:port]", setList(func(c *Config) *[]string { return &c.Database.ReplicaHosts })},
+	{"DB_CONNECT_ATTEMPTS", "db-connect-attempts", "connection attempts at startup, 0 retries forever", setInt(func(c *Config) *int { return &c.Database.ConnectAttempts })},
+	{"DB_CONNECT_BACKOFF", "db-connect-backoff", "delay after the first failed connection attempt", setDuration(func(c *Config) *time.Duration { return &c.Database.ConnectBackoff })},
+	{"DB_CONNECT_MAX_BACKOFF", "db-connect-max-backoff", "upper bound of the connection retry delay", setDuration(func(c *Config) *time.Duration { return &c.Database.ConnectMaxBackoff })},
+	{"DB_HEALTH_CHECK_INTERVAL", "db-health-check-interval", "interval of the database health check", setDuration(func(c *Config) *time.Duration { return &c.Database.HealthCheckInterval })},
+	{"DB_MIGRATE_ON_START", "db-migrate-on-start", "apply pending migrations when the server starts", setBool(func(c *Config) *bool { return &c.Database.MigrateOnStart })},
+	{"JWT_SECRET", "jwt-secret", "secret used to sign access tokens with HS256", setString(func(c *Config) *string { return &c.JWT.Secret })},
+	{"JWT_SIGNING_KEY_FILE", "jwt-signing-key-file", "PEM RSA or Ed25519 private key used to sign access tokens instead of the secret", setString(func(c *Config) *string { return &c.JWT.SigningKeyFile })},
+	{"JWT_VERIFY_KEY_FILES", "jwt-verify-key-files", "comma separated PEM keys that signed access tokens before the current one", setList(func(c *Config) *[]string { return &c.JWT.VerifyKeyFiles })},
+	{"JWT_ISSUER", "jwt-issuer", "iss claim of the access tokens", setString(func(c *Config) *string { return &c.JWT.Issuer })},
+	{"JWT_AUDIENCE", "jwt-audience", "aud claim of the access tokens", setString(func(c *Config) *string { return &c.JWT.Audience })},
+	{"JWT_CLOCK_SKEW", "jwt-clock-skew", "clock skew tolerated when checking exp, nbf and iat", setDuration(func(c *Config) *time.Duration { return &c.JWT.ClockSkew })},
+	{"JWT_EXPIRATION", "jwt-expiration", "access token lifetime in minutes", setInt(func(c *Config) *int { return &c.JWT.ExpirationMinutes })},
+	{"JWT_REFRESH_EXPIRATION", "jwt-refresh-expiration", "refresh token lifetime, e.g. 720h", setDuration(func(c *Config) *time.Duration { return &c.JWT.RefreshExpiration })},
+	{"JWT_REVOCATION_SYNC_INTERVAL", "jwt-revocation-sync-interval", "how often revoked tokens are reloaded from the database", setDuration(func(c *Config) *time.Duration { return &c.JWT.RevocationSyncInterval })},
+	{"STORAGE_PHOTO_DIR", "storage-photo-dir", "folder where uploaded photos are stored", setString(func(c *Config) *string { return &c.Storage.PhotoDir })},
+	{"TRASH_RETENTION", "trash-retention", "how long deleted users and photos are kept", setDuration(func(c *Config) *time.Duration { return &c.Trash.Retention })},
+	{"TRASH_PURGE_INTERVAL", "trash-purge-interval", "how often the trash is purged", setDuration(func(c *Config) *time.Duration { return &c.Trash.PurgeInterval })},
+	{"MAIL_DRIVER", "mail-driver", "how emails are sent: log, file or smtp", setString(func(c *Config) *string { return &c.Mail.Driver })},
+	{"MAIL_FROM", "mail-from", "sender of the emails", setString(func(c *Config) *string { return &c.Mail.From })},
+	{"MAIL_FILE_DIR", "mail-file-dir", "folder the file driver writes emails to", setString(func(c *Config) *string { return &c.Mail.FileDir })},
+	{"SMTP_HOST", "smtp-host", "SMTP server host", setString(func(c *Config) *string { return &c.Mail.SMTPHost })},
+	{"SMTP_PORT", "smtp-port", "SMTP server port", setInt(func(c *Config) *int { return &c.Mail.SMTPPort })},
+	{"SMTP_USERNAME", "smtp-username", "SMTP username, leave empty to send without authentication", setString(func(c *Config) *string { return &c.Mail.SMTPUsername })},
+	{"SMTP_PASSWORD", "smtp-password", "SMTP password", setString(func(c *Config) *string { return &c.Mail.SMTPPassword })},
+	{"PASSWORD_RESET_TOKEN_LIFETIME", "password-reset-token-lifetime", "how long a password reset token can be used", setDuration(func(c *Config) *time.Duration { return &c.Password.ResetTokenLifetime })},
+	{"PASSWORD_HASH", "password-hash", "algorithm new passwords are hashed with: argon2id or bcrypt, older hashes are upgraded on login", setString(func(c *Config) *string { return &c.Password.Hash })},
+	{"PASSWORD_BCRYPT_COST", "password-bcrypt-cost", "cost of the bcrypt hashes", setInt(func(c *Config) *int { return &c.Password.BcryptCost })},
+	{"PASSWORD_ARGON2_MEMORY", "password-argon2-memory", "memory used by an argon2id hash, in KiB", setInt(func(c *Config) *int { return &c.Password.Argon2Memory })},
+	{"PASSWORD_ARGON2_ITERATIONS", "password-argon2-iterations", "passes over the memory of an argon2id hash", setInt(func(c *Config) *int { return &c.Password.Argon2Iterations })},
+	{"PASSWORD_ARGON2_PARALLELISM", "password-argon2-parallelism", "threads used by an argon2id hash", setInt(func(c *Config) *int { return &c.Password.Argon2Parallelism })},
+	{"LOGIN_THROTTLE_STORE", "login-throttle-store", "where failed logins are counted: memory or database (shared by the instances)", setString(func(c *Config) *string { return &c.LoginThrottle.Store })},
+	{"LOGIN_MAX_ATTEMPTS", "login-max-attempts", "failed logins of an email before it's locked out", setInt(func(c *Config) *int { return &c.LoginThrottle.MaxAttempts })},
+	{"LOGIN_IP_MAX_ATTEMPTS", "login-ip-max-attempts", "failed logins from an address before it's locked out", setInt(func(c *Config) *int { return &c.LoginThrottle.IPMaxAttempts })},
+	{"LOGIN_LOCKOUT_DURATION", "login-lockout-duration", "how long a lockout lasts", setDuration(func(c *Config) *time.Duration { return &c.LoginThrottle.LockoutDuration })},
+	{"LOGIN_BASE_DELAY", "login-base-delay", "wait after the first failed login, doubled after each failure", setDuration(func(c *Config) *time.Duration { return &c.LoginThrottle.BaseDelay })},
+	{"LOGIN_MAX_DELAY", "login-max-delay", "longest wait between failed logins before the lockout", setDuration(func(c *Config) *time.Duration { return &c.LoginThrottle.MaxDelay })},
+	{"LOGIN_ATTEMPT_WINDOW", "login-attempt-window", "how long failed logins are remembered after the last one", setDuration(func(c *Config) *time.Duration { return &c.LoginThrottle.Window })},
+	{"LOGIN_RESET_MAX_REQUESTS", "login-reset-max-requests", "password resets an email, or verification emails a user, can request within the attempt window", setInt(func(c *Config) *int { return &c.LoginThrottle.ResetMaxRequests })},
+	{"LOGIN_RESET_IP_MAX_REQUESTS", "login-reset-ip-max-requests", "password resets, or verification emails, an address can request within the attempt window", setInt(func(c *Config) *int { return &c.LoginThrottle.ResetIPMaxRequests })},
+	{"MFA_ISSUER", "mfa-issuer", "name shown by authenticator apps", setString(func(c *Config) *string { return &c.MFA.Issuer })},
+	{"MFA_CHALLENGE_LIFETIME", "mfa-challenge-lifetime", "how long the second login step can be completed", setDuration(func(c *Config) *time.Duration { return &c.MFA.ChallengeLifetime })},
+	{"MFA_MAX_ATTEMPTS", "mfa-max-attempts", "invalid codes in a row before the password is asked again", setInt(func(c *Config) *int { return &c.MFA.MaxAttempts })},
+	{"WEBAUTHN_RP_ID", "webauthn-rp-id", "domain passkeys are bound to", setString(func(c *Config) *string { return &c.WebAuthn.RPID })},
+	{"WEBAUTHN_RP_DISPLAY_NAME", "webauthn-rp-display-name", "name shown when creating a passkey", setString(func(c *Config) *string { return &c.WebAuthn.RPDisplayName })},
+	{"WEBAUTHN_RP_ORIGINS", "webauthn-rp-origins", "comma separated origins allowed to use passkeys", setList(func(c *Config) *[]string { return &c.WebAuthn.RPOrigins })},
+	{"WEBAUTHN_SESSION_LIFETIME", "webauthn-session-lifetime", "how long a passkey ceremony can take", setDuration(func(c *Config) *time.Duration { return &c.WebAuthn.SessionLifetime })},
+	{"OIDC_ISSUER_URL", "oidc-issuer-url", "OpenID Connect provider users can sign in with, disabled when empty", setString(func(c *Config) *string { return &c.OIDC.IssuerURL })},
+	{"OIDC_CLIENT_ID", "oidc-client-id", "client id registered at the provider", setString(func(c *Config) *string { return &c.OIDC.ClientID })},
+	{"OIDC_CLIENT_SECRET", "oidc-client-secret", "client secret registered at the provider", setString(func(c *Config) *string { return &c.OIDC.ClientSecret })},
+	{"OIDC_REDIRECT_URL", "oidc-redirect-url", "page of the client the provider redirects back to", setString(func(c *Config) *string { return &c.OIDC.RedirectURL })},
+	{"OIDC_SCOPES", "oidc-scopes", "comma separated scopes requested from the provider", setList(func(c *Config) *[]string { return &c.OIDC.Scopes })},
+	{"OIDC_PROVISION", "oidc-provision", "create a user on the first login of an unknown identity", setBool(func(c *Config) *bool { return &c.OIDC.Provision })},
+	{"OIDC_STATE_LIFETIME", "oidc-state-lifetime", "how long a login with the provider can take", setDuration(func(c *Config) *time.Duration { return &c.OIDC.StateLifetime })},
+	{"EMAIL_VERIFICATION_TOKEN_LIFETIME", "email-verification-token-lifetime", "how long an email verification link can be used", setDuration(func(c *Config) *time.Duration { return &c.EmailVerification.TokenLifetime })},
+	{"PASSWORD_RESET_URL", "password-reset-url", "page linked from the password reset email, the token is added as ?token=", setString(func(c *Config) *string { return &c.Password.ResetURL })},
+}
+
+// Load builds the configuration from, in increasing order of precedence, the
+// defaults, the YAML file given with -config or CONFIG_FILE, the .env file,
+// the environment and the command line flags. It returns the arguments left
+// after the flags. The .env file is optional unless -env-file is given.
+func Load(args []string) (*Config, []string, error) {
+	flagSet := flag.NewFlagSet("config", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+	configFile := flagSet.String("config", "", "path of a YAML configuration file")
+	envFile := flagSet.String("env-file", "", "path of a .env file (default .env if present)")
+	flagValues := map[string]*string{}
+	for _, s := range settings {
+		flagValues[s.flag] = flagSet.String(s.flag, "", fmt.Sprintf("%s (%s)", s.usage, s.env))
+	}
+	if err := flagSet.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *envFile != "" {
+		if err := godotenv.Load(*envFile); err != nil {
+			return nil, nil, fmt.Errorf("config: loading %s: %w", *envFile, err)
+		}
+	} else if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, fmt.Errorf("config: loading .env: %w", err)
+	}
+
+	config := Default()
+
+	if *configFile == "" {
+		*configFile = os.Getenv("CONFIG_FILE")
+	}
+	if *configFile != "" {
+		content, err := os.ReadFile(*configFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("config: %w", err)
+		}
+		if err := yaml.Unmarshal(content, config); err != nil {
+			return nil, nil, fmt.Errorf("config: parsing %s: %w", *configFile, err)
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.env); ok && value != "" {
+			if err := s.set(config, value); err != nil {
+				return nil, nil, fmt.Errorf("config: %s: %w", s.env, err)
+			}
+		}
+	}
+
+	var flagErr error
+	flagSet.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flag == f.Name && flagErr == nil {
+				if err := s.set(config, *flagValues[s.flag]); err != nil {
+					flagErr = fmt.Errorf("config: -%s: %w", s.flag, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, nil, flagErr
+	}
+
+	return config, flagSet.Args(), nil
+}
+
+// Usage lists the flags accepted by Load.
+func Usage(output io.Writer) {
+	fmt.Fprintln(output, "  -config string\n    \tpath of a YAML configuration file (CONFIG_FILE)")
+	fmt.Fprintln(output, "  -env-file string\n    \tpath of a .env file (default .env if present)")
+	for _, s := range settings {
+		fmt.Fprintf(output, "  -%s string\n    \t%s (%s)\n", s.flag, s.usage, s.env)
+	}
+}
+
+func setString(field func(c *Config) *string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func setInt(field func(c *Config) *int) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		*field(c) = parsed
+		return nil
+	}
+}
+
+func setBool(field func(c *Config) *bool) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+		*field(c) = parsed
+		return nil
+	}
+}
+
+func setDuration(field func(c *Config) *time.Duration) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration, e.g. 30s or 1h", value)
+		}
+		*field(c) = parsed
+		return nil
+	}
+}
+
+func setList(field func(c *Config) *[]string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		list := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*field(c) = list
+		return nil
+	}
+}
+
+// splitHostPort splits a host[:port] address, the port is 0 when it's left
+// out. IPv6 hosts with a port are written in brackets, like [::1]:5432.
+func splitHostPort(address string) (string, int, error) {
+	if !strings.Contains(address, ":") || net.ParseIP(address) != nil {
+		return address, 0, nil
+	}
+	if strings.HasPrefix(address, "[") && strings.HasSuffix(address, "]") {
+		return address[1 : len(address)-1], 0, nil
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || host == "" {
+		return "", 0, fmt.Errorf("config: invalid replica address %q", address)
+	}
+	parsedPort, err := strconv.ParseUint(port, 10, 16)
+	if err != nil || parsedPort == 0 {
+		return "", 0, fmt.Errorf("config: invalid replica port in %q", address)
+	}
+	return host, int(parsedPort), nil
+}
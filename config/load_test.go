@@ -0,0 +1,32 @@
+package config
+
+import "testing"
+
+func TestSplitHostPort(t *testing.T) {
+	tests := []struct {
+		address string
+		host    string
+		port    int
+		valid   bool
+	}{
+		{"replica", "replica", 0, true},
+		{"replica:5433", "replica", 5433, true},
+		{"::1", "::1", 0, true},
+		{"[::1]", "::1", 0, true},
+		{"[::1]:5433", "::1", 5433, true},
+		{"replica:0", "", 0, false},
+		{"replica:65536", "", 0, false},
+		{"replica:port", "", 0, false},
+		{"replica:", "", 0, false},
+		{":5433", "", 0, false},
+	}
+	for _, test := range tests {
+		host, port, err := splitHostPort(test.address)
+		if (err == nil) != test.valid {
+			t.Fatalf("%q: expected valid to be %t, got error %v", test.address, test.valid, err)
+		}
+		if host != test.host || port != test.port {
+			t.Fatalf("%q: expected %s and %d, got %s and %d", test.address, test.host, test.port, host, port)
+		}
+	}
+}
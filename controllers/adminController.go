@@ -0,0 +1,292 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/app"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/stores"
+	"gorm.io/gorm"
+)
+
+// maxAuditLogLimit caps the number of audit log entries returned at once.
+const maxAuditLogLimit = 500
+
+type IAdminController interface {
+	HandleSetRole() gin.HandlerFunc
+	HandleUnlock(loginThrottle stores.ILoginThrottle) gin.HandlerFunc
+	HandleFetchAuditLogs() gin.HandlerFunc
+}
+
+type AdminController struct {
+	db            database.IDatabase
+	userModel     models.IUserModel
+	auditLogModel models.IAuditLogModel
+	validator     helpers.IValidator
+}
+
+func NewAdminController(db database.IDatabase, userModel models.IUserModel, auditLogModel models.IAuditLogModel, validator helpers.IValidator) IAdminController {
+	return &AdminController{
+		db:            db,
+		userModel:     userModel,
+		auditLogModel: auditLogModel,
+		validator:     validator,
+	}
+}
+
+func (adminController *AdminController) HandleSetRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan Admin Set Role
+		// [x] Memperoleh admin dengan informasi token dari middleware
+		// [x] Memvalidasi request json, role harus dikenal
+		// [x] Mengambil user sesuai id pada parameter, admin tidak dapat mengubah role miliknya sendiri
+		// [x] Dalam satu transaksi: mengubah role user dan mencatatnya pada audit log
+		// [x] Mengirimkan response kembali ke client.
+
+		// Memperoleh admin dari auth middleware
+		currentUser := c.MustGet("currentUser").(*models.User)
+
+		parsedId, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"user_id": "Invalid user ID",
+				},
+			})
+			return
+		}
+
+		var setRoleRequest app.AdminSetRoleRequest
+		if err := c.ShouldBindJSON(&setRoleRequest); err != nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"json": "Invalid json format",
+				},
+			})
+			return
+		}
+
+		// Memvalidasi request json
+		msg, _ := adminController.validator.Validate(setRoleRequest)
+		if setRoleRequest.Role != "" && !models.IsRole(setRoleRequest.Role) {
+			msg["role"] = fmt.Sprintf("unknown role %s, available roles are %v", setRoleRequest.Role, models.Roles())
+		}
+		if len(msg) != 0 {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data:   msg,
+			})
+			return
+		}
+
+		// Admin yang menurunkan role miliknya sendiri dapat mengunci seluruh admin keluar
+		if uint(parsedId) == currentUser.ID {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"role": "You can't change your own role",
+				},
+			})
+			return
+		}
+
+		// Mengubah role dan mencatatnya pada audit log dalam satu transaksi
+		var updatedUser *models.User
+		err = adminController.db.WithTransaction(func(tx database.IDatabase) error {
+			txUserModel := adminController.userModel.WithDB(tx)
+
+			relatedUser, err := txUserModel.GetById(uint(parsedId), false)
+			if err != nil {
+				return err
+			}
+			previousRole := relatedUser.Role
+
+			updatedUser, err = txUserModel.SetRole(relatedUser, setRoleRequest.Role)
+			if err != nil {
+				return err
+			}
+
+			_, err = adminController.auditLogModel.WithDB(tx).Record(&models.AuditLog{
+				ActorID:    currentUser.ID,
+				Action:     models.AuditRoleChange,
+				Resource:   "user",
+				ResourceID: updatedUser.ID,
+				OwnerID:    updatedUser.ID,
+				Method:     c.Request.Method,
+				Path:       c.Request.URL.Path,
+				Detail:     fmt.Sprintf("%s -> %s", previousRole, updatedUser.Role),
+			})
+			return err
+		})
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, &app.JsendFailResponse{
+					Status: "fail",
+					Data: gin.H{
+						"user": "There's no user found related with provided user id",
+					},
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mengirimkan response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: &app.AdminUserRoleResponse{
+				ID:       updatedUser.ID,
+				Username: updatedUser.Username,
+				Email:    updatedUser.Email,
+				Role:     updatedUser.Role,
+			},
+		})
+	}
+}
+
+func (adminController *AdminController) HandleUnlock(loginThrottle stores.ILoginThrottle) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan Admin Unlock Login
+		// [x] Memperoleh admin dengan informasi token dari middleware
+		// [x] Mengambil user sesuai id pada parameter
+		// [x] Menghapus login yang gagal dari email user sehingga user dapat langsung login
+		// [x] Mencatat pembukaan kunci pada audit log
+		// [x] Mengirimkan response kembali ke client.
+
+		// Memperoleh admin dari auth middleware
+		currentUser := c.MustGet("currentUser").(*models.User)
+
+		parsedId, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"user_id": "Invalid user ID",
+				},
+			})
+			return
+		}
+
+		// Mengambil user sesuai id pada parameter
+		relatedUser, err := adminController.userModel.WithDB(adminController.db.Primary()).GetById(uint(parsedId), false)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, &app.JsendFailResponse{
+					Status: "fail",
+					Data: gin.H{
+						"user": "There's no user found related with provided user id",
+					},
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Membuka kunci login dan kode 2FA dari user, alamat IP yang terkunci tetap menunggu
+		if err := loginThrottle.Unlock(relatedUser.ID, relatedUser.Email); err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mencatat pembukaan kunci pada audit log
+		_, err = adminController.auditLogModel.Record(&models.AuditLog{
+			ActorID:    currentUser.ID,
+			Action:     models.AuditLoginUnlock,
+			Resource:   "user",
+			ResourceID: relatedUser.ID,
+			OwnerID:    relatedUser.ID,
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mengirimkan response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: gin.H{
+				"message": fmt.Sprintf("Login of %s has been unlocked", relatedUser.Email),
+			},
+		})
+	}
+}
+
+func (adminController *AdminController) HandleFetchAuditLogs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan Admin Fetch Audit Logs
+		// [x] Memvalidasi query limit, default 100 entry
+		// [x] Mengambil entry audit log terbaru
+		// [x] Mengirimkan response kembali ke client.
+
+		limit := 100
+		if rawLimit := c.Query("limit"); rawLimit != "" {
+			parsedLimit, err := strconv.Atoi(rawLimit)
+			if err != nil || parsedLimit < 1 || parsedLimit > maxAuditLogLimit {
+				c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+					Status: "fail",
+					Data: gin.H{
+						"limit": fmt.Sprintf("limit must be a number between 1 and %d", maxAuditLogLimit),
+					},
+				})
+				return
+			}
+			limit = parsedLimit
+		}
+
+		// Mengambil entry audit log terbaru
+		entries, err := adminController.auditLogModel.GetRecent(limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		entriesResponse := []app.AdminAuditLogResponse{}
+		for _, entry := range entries {
+			entriesResponse = append(entriesResponse, app.AdminAuditLogResponse{
+				ID:         entry.ID,
+				ActorID:    entry.ActorID,
+				Action:     entry.Action,
+				Resource:   entry.Resource,
+				ResourceID: entry.ResourceID,
+				OwnerID:    entry.OwnerID,
+				Method:     entry.Method,
+				Path:       entry.Path,
+				Detail:     entry.Detail,
+				CreatedAt:  entry.CreatedAt,
+			})
+		}
+
+		// Mengirimkan response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data:   entriesResponse,
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/app"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"gorm.io/gorm"
+)
+
+type IAPIKeyController interface {
+	HandleCreateAPIKey(apiKey helpers.IAPIKey) gin.HandlerFunc
+	HandleFetchAPIKeys() gin.HandlerFunc
+	HandleRevokeAPIKey() gin.HandlerFunc
+}
+
+type APIKeyController struct {
+	db          database.IDatabase
+	apiKeyModel models.IAPIKeyModel
+	validator   helpers.IValidator
+}
+
+func NewAPIKeyController(db database.IDatabase, apiKeyModel models.IAPIKeyModel, validator helpers.IValidator) IAPIKeyController {
+	return &APIKeyController{
+		db:          db,
+		apiKeyModel: apiKeyModel,
+		validator:   validator,
+	}
+}
+
+func apiKeyResponse(apiKey *models.APIKey) *app.UserAPIKeyResponse {
+	return &app.UserAPIKeyResponse{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     apiKey.ScopeList(),
+		ExpiresAt:  apiKey.ExpiresAt,
+		LastUsedAt: apiKey.LastUsedAt,
+		RevokedAt:  apiKey.RevokedAt,
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
+
+func (apiKeyController *APIKeyController) HandleCreateAPIKey(apiKey helpers.IAPIKey) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Create API Key
+		// [x] Memperoleh user dengan informasi token dari middleware
+		// [x] Memvalidasi request json, scope harus dikenal dan waktu kedaluwarsa di masa depan
+		// [x] Membuat API key dan menyimpan hash miliknya pada database
+		// [x] Mengirimkan API key kembali ke client, key hanya ditampilkan satu kali ini.
+
+		// Memperoleh user dari auth middleware
+		currentUser := c.MustGet("currentUser").(*models.User)
+
+		var createRequest app.UserAPIKeyCreateRequest
+		if err := c.ShouldBindJSON(&createRequest); err != nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"json": "Invalid json format",
+				},
+			})
+			return
+		}
+
+		// Memvalidasi request json
+		msg, _ := apiKeyController.validator.Validate(createRequest)
+
+		scopes := []string{}
+		for _, scope := range createRequest.Scopes {
+			if !slices.Contains(helpers.APIKeyScopes, scope) {
+				msg["scopes"] = fmt.Sprintf("unknown scope %s, available scopes are %v", scope, helpers.APIKeyScopes)
+				break
+			}
+			if !slices.Contains(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+		if len(createRequest.Scopes) == 0 {
+			msg["scopes"] = "at least one scope is required"
+		}
+
+		if createRequest.ExpiresAt != nil && !createRequest.ExpiresAt.After(time.Now()) {
+			msg["expiresAt"] = "expiry must be in the future"
+		}
+
+		if len(msg) != 0 {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data:   msg,
+			})
+			return
+		}
+
+		// Hanya hash yang disimpan, key yang bocor dari database tidak dapat digunakan
+		key, keyHash, prefix, err := apiKey.Generate()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		newKey, err := apiKeyController.apiKeyModel.WithDB(apiKeyController.db.Primary()).CreateKey(currentUser.ID, createRequest.Name, keyHash, prefix, scopes, createRequest.ExpiresAt)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mengirimkan API key kembali ke client
+		c.JSON(http.StatusCreated, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: &app.UserAPIKeyCreatedResponse{
+				UserAPIKeyResponse: *apiKeyResponse(newKey),
+				Key:                key,
+			},
+		})
+	}
+}
+
+func (apiKeyController *APIKeyController) HandleFetchAPIKeys() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Fetch API Keys
+		// [x] Memperoleh user dengan informasi token dari middleware
+		// [x] Mengambil seluruh API key milik user, termasuk yang telah dicabut
+		// [x] Mengirimkan response kembali ke client.
+
+		// Memperoleh user dari auth middleware
+		currentUser := c.MustGet("currentUser").(*models.User)
+
+		// Mengambil seluruh API key milik user
+		apiKeys, err := apiKeyController.apiKeyModel.GetByUser(currentUser.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		apiKeysResponse := []app.UserAPIKeyResponse{}
+		for i := range apiKeys {
+			apiKeysResponse = append(apiKeysResponse, *apiKeyResponse(&apiKeys[i]))
+		}
+
+		// Mengirimkan response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data:   apiKeysResponse,
+		})
+	}
+}
+
+func (apiKeyController *APIKeyController) HandleRevokeAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Revoke API Key
+		// [x] Memperoleh user dengan informasi token dari middleware
+		// [x] Mengambil API key sesuai id pada parameter, API key milik user lain dianggap tidak ada
+		// [x] Mencabut API key sehingga tidak dapat digunakan kembali
+		// [x] Mengirimkan response kembali ke client.
+
+		// Memperoleh user dari auth middleware
+		currentUser := c.MustGet("currentUser").(*models.User)
+
+		parsedId, err := strconv.ParseUint(c.Param("apiKeyId"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"api_key_id": "Invalid API key ID",
+				},
+			})
+			return
+		}
+
+		primaryModel := apiKeyController.apiKeyModel.WithDB(apiKeyController.db.Primary())
+
+		// Mengambil API key sesuai id pada parameter
+		apiKey, err := primaryModel.GetById(uint(parsedId))
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if apiKey == nil || apiKey.UserID != currentUser.ID {
+			c.JSON(http.StatusNotFound, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"apiKey": "There's no API key found related with provided API key id",
+				},
+			})
+			return
+		}
+		if apiKey.RevokedAt != nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"apiKey": "API key is already revoked",
+				},
+			})
+			return
+		}
+
+		// Mencabut API key
+		revokedKey, err := primaryModel.RevokeKey(apiKey)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mengirimkan response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data:   apiKeyResponse(revokedKey),
+		})
+	}
+}
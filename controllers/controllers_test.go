@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/app"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/migrations"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestDB returns an in-memory SQLite database with every migration applied.
+func newTestDB(t *testing.T) database.IDatabase {
+	t.Helper()
+	db, err := database.CreateSQLiteDB(database.SQLiteInMemory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.CloseDB() })
+	if _, err := migrations.NewMigrator(db).Up(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func newTestKeySet(t *testing.T) *helpers.KeySet {
+	t.Helper()
+	keySet, err := helpers.NewKeySet(helpers.NewHMACKey(strings.Repeat("k", 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keySet
+}
+
+func newTestWebToken(t *testing.T, keySet *helpers.KeySet) helpers.IWebToken {
+	return helpers.NewWebToken(15, keySet, "photo-api", "photo-api", time.Minute)
+}
+
+func createTestUser(t *testing.T, db database.IDatabase, email string) *models.User {
+	t.Helper()
+	user, err := models.NewUserModel(db).CreateUser(&app.UserRegisterRequest{
+		Username: strings.Split(email, "@")[0],
+		Email:    email,
+		Password: "not-used",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// asUser stands in for the auth middleware.
+func asUser(user *models.User) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("currentUser", user)
+	}
+}
+
+// doJSON sends body to the handler and decodes the JSend response into data.
+func doJSON(t *testing.T, handler http.Handler, method string, path string, body interface{}, data interface{}) int {
+	t.Helper()
+	payload, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if data != nil {
+		response := struct {
+			Data json.RawMessage `json:"data"`
+		}{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s %s: invalid response %s", method, path, rec.Body.String())
+		}
+		if err := json.Unmarshal(response.Data, data); err != nil {
+			t.Fatalf("%s %s: unexpected data %s", method, path, rec.Body.String())
+		}
+	}
+	return rec.Code
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/app"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+)
+
+type IHealthController interface {
+	HandleHealth() gin.HandlerFunc
+}
+
+type HealthController struct {
+	db database.IDatabase
+}
+
+func NewHealthController(db database.IDatabase) IHealthController {
+	return &HealthController{
+		db: db,
+	}
+}
+
+func (healthController *HealthController) HandleHealth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Status database diambil dari health check yang berjalan di background,
+		// sehingga endpoint ini tidak membebani database.
+		status := healthController.db.Status()
+		if status != database.StatusUp {
+			c.JSON(http.StatusServiceUnavailable, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: "database is " + string(status),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: gin.H{
+				"database": status,
+			},
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+)
+
+type IJWKSController interface {
+	HandleJWKS() gin.HandlerFunc
+}
+
+type JWKSController struct {
+	webToken helpers.IWebToken
+}
+
+func NewJWKSController(webToken helpers.IWebToken) IJWKSController {
+	return &JWKSController{
+		webToken: webToken,
+	}
+}
+
+// HandleJWKS publishes the public keys in the JWK Set format instead of JSend,
+// that is what JWT libraries of the services verifying our tokens expect.
+func (jwksController *JWKSController) HandleJWKS() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, jwksController.webToken.JWKS())
+	}
+}
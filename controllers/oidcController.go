@@ -0,0 +1,566 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/app"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/stores"
+	"gorm.io/gorm"
+)
+
+type IOIDCController interface {
+	HandleBeginLogin(provider helpers.IOIDCProvider, stateToken helpers.IOIDCStateToken) gin.HandlerFunc
+	HandleFinishLogin(provider helpers.IOIDCProvider, stateToken helpers.IOIDCStateToken, hasher helpers.IHasher, webToken helpers.IWebToken, refreshToken helpers.IRefreshToken, mfaChallenge helpers.IMFAChallengeToken, revocationStore stores.IRevocationStore, provision bool) gin.HandlerFunc
+	HandleBeginLink(provider helpers.IOIDCProvider, stateToken helpers.IOIDCStateToken) gin.HandlerFunc
+	HandleFinishLink(provider helpers.IOIDCProvider, stateToken helpers.IOIDCStateToken, revocationStore stores.IRevocationStore) gin.HandlerFunc
+	HandleFetchIdentities() gin.HandlerFunc
+	HandleDeleteIdentity() gin.HandlerFunc
+}
+
+type OIDCController struct {
+	db                database.IDatabase
+	userModel         models.IUserModel
+	identityModel     models.IExternalIdentityModel
+	refreshTokenModel models.IRefreshTokenModel
+	totpModel         models.ITOTPModel
+	validator         helpers.IValidator
+}
+
+func NewOIDCController(db database.IDatabase, userModel models.IUserModel, identityModel models.IExternalIdentityModel, refreshTokenModel models.IRefreshTokenModel, totpModel models.ITOTPModel, validator helpers.IValidator) IOIDCController {
+	return &OIDCController{
+		db:                db,
+		userModel:         userModel,
+		identityModel:     identityModel,
+		refreshTokenModel: refreshTokenModel,
+		totpModel:         totpModel,
+		validator:         validator,
+	}
+}
+
+func identityResponse(identity *models.ExternalIdentity) *app.UserExternalIdentityResponse {
+	return &app.UserExternalIdentityResponse{
+		ID:         identity.ID,
+		Issuer:     identity.Issuer,
+		Email:      identity.Email,
+		LastUsedAt: identity.LastUsedAt,
+		CreatedAt:  identity.CreatedAt,
+	}
+}
+
+// provisionedUsername memilih username untuk user baru dari klaim identity provider,
+// username tidak harus unik sehingga bagian lokal email dapat digunakan.
+func provisionedUsername(identity *helpers.OIDCIdentity) string {
+	if identity.PreferredUsername != "" {
+		return identity.PreferredUsername
+	}
+	if identity.Name != "" {
+		return identity.Name
+	}
+	return strings.SplitN(identity.Email, "@", 2)[0]
+}
+
+// bindFinishRequest memvalidasi request json dan state token dari flow yang sama, state
+// yang dikembalikan identity provider harus sama dengan state pada token.
+func (oidcController *OIDCController) bindFinishRequest(c *gin.Context, flow string, stateToken helpers.IOIDCStateToken, revocationStore stores.IRevocationStore) (*app.UserOIDCFinishRequest, *helpers.OIDCStateClaims, bool) {
+	var finishRequest app.UserOIDCFinishRequest
+	if err := c.ShouldBindJSON(&finishRequest); err != nil {
+		c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+			Status: "fail",
+			Data: gin.H{
+				"json": "Invalid json format",
+			},
+		})
+		return nil, nil, false
+	}
+
+	// Memvalidasi request json
+	msg, _ := oidcController.validator.Validate(finishRequest)
+	if len(msg) != 0 {
+		c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+			Status: "fail",
+			Data:   msg,
+		})
+		return nil, nil, false
+	}
+
+	// State token yang telah digunakan dicatat pada revocation store
+	claims, err := stateToken.ParseToken(flow, finishRequest.StateToken)
+	if err != nil || revocationStore.IsRevoked(claims.ID, claims.UserID, claims.IssuedAt.Time) ||
+		subtle.ConstantTimeCompare([]byte(claims.State), []byte(finishRequest.State)) != 1 {
+		c.JSON(http.StatusUnauthorized, &app.JsendFailResponse{
+			Status: "fail",
+			Data: gin.H{
+				"stateToken": "State token is invalid or has expired",
+			},
+		})
+		return nil, nil, false
+	}
+	return &finishRequest, claims, true
+}
+
+func (oidcController *OIDCController) HandleBeginLogin(provider helpers.IOIDCProvider, stateToken helpers.IOIDCStateToken) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Begin OIDC Login
+		// [x] Membuat state token berisi state, nonce dan PKCE verifier
+		// [x] Membuat url halaman login identity provider
+		// [x] Mengirimkan url dan state token kembali ke client.
+
+		token, claims, err := stateToken.GenerateToken(helpers.OIDCLogin, 0)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Url hanya berisi PKCE challenge, verifier tetap berada pada state token
+		authorizationURL, err := provider.AuthCodeURL(claims.State, claims.Nonce, claims.Verifier)
+		if err != nil {
+			log.Printf("oidc: %s", err.Error())
+			c.JSON(http.StatusBadGateway, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: "Identity provider is not available",
+			})
+			return
+		}
+
+		// Mengirimkan url dan state token kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: &app.UserOIDCBeginResponse{
+				AuthorizationURL: authorizationURL,
+				StateToken:       token,
+			},
+		})
+	}
+}
+
+func (oidcController *OIDCController) HandleFinishLogin(provider helpers.IOIDCProvider, stateToken helpers.IOIDCStateToken, hasher helpers.IHasher, webToken helpers.IWebToken, refreshToken helpers.IRefreshToken, mfaChallenge helpers.IMFAChallengeToken, revocationStore stores.IRevocationStore, provision bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Finish OIDC Login
+		// [x] Memvalidasi request json dan state token yang belum pernah digunakan
+		// [x] Menukarkan code dengan ID token dan memverifikasi ID token beserta nonce
+		// [x] Mengambil user yang terhubung dengan identity
+		// [x] Menghubungkan identity dengan user yang memiliki email terverifikasi yang sama
+		// [x] Membuat user baru apabila belum ada user dengan email tersebut
+		// [x] Membatalkan state token sehingga hanya dapat digunakan satu kali
+		// [x] Mengirimkan mfa token apabila user telah mengaktifkan 2FA
+		// [x] Membuat access token dan refresh token baru
+		// [x] Mengembalikan respon berupa access token dan refresh token
+
+		finishRequest, claims, ok := oidcController.bindFinishRequest(c, helpers.OIDCLogin, stateToken, revocationStore)
+		if !ok {
+			return
+		}
+
+		// Menukarkan code dengan ID token, code tanpa PKCE verifier pada state token ditolak provider
+		identity, err := provider.Exchange(c.Request.Context(), finishRequest.Code, claims.Verifier, claims.Nonce)
+		if err != nil {
+			log.Printf("oidc: %s", err.Error())
+			c.JSON(http.StatusUnauthorized, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"code": "Login with the identity provider failed",
+				},
+			})
+			return
+		}
+
+		primaryUserModel := oidcController.userModel.WithDB(oidcController.db.Primary())
+		primaryIdentityModel := oidcController.identityModel.WithDB(oidcController.db.Primary())
+
+		// Mengambil user yang terhubung dengan identity
+		var relatedUser *models.User
+		relatedIdentity, err := primaryIdentityModel.GetBySubject(identity.Issuer, identity.Subject)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if relatedIdentity != nil {
+			relatedUser, err = primaryUserModel.GetById(relatedIdentity.UserID, false)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+					Status:  "error",
+					Message: err.Error(),
+				})
+				return
+			}
+			// User yang terhubung berada pada trash
+			if relatedUser == nil {
+				c.JSON(http.StatusForbidden, &app.JsendFailResponse{
+					Status: "fail",
+					Data: gin.H{
+						"message": "This account has been deleted",
+					},
+				})
+				return
+			}
+		} else {
+			// Identity baru hanya dapat dihubungkan atau dibuatkan user apabila provider
+			// menjamin email tersebut milik user, selain itu identity harus dihubungkan dari akun.
+			if identity.Email == "" || !identity.EmailVerified {
+				c.JSON(http.StatusForbidden, &app.JsendFailResponse{
+					Status: "fail",
+					Data: gin.H{
+						"message": "No account is linked to this identity, sign in and link it first",
+					},
+				})
+				return
+			}
+
+			relatedUser, err = primaryUserModel.GetByEmail(identity.Email, false)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+					Status:  "error",
+					Message: err.Error(),
+				})
+				return
+			}
+			trashedUser, _ := primaryUserModel.GetTrashedByEmail(identity.Email)
+
+			// Email yang belum diverifikasi pada akun bisa saja didaftarkan oleh orang lain,
+			// akun tersebut tidak dihubungkan secara otomatis.
+			if (relatedUser != nil && relatedUser.EmailVerifiedAt == nil) || trashedUser != nil {
+				c.JSON(http.StatusConflict, &app.JsendFailResponse{
+					Status: "fail",
+					Data: gin.H{
+						"message": "An account with this email already exists, sign in and link the identity first",
+					},
+				})
+				return
+			}
+			if relatedUser == nil && !provision {
+				c.JSON(http.StatusForbidden, &app.JsendFailResponse{
+					Status: "fail",
+					Data: gin.H{
+						"message": "No account is linked to this identity",
+					},
+				})
+				return
+			}
+
+			err = oidcController.db.WithTransaction(func(tx database.IDatabase) error {
+				// Membuat user baru dengan password acak, user dapat memilih password
+				// melalui password reset apabila ingin login tanpa identity provider.
+				if relatedUser == nil {
+					buf := make([]byte, 32)
+					if _, err := rand.Read(buf); err != nil {
+						return err
+					}
+					hashedPassword, err := hasher.HashString(base64.RawURLEncoding.EncodeToString(buf))
+					if err != nil {
+						return err
+					}
+					newUser, err := oidcController.userModel.WithDB(tx).CreateUser(&app.UserRegisterRequest{
+						Username: provisionedUsername(identity),
+						Email:    identity.Email,
+						Password: hashedPassword,
+					})
+					if err != nil {
+						return err
+					}
+					if relatedUser, err = oidcController.userModel.WithDB(tx).MarkEmailVerified(newUser); err != nil {
+						return err
+					}
+				}
+
+				relatedIdentity, err = oidcController.identityModel.WithDB(tx).CreateIdentity(relatedUser.ID, identity.Issuer, identity.Subject, identity.Email)
+				return err
+			})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+					Status:  "error",
+					Message: err.Error(),
+				})
+				return
+			}
+		}
+
+		// Akun yang dinonaktifkan tidak dapat login
+		if relatedUser.DisabledAt != nil {
+			c.JSON(http.StatusForbidden, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"message": "This account has been disabled",
+				},
+			})
+			return
+		}
+
+		_, err = primaryIdentityModel.MarkUsed(relatedIdentity)
+		if err == nil {
+			// State token hanya dapat digunakan satu kali
+			err = revocationStore.Revoke(claims.ID, relatedUser.ID, claims.ExpiresAt.Time)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Identity provider tidak menggantikan kode 2FA, user menyelesaikan login pada POST /users/login/mfa
+		mfaRequired, err := hasConfirmedTOTP(oidcController.totpModel.WithDB(oidcController.db.Primary()), relatedUser.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if mfaRequired {
+			mfaChallengeResponse(c, mfaChallenge, relatedUser.ID)
+			return
+		}
+
+		// Membuat access token dan refresh token, setiap login memulai family refresh token yang baru
+		authResponse, err := issueTokens(oidcController.refreshTokenModel.WithDB(oidcController.db.Primary()), webToken, refreshToken, relatedUser.ID, "")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mengembalikan response berupa akses token dan refresh token kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data:   authResponse,
+		})
+	}
+}
+
+func (oidcController *OIDCController) HandleBeginLink(provider helpers.IOIDCProvider, stateToken helpers.IOIDCStateToken) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Begin OIDC Link
+		// [x] Memperoleh user dengan informasi token dari middleware
+		// [x] Membuat state token milik user berisi state, nonce dan PKCE verifier
+		// [x] Membuat url halaman login identity provider
+		// [x] Mengirimkan url dan state token kembali ke client.
+
+		// Memperoleh user dari auth middleware
+		currentUser := c.MustGet("currentUser").(*models.User)
+
+		token, claims, err := stateToken.GenerateToken(helpers.OIDCLink, currentUser.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		authorizationURL, err := provider.AuthCodeURL(claims.State, claims.Nonce, claims.Verifier)
+		if err != nil {
+			log.Printf("oidc: %s", err.Error())
+			c.JSON(http.StatusBadGateway, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: "Identity provider is not available",
+			})
+			return
+		}
+
+		// Mengirimkan url dan state token kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: &app.UserOIDCBeginResponse{
+				AuthorizationURL: authorizationURL,
+				StateToken:       token,
+			},
+		})
+	}
+}
+
+func (oidcController *OIDCController) HandleFinishLink(provider helpers.IOIDCProvider, stateToken helpers.IOIDCStateToken, revocationStore stores.IRevocationStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Finish OIDC Link
+		// [x] Memperoleh user dengan informasi token dari middleware
+		// [x] Memvalidasi request json dan state token milik user saat ini
+		// [x] Menukarkan code dengan ID token dan memverifikasi ID token beserta nonce
+		// [x] Memastikan identity belum terhubung dengan user manapun
+		// [x] Menyimpan identity pada database
+		// [x] Membatalkan state token sehingga hanya dapat digunakan satu kali
+		// [x] Mengirimkan identity kembali ke client.
+
+		// Memperoleh user dari auth middleware
+		currentUser := c.MustGet("currentUser").(*models.User)
+
+		finishRequest, claims, ok := oidcController.bindFinishRequest(c, helpers.OIDCLink, stateToken, revocationStore)
+		if !ok {
+			return
+		}
+		if claims.UserID != currentUser.ID {
+			c.JSON(http.StatusUnauthorized, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"stateToken": "State token is invalid or has expired",
+				},
+			})
+			return
+		}
+
+		identity, err := provider.Exchange(c.Request.Context(), finishRequest.Code, claims.Verifier, claims.Nonce)
+		if err != nil {
+			log.Printf("oidc: %s", err.Error())
+			c.JSON(http.StatusUnauthorized, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"code": "Login with the identity provider failed",
+				},
+			})
+			return
+		}
+
+		primaryModel := oidcController.identityModel.WithDB(oidcController.db.Primary())
+
+		// Identity hanya dapat terhubung dengan satu user
+		_, err = primaryModel.GetBySubject(identity.Issuer, identity.Subject)
+		if err == nil {
+			c.JSON(http.StatusConflict, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"message": "This identity is already linked to an account",
+				},
+			})
+			return
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Menyimpan identity pada database
+		newIdentity, err := primaryModel.CreateIdentity(currentUser.ID, identity.Issuer, identity.Subject, identity.Email)
+		if err == nil {
+			// State token hanya dapat digunakan satu kali
+			err = revocationStore.Revoke(claims.ID, currentUser.ID, claims.ExpiresAt.Time)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mengirimkan identity kembali ke client
+		c.JSON(http.StatusCreated, &app.JsendSuccessResponse{
+			Status: "success",
+			Data:   identityResponse(newIdentity),
+		})
+	}
+}
+
+func (oidcController *OIDCController) HandleFetchIdentities() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Fetch External Identities
+		// [x] Memperoleh user dengan informasi token dari middleware
+		// [x] Mengambil seluruh identity milik user
+		// [x] Mengirimkan response kembali ke client.
+
+		// Memperoleh user dari auth middleware
+		currentUser := c.MustGet("currentUser").(*models.User)
+
+		// Mengambil seluruh identity milik user
+		identities, err := oidcController.identityModel.GetByUser(currentUser.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		identitiesResponse := []app.UserExternalIdentityResponse{}
+		for i := range identities {
+			identitiesResponse = append(identitiesResponse, *identityResponse(&identities[i]))
+		}
+
+		// Mengirimkan response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data:   identitiesResponse,
+		})
+	}
+}
+
+func (oidcController *OIDCController) HandleDeleteIdentity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Delete External Identity
+		// [x] Memperoleh user dengan informasi token dari middleware
+		// [x] Mengambil identity sesuai id pada parameter, identity milik user lain dianggap tidak ada
+		// [x] Menghapus identity dari database
+		// [x] Mengirimkan response kembali ke client.
+
+		// Memperoleh user dari auth middleware
+		currentUser := c.MustGet("currentUser").(*models.User)
+
+		parsedId, err := strconv.ParseUint(c.Param("identityId"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"identity_id": "Invalid identity ID",
+				},
+			})
+			return
+		}
+
+		primaryModel := oidcController.identityModel.WithDB(oidcController.db.Primary())
+
+		// Mengambil identity sesuai id pada parameter
+		identity, err := primaryModel.GetById(uint(parsedId))
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if identity == nil || identity.UserID != currentUser.ID {
+			c.JSON(http.StatusNotFound, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"identity": "There's no identity found related with provided identity id",
+				},
+			})
+			return
+		}
+
+		// Menghapus identity dari database
+		deletedIdentity, err := primaryModel.DeleteIdentity(identity)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mengirimkan response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data:   identityResponse(deletedIdentity),
+		})
+	}
+}
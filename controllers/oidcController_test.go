@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/app"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/config"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/stores"
+)
+
+// fakeOIDCProvider answers like an identity provider whose ID token carries
+// the nonce of the last login it was asked for, or nonce when it's set.
+type fakeOIDCProvider struct {
+	identity *helpers.OIDCIdentity
+	nonce    string
+	asked    string
+	state    string
+}
+
+func (provider *fakeOIDCProvider) AuthCodeURL(state string, nonce string, verifier string) (string, error) {
+	provider.asked = nonce
+	provider.state = state
+	return "https://idp.example.com/authorize?state=" + state, nil
+}
+
+func (provider *fakeOIDCProvider) Exchange(ctx context.Context, code string, verifier string, nonce string) (*helpers.OIDCIdentity, error) {
+	idTokenNonce := provider.asked
+	if provider.nonce != "" {
+		idTokenNonce = provider.nonce
+	}
+	if idTokenNonce != nonce {
+		return nil, helpers.ErrOIDCNonceMismatch
+	}
+	identity := *provider.identity
+	return &identity, nil
+}
+
+func newOIDCTestRouter(t *testing.T, db database.IDatabase, provider helpers.IOIDCProvider) *gin.Engine {
+	keySet := newTestKeySet(t)
+	cfg := config.Default()
+	stateToken := helpers.NewOIDCStateToken(keySet, cfg.JWT.Issuer, time.Minute)
+	revocationStore := stores.NewRevocationStore(models.NewTokenRevocationModel(db), time.Hour, 0)
+	oidcController := NewOIDCController(db, models.NewUserModel(db), models.NewExternalIdentityModel(db), models.NewRefreshTokenModel(db), models.NewTOTPModel(db), helpers.NewValidator())
+
+	route := gin.New()
+	route.POST("/login/begin", oidcController.HandleBeginLogin(provider, stateToken))
+	route.POST("/login/finish", oidcController.HandleFinishLogin(provider, stateToken, cfg.Password.Hasher(), newTestWebToken(t, keySet), helpers.NewRefreshToken(time.Hour), helpers.NewMFAChallengeToken(keySet, cfg.JWT.Issuer, time.Minute), revocationStore, true))
+	return route
+}
+
+// finishOIDCLogin begins a login and finishes it with state, the state sent
+// to the provider when it's empty.
+func finishOIDCLogin(t *testing.T, route *gin.Engine, provider *fakeOIDCProvider, state string, data interface{}) int {
+	var begin app.UserOIDCBeginResponse
+	if code := doJSON(t, route, http.MethodPost, "/login/begin", gin.H{}, &begin); code != http.StatusOK {
+		t.Fatalf("begin: status %d", code)
+	}
+	if state == "" {
+		state = provider.state
+	}
+	request := &app.UserOIDCFinishRequest{StateToken: begin.StateToken, State: state, Code: "code"}
+	return doJSON(t, route, http.MethodPost, "/login/finish", request, data)
+}
+
+func verifiedUser(t *testing.T, db database.IDatabase, email string) *models.User {
+	t.Helper()
+	user, err := models.NewUserModel(db).MarkEmailVerified(createTestUser(t, db, email))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestOIDCLoginRefusesMismatchedStateAndNonce(t *testing.T) {
+	db := newTestDB(t)
+	provider := &fakeOIDCProvider{identity: &helpers.OIDCIdentity{Issuer: "https://idp.example.com", Subject: "1", Email: "user@example.com", EmailVerified: true}}
+	route := newOIDCTestRouter(t, db, provider)
+
+	if code := finishOIDCLogin(t, route, provider, "other-state", nil); code != http.StatusUnauthorized {
+		t.Fatalf("state mismatch: expected %d, got %d", http.StatusUnauthorized, code)
+	}
+
+	// The ID token was issued for another login
+	provider.nonce = "other-nonce"
+	if code := finishOIDCLogin(t, route, provider, "", nil); code != http.StatusUnauthorized {
+		t.Fatalf("nonce mismatch: expected %d, got %d", http.StatusUnauthorized, code)
+	}
+}
+
+func TestOIDCLoginRefusesUnverifiedEmail(t *testing.T) {
+	db := newTestDB(t)
+	verifiedUser(t, db, "user@example.com")
+	provider := &fakeOIDCProvider{identity: &helpers.OIDCIdentity{Issuer: "https://idp.example.com", Subject: "1", Email: "user@example.com"}}
+	route := newOIDCTestRouter(t, db, provider)
+
+	if code := finishOIDCLogin(t, route, provider, "", nil); code != http.StatusForbidden {
+		t.Fatalf("unverified email: expected %d, got %d", http.StatusForbidden, code)
+	}
+	if _, err := models.NewExternalIdentityModel(db).GetBySubject("https://idp.example.com", "1"); err == nil {
+		t.Fatal("expected the identity not to be linked")
+	}
+}
+
+func TestOIDCLoginLinksUserWithVerifiedEmail(t *testing.T) {
+	db := newTestDB(t)
+	user := verifiedUser(t, db, "user@example.com")
+	provider := &fakeOIDCProvider{identity: &helpers.OIDCIdentity{Issuer: "https://idp.example.com", Subject: "1", Email: "user@example.com", EmailVerified: true}}
+	route := newOIDCTestRouter(t, db, provider)
+
+	var tokens app.UserAuthResponse
+	if code := finishOIDCLogin(t, route, provider, "", &tokens); code != http.StatusOK {
+		t.Fatalf("login: expected %d, got %d", http.StatusOK, code)
+	}
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("login: expected tokens, got %+v", tokens)
+	}
+	identity, err := models.NewExternalIdentityModel(db).GetBySubject("https://idp.example.com", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.UserID != user.ID {
+		t.Fatalf("expected the identity to be linked to user %d, got %d", user.ID, identity.UserID)
+	}
+}
+
+func TestOIDCLoginAsksForSecondFactor(t *testing.T) {
+	db := newTestDB(t)
+	user := verifiedUser(t, db, "user@example.com")
+	totpModel := models.NewTOTPModel(db)
+	userTOTP, err := totpModel.SaveSecret(user.ID, "JBSWY3DPEHPK3PXP")
+	if err == nil {
+		_, err = totpModel.Confirm(userTOTP, 1)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := &fakeOIDCProvider{identity: &helpers.OIDCIdentity{Issuer: "https://idp.example.com", Subject: "1", Email: "user@example.com", EmailVerified: true}}
+	route := newOIDCTestRouter(t, db, provider)
+
+	var challenge app.UserMFAChallengeResponse
+	if code := finishOIDCLogin(t, route, provider, "", &challenge); code != http.StatusOK {
+		t.Fatalf("login: expected %d, got %d", http.StatusOK, code)
+	}
+	if !challenge.MFARequired || challenge.MFAToken == "" {
+		t.Fatalf("expected an mfa challenge, got %+v", challenge)
+	}
+}
@@ -3,14 +3,16 @@ package controllers
 import (
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
-	"os"
-	"strings"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/app"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
 	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/middlewares"
 	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
 	"gorm.io/gorm"
 )
@@ -18,19 +20,29 @@ import (
 type IPhotoController interface {
 	HandleCreatePhoto() gin.HandlerFunc
 	HandleFetchPhotos() gin.HandlerFunc
+	HandleFetchPhoto() gin.HandlerFunc
+	ResolvePhotoFile(param string) gin.HandlerFunc
+	HandleFetchPhotoFile() gin.HandlerFunc
 	HandleUpdatePhoto() gin.HandlerFunc
 	HandleDeletePhoto() gin.HandlerFunc
+	HandleFetchTrashedPhotos() gin.HandlerFunc
+	HandleRestorePhoto() gin.HandlerFunc
+	HandlePurgePhoto() gin.HandlerFunc
 }
 
 type PhotoController struct {
+	db        database.IDatabase
 	model     models.IPhotoModel
 	validator helpers.IValidator
+	storage   helpers.IStorage
 }
 
-func NewPhotoController(model models.IPhotoModel, validator helpers.IValidator) IPhotoController {
+func NewPhotoController(db database.IDatabase, model models.IPhotoModel, validator helpers.IValidator, storage helpers.IStorage) IPhotoController {
 	return &PhotoController{
+		db:        db,
 		model:     model,
 		validator: validator,
+		storage:   storage,
 	}
 }
 
@@ -40,9 +52,9 @@ func (photoController *PhotoController) HandleCreatePhoto() gin.HandlerFunc {
 		// [x] Memperoleh user dengan informasi token dari middleware
 		// [x] Mengambil file foto yang diupload dan melakukan rename sehingga unik.
 		// [x] Memvalidasi request form-data dari pengguna
-		// [x] Membuat data photo pada baru pada database.
-		// [x] Mengambil informasi pemilik photo (user) dengan id dari photo yang telah dibuat.
-		// [x] Menyimpan file foto yang diupload dan direname ke dalam folder static.
+		// [x] Dalam satu transaksi: membuat data photo baru pada database, mengambil informasi
+		//     pemilik photo (user) dan menyimpan file foto yang diupload ke dalam folder static.
+		// [x] Menghapus kembali file foto apabila transaksi gagal.
 		// [x] Mengirimkan kembali response ke client.
 
 		// Memperoleh user dengan informasi token dari middleware
@@ -87,19 +99,39 @@ func (photoController *PhotoController) HandleCreatePhoto() gin.HandlerFunc {
 			return
 		}
 
-		// Membuat photo baru pada database sesuai dengan informasi pada request
-		newPhoto, err := photoController.model.CreatePhoto(&photoCreationRequest)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
-				Status:  "error",
-				Message: err.Error(),
-			})
-			return
-		}
+		// Membuat photo baru, mengambil pemilik photo dan menyimpan file yang diupload dalam satu transaksi,
+		// sehingga data photo tidak tersimpan apabila file gagal disimpan.
+		var newPhoto *models.Photo
+		var photoOwner *models.User
+		fileSaved := false
+		err := photoController.db.WithTransaction(func(tx database.IDatabase) error {
+			txModel := photoController.model.WithDB(tx)
 
-		// Mengambil informasi mengenai pemilik photo (user) dengan id dari photo yang baru saja dibuat.
-		photoOwner, err := photoController.model.GetOwner(newPhoto.UserID)
+			var err error
+			newPhoto, err = txModel.CreatePhoto(&photoCreationRequest)
+			if err != nil {
+				return err
+			}
+
+			photoOwner, err = txModel.GetOwner(newPhoto.UserID)
+			if err != nil {
+				return err
+			}
+
+			err = photoController.storage.Save(file, file.Filename)
+			if err != nil {
+				return err
+			}
+			fileSaved = true
+			return nil
+		})
 		if err != nil {
+			// Menghapus file yang sudah tersimpan karena data photo batal dibuat
+			if fileSaved {
+				if removeErr := photoController.storage.Remove(file.Filename); removeErr != nil {
+					log.Printf("photo: failed to clean up %s after rollback: %s", file.Filename, removeErr.Error())
+				}
+			}
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
 					Status: "fail",
@@ -116,16 +148,6 @@ func (photoController *PhotoController) HandleCreatePhoto() gin.HandlerFunc {
 			return
 		}
 
-		// Menyimpan file yang diupload pada folder static
-		err = c.SaveUploadedFile(file, fmt.Sprintf("./static/photos/%s", file.Filename))
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
-				Status:  "error",
-				Message: err.Error(),
-			})
-			return
-		}
-
 		// Mengirimkan kembali response ke client.
 		c.JSON(http.StatusCreated, &app.JsendSuccessResponse{
 			Status: "success",
@@ -151,12 +173,16 @@ func (photoController *PhotoController) HandleCreatePhoto() gin.HandlerFunc {
 func (photoController *PhotoController) HandleFetchPhotos() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// NOTE: Langkah Kasus Penggunaan Fetch photos
-		// [x] Mengambil seluruh data photo dari database
+		// [x] Memperoleh user dengan informasi token dari middleware
+		// [x] Mengambil photo milik user dan photo yang dibagikan kepadanya dari database
 		// [x] Membentuk response untuk masing masing photo
 		// [x] Mengirimkan response kembali ke client.
 
-		// Mengambil semua photo yang terdapat pada database
-		photos, err := photoController.model.GetAllPhoto()
+		// Memperoleh user dengan informasi token dari middleware
+		currentUser := c.MustGet("currentUser").(*models.User)
+
+		// Grant yang baru dicabut masih dapat terbaca dari replica, sehingga photo diambil dari database primary
+		photos, err := photoController.model.WithDB(photoController.db.Primary()).GetVisibleTo(currentUser.ID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
 				Status:  "error",
@@ -189,26 +215,113 @@ func (photoController *PhotoController) HandleFetchPhotos() gin.HandlerFunc {
 	}
 }
 
+// ResolvePhotoFile mencari photo dari nama file pada url dan menyimpan id-nya pada param,
+// sehingga akses ke file diputuskan oleh Authorize seperti akses ke photo.
+func (photoController *PhotoController) ResolvePhotoFile(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		relatedPhoto, err := photoController.model.GetByFilename(c.Param("filename"))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.AbortWithStatusJSON(http.StatusNotFound, &app.JsendFailResponse{
+					Status: "fail",
+					Data: gin.H{
+						"photo": "There's no photo found related with provided filename",
+					},
+				})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		c.Params = append(c.Params, gin.Param{Key: param, Value: strconv.FormatUint(uint64(relatedPhoto.ID), 10)})
+	}
+}
+
+func (photoController *PhotoController) HandleFetchPhotoFile() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan Fetch photo file
+		// [x] Memperoleh photo dengan photo id dari middleware authorization
+		// [x] Mengirimkan file foto kembali ke client.
+
+		// Memperoleh photo dengan photo id dari middleware authorization
+		relatedPhoto := middlewares.RequestedPhoto.MustGet(c)
+
+		// File hanya dapat diakses oleh user yang dapat melihat photo, sehingga tidak disimpan oleh cache bersama
+		c.Header("Cache-Control", "private")
+		c.File(photoController.storage.Path(photoController.storage.FilenameFromUrl(relatedPhoto.PhotoUrl)))
+	}
+}
+
+func (photoController *PhotoController) HandleFetchPhoto() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan Fetch photo
+		// [x] Memperoleh photo dengan photo id dari middleware authorization
+		// [x] Mendapatkan informasi mengenai pemilik dari photo
+		// [x] Mengirim response kembali ke client.
+
+		// Memperoleh photo dengan photo id dari middleware authorization
+		relatedPhoto := middlewares.RequestedPhoto.MustGet(c)
+
+		// Mengambil pemilik dari photo (user)
+		photoOwner, err := photoController.model.GetOwner(relatedPhoto.UserID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+					Status: "fail",
+					Data: gin.H{
+						"message": "Can't populate owner of the photo, user with related userId is not found",
+					},
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mengirim response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: &app.PhotoDetailGeneralReponse{
+				ID:       relatedPhoto.ID,
+				Title:    relatedPhoto.Title,
+				Caption:  relatedPhoto.Caption,
+				PhotoUrl: relatedPhoto.PhotoUrl,
+				Owner: &app.UserGeneralResponse{
+					ID:        photoOwner.ID,
+					Username:  photoOwner.Username,
+					Email:     photoOwner.Email,
+					CreatedAt: photoOwner.CreatedAt,
+					UpdatedAt: photoOwner.UpdatedAt,
+				},
+				CreatedAt: relatedPhoto.CreatedAt,
+				UpdatedAt: relatedPhoto.UpdatedAt,
+			},
+		})
+	}
+}
+
 // HandleUpdatePhoto implements IPhotoController
 func (photoController *PhotoController) HandleUpdatePhoto() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// NOTE: Langkah Kasus Penggunaan Update photo
 		// [x] Memperoleh photo dengan photo id dari middleware authorization
-		// [x] Memperoleh user dengan informasi token dari middleware
 		// [x] Memperoleh nama file dengan photoUrl dari photo yang akan diupdate.
 		// [x] Mengambil file foto yang diupload dan melakukan rename sehingga unik.
 		// [x] Melakukan validasi pada data yang diberikan pengguna
-		// [x] Melakukan update pada photo dengan data dari request
-		// [x] Memperoleh informasi dari pemilik photo (user) dengan id dari photo yang baru saja diupdate
-		// [x] Simpan file yang diupload (jika ada)
-		// [x] Hapus file lama jika ada file baru yang diupload
+		// [x] Dalam satu transaksi: melakukan update pada photo dengan data dari request, memperoleh
+		//     informasi dari pemilik photo (user) dan menyimpan file yang diupload (jika ada)
+		// [x] Menghapus file baru apabila transaksi gagal
+		// [x] Hapus file lama jika ada file baru yang diupload dan transaksi berhasil
 		// [x] Mengirimkan kembali response ke client.
 
 		// Memperoleh photo dengan photo id dari middleware authorization
-		relatedPhoto := c.MustGet("requestedPhoto").(*models.Photo)
-
-		// Memperoleh user dengan informasi token dari middleware
-		currentUser := c.MustGet("currentUser").(*models.User)
+		relatedPhoto := middlewares.RequestedPhoto.MustGet(c)
 
 		var photoUpdateRequest app.FormPhotoUpdateRequest
 		if err := c.Bind(&photoUpdateRequest); err != nil {
@@ -223,16 +336,16 @@ func (photoController *PhotoController) HandleUpdatePhoto() gin.HandlerFunc {
 
 		// Memperoleh nama file dengan photoUrl dari photo yang akan diupdate
 		photoUpdateRequest.PhotoUrl = relatedPhoto.PhotoUrl
-		strSliceFileLoc := strings.Split(relatedPhoto.PhotoUrl, "/")
-		oldFilename := strSliceFileLoc[len(strSliceFileLoc)-1]
+		oldFilename := photoController.storage.FilenameFromUrl(relatedPhoto.PhotoUrl)
 
 		// Memperoleh informasi dari file yang diupload
 		file, _ := c.FormFile("photo")
 
-		// Melakukan perubahan nama file yang diupload sehingga bersifat unik
+		// Melakukan perubahan nama file yang diupload sehingga bersifat unik, nama file memuat id
+		// pemilik photo walaupun photo diupdate oleh editor
 		if file != nil {
 			timeStamp := time.Now().UnixNano()
-			file.Filename = fmt.Sprintf("photos_%d_%d_%s", currentUser.ID, timeStamp, file.Filename)
+			file.Filename = fmt.Sprintf("photos_%d_%d_%s", relatedPhoto.UserID, timeStamp, file.Filename)
 			photoUpdateRequest.PhotoUrl = fmt.Sprintf("http://%s/public/%s", c.Request.Host, file.Filename)
 		}
 
@@ -247,19 +360,41 @@ func (photoController *PhotoController) HandleUpdatePhoto() gin.HandlerFunc {
 			return
 		}
 
-		// Melakukan update pada photo dengan data dari request
-		updatedPhoto, err := photoController.model.UpdatePhoto(relatedPhoto, &photoUpdateRequest)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
-				Status:  "error",
-				Message: err.Error(),
-			})
-			return
-		}
+		// Melakukan update pada photo, mengambil pemilik photo dan menyimpan file baru (jika ada)
+		// dalam satu transaksi, sehingga photoUrl tidak mengarah ke file yang gagal disimpan.
+		var updatedPhoto *models.Photo
+		var photoOwner *models.User
+		fileSaved := false
+		err := photoController.db.WithTransaction(func(tx database.IDatabase) error {
+			txModel := photoController.model.WithDB(tx)
+
+			var err error
+			updatedPhoto, err = txModel.UpdatePhoto(relatedPhoto, &photoUpdateRequest)
+			if err != nil {
+				return err
+			}
 
-		// Memperoleh informasi dari pemilik photo (user) dengan id dari photo yang baru saja diupdate
-		photoOwner, err := photoController.model.GetOwner(updatedPhoto.UserID)
+			photoOwner, err = txModel.GetOwner(updatedPhoto.UserID)
+			if err != nil {
+				return err
+			}
+
+			if file != nil {
+				err = photoController.storage.Save(file, file.Filename)
+				if err != nil {
+					return err
+				}
+				fileSaved = true
+			}
+			return nil
+		})
 		if err != nil {
+			// Menghapus file baru yang sudah tersimpan karena update dibatalkan
+			if fileSaved {
+				if removeErr := photoController.storage.Remove(file.Filename); removeErr != nil {
+					log.Printf("photo: failed to clean up %s after rollback: %s", file.Filename, removeErr.Error())
+				}
+			}
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
 					Status: "fail",
@@ -276,27 +411,11 @@ func (photoController *PhotoController) HandleUpdatePhoto() gin.HandlerFunc {
 			return
 		}
 
-		// jika terdapat file yang diupload maka simpan file tersebut pada folder static dan hapus file lama
+		// Menghapus file lama, perubahan pada database sudah tersimpan sehingga kegagalan
+		// di sini hanya meninggalkan file yang tidak terpakai.
 		if file != nil {
-
-			// Menyimpan file baru.
-			err = c.SaveUploadedFile(file, fmt.Sprintf("./static/photos/%s", file.Filename))
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
-					Status:  "error",
-					Message: err.Error(),
-				})
-				return
-			}
-
-			// Menghapus file lama
-			err = os.Remove(fmt.Sprintf("./static/photos/%s", oldFilename))
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
-					Status:  "error",
-					Message: err.Error(),
-				})
-				return
+			if err := photoController.storage.Remove(oldFilename); err != nil {
+				log.Printf("photo: failed to remove replaced file %s: %s", oldFilename, err.Error())
 			}
 		}
 
@@ -327,12 +446,12 @@ func (photoController *PhotoController) HandleDeletePhoto() gin.HandlerFunc {
 		// NOTE: Langkah Kasus Penggunaan Delete photo
 		// [x] Memperoleh photo dengan photo id dari middleware authorization
 		// [x] Mendapatkan informasi mengenai pemilik dari photo
-		// [x] Menghapus data photo dari database
-		// [x] Menghapus file photo yang terkait dengan photo yang dihapus
+		// [x] Memindahkan photo ke trash (soft delete), file photo tetap disimpan sampai photo
+		//     dihapus permanen
 		// [x] Mengirim response kembali ke client.
 
 		// Mengambil photo dengan photo id dari middleware authorization
-		relatedPhoto := c.MustGet("requestedPhoto").(*models.Photo)
+		relatedPhoto := middlewares.RequestedPhoto.MustGet(c)
 
 		// Mengambil pemilik dari photo (user) dengan photo id
 		photoOwner, err := photoController.model.GetOwner(relatedPhoto.UserID)
@@ -353,7 +472,7 @@ func (photoController *PhotoController) HandleDeletePhoto() gin.HandlerFunc {
 			return
 		}
 
-		// Menghapus photo terkait dari database
+		// Memindahkan photo terkait ke trash, photo masih dapat direstore sampai dihapus permanen
 		deletedPhoto, err := photoController.model.DeletePhoto(relatedPhoto)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
@@ -363,18 +482,6 @@ func (photoController *PhotoController) HandleDeletePhoto() gin.HandlerFunc {
 			return
 		}
 
-		// // Menghapus file photo yang terkait dengan photo yang dihapus
-		strSliceFileLoc := strings.Split(deletedPhoto.PhotoUrl, "/")
-		oldFilename := strSliceFileLoc[len(strSliceFileLoc)-1]
-		err = os.Remove(fmt.Sprintf("./static/photos/%s", oldFilename))
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
-				Status:  "error",
-				Message: err.Error(),
-			})
-			return
-		}
-
 		// Mengirim response kembali ke client
 		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
 			Status: "success",
@@ -396,3 +503,147 @@ func (photoController *PhotoController) HandleDeletePhoto() gin.HandlerFunc {
 		})
 	}
 }
+
+func (photoController *PhotoController) HandleFetchTrashedPhotos() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan Fetch trashed photos
+		// [x] Memperoleh user dengan informasi token dari middleware
+		// [x] Mengambil seluruh photo milik user yang berada pada trash
+		// [x] Membentuk response untuk masing masing photo
+		// [x] Mengirimkan response kembali ke client.
+
+		// Memperoleh user dengan informasi token dari middleware
+		currentUser := c.MustGet("currentUser").(*models.User)
+
+		// Mengambil seluruh photo milik user yang berada pada trash
+		photos, err := photoController.model.GetTrashedByOwner(currentUser.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Membentuk response untuk masing masing photo yang diperoleh
+		photosReponse := []*app.PhotoGeneralResponse{}
+		for _, photo := range photos {
+			deletedAt := photo.DeletedAt.Time
+			photosReponse = append(photosReponse, &app.PhotoGeneralResponse{
+				ID:        photo.ID,
+				Title:     photo.Title,
+				Caption:   photo.Caption,
+				PhotoUrl:  photo.PhotoUrl,
+				UserID:    photo.UserID,
+				CreatedAt: photo.CreatedAt,
+				UpdatedAt: photo.UpdatedAt,
+				DeletedAt: &deletedAt,
+			})
+		}
+
+		// Mengirimkan response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: gin.H{
+				"photos": photosReponse,
+			},
+		})
+	}
+}
+
+func (photoController *PhotoController) HandleRestorePhoto() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan Restore photo
+		// [x] Memperoleh photo pada trash dengan photo id dari middleware authorization
+		// [x] Mengeluarkan photo dari trash
+		// [x] Mengirim response kembali ke client.
+
+		// Memperoleh photo pada trash dengan photo id dari middleware authorization
+		relatedPhoto := middlewares.RequestedPhoto.MustGet(c)
+
+		// Mengeluarkan photo dari trash
+		restoredPhoto, err := photoController.model.RestorePhoto(relatedPhoto)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mengirim response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: &app.PhotoGeneralResponse{
+				ID:        restoredPhoto.ID,
+				Title:     restoredPhoto.Title,
+				Caption:   restoredPhoto.Caption,
+				PhotoUrl:  restoredPhoto.PhotoUrl,
+				UserID:    restoredPhoto.UserID,
+				CreatedAt: restoredPhoto.CreatedAt,
+				UpdatedAt: restoredPhoto.UpdatedAt,
+			},
+		})
+	}
+}
+
+func (photoController *PhotoController) HandlePurgePhoto() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan Purge photo
+		// [x] Memperoleh photo pada trash dengan photo id dari middleware authorization
+		// [x] Dalam satu transaksi: menghapus permanen data photo dari database dan memindahkan file photo
+		//     ke lokasi sementara
+		// [x] Mengembalikan file photo apabila transaksi gagal, atau menghapusnya apabila berhasil
+		// [x] Mengirim response kembali ke client.
+
+		// Memperoleh photo pada trash dengan photo id dari middleware authorization
+		relatedPhoto := middlewares.RequestedPhoto.MustGet(c)
+
+		// Menghapus permanen photo dari database dan memindahkan file photo ke lokasi sementara dalam
+		// satu transaksi, file baru benar-benar dihapus setelah transaksi berhasil.
+		var purgedPhoto *models.Photo
+		var stagedFile *helpers.StagedRemoval
+		err := photoController.db.WithTransaction(func(tx database.IDatabase) error {
+			var err error
+			purgedPhoto, err = photoController.model.WithDB(tx).PurgePhoto(relatedPhoto)
+			if err != nil {
+				return err
+			}
+
+			stagedFile, err = photoController.storage.StageRemoval(photoController.storage.FilenameFromUrl(purgedPhoto.PhotoUrl))
+			return err
+		})
+		if err != nil {
+			// Mengembalikan file photo karena penghapusan dibatalkan
+			if stagedFile != nil {
+				if restoreErr := stagedFile.Rollback(); restoreErr != nil {
+					log.Printf("photo: failed to restore file of photo %d: %s", relatedPhoto.ID, restoreErr.Error())
+				}
+			}
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Menghapus file photo yang terkait dengan photo yang dihapus permanen
+		if err := stagedFile.Commit(); err != nil {
+			log.Printf("photo: failed to remove file of purged photo %d: %s", purgedPhoto.ID, err.Error())
+		}
+
+		// Mengirim response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: &app.PhotoGeneralResponse{
+				ID:        purgedPhoto.ID,
+				Title:     purgedPhoto.Title,
+				Caption:   purgedPhoto.Caption,
+				PhotoUrl:  purgedPhoto.PhotoUrl,
+				UserID:    purgedPhoto.UserID,
+				CreatedAt: purgedPhoto.CreatedAt,
+				UpdatedAt: purgedPhoto.UpdatedAt,
+			},
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/app"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/middlewares"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"gorm.io/gorm"
+)
+
+type IPhotoGrantController interface {
+	HandleGrantAccess() gin.HandlerFunc
+	HandleFetchGrants() gin.HandlerFunc
+	HandleRevokeGrant() gin.HandlerFunc
+	HandleFetchSharedPhotos() gin.HandlerFunc
+}
+
+type PhotoGrantController struct {
+	db              database.IDatabase
+	photoGrantModel models.IPhotoGrantModel
+	userModel       models.IUserModel
+	validator       helpers.IValidator
+}
+
+func NewPhotoGrantController(db database.IDatabase, photoGrantModel models.IPhotoGrantModel, userModel models.IUserModel, validator helpers.IValidator) IPhotoGrantController {
+	return &PhotoGrantController{
+		db:              db,
+		photoGrantModel: photoGrantModel,
+		userModel:       userModel,
+		validator:       validator,
+	}
+}
+
+func photoGrantResponse(grant *models.PhotoGrant) *app.PhotoGrantResponse {
+	return &app.PhotoGrantResponse{
+		ID:      grant.ID,
+		PhotoID: grant.PhotoID,
+		User: &app.UserGeneralResponse{
+			ID:        grant.User.ID,
+			Username:  grant.User.Username,
+			Email:     grant.User.Email,
+			CreatedAt: grant.User.CreatedAt,
+			UpdatedAt: grant.User.UpdatedAt,
+		},
+		Access:    grant.Access,
+		GrantedBy: grant.GrantedBy,
+		CreatedAt: grant.CreatedAt,
+		UpdatedAt: grant.UpdatedAt,
+	}
+}
+
+func (photoGrantController *PhotoGrantController) HandleGrantAccess() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan Grant photo access
+		// [x] Memperoleh photo dengan photo id dari middleware authorization
+		// [x] Memvalidasi request json, access harus dikenal
+		// [x] Mengambil user yang diberi akses, pemilik photo tidak dapat diberi akses
+		// [x] Membuat grant atau mengganti access dari grant yang telah ada
+		// [x] Mengirimkan response kembali ke client.
+
+		// Memperoleh photo dan user dari middleware
+		relatedPhoto := middlewares.RequestedPhoto.MustGet(c)
+		currentUser := c.MustGet("currentUser").(*models.User)
+
+		var grantRequest app.PhotoGrantRequest
+		if err := c.ShouldBindJSON(&grantRequest); err != nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"json": "Invalid json format",
+				},
+			})
+			return
+		}
+
+		// Memvalidasi request json
+		msg, _ := photoGrantController.validator.Validate(grantRequest)
+		if grantRequest.Access != "" && !models.IsPhotoAccess(grantRequest.Access) {
+			msg["access"] = fmt.Sprintf("unknown access %s, available accesses are %v", grantRequest.Access, models.PhotoAccesses())
+		}
+		if grantRequest.UserID != 0 && grantRequest.UserID == relatedPhoto.UserID {
+			msg["userId"] = "the owner of the photo already has every access"
+		}
+		if len(msg) != 0 {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data:   msg,
+			})
+			return
+		}
+
+		// Mengambil user yang diberi akses dan membuat grant dalam satu transaksi
+		var grant *models.PhotoGrant
+		err := photoGrantController.db.WithTransaction(func(tx database.IDatabase) error {
+			if _, err := photoGrantController.userModel.WithDB(tx).GetById(grantRequest.UserID, false); err != nil {
+				return err
+			}
+
+			var err error
+			grant, err = photoGrantController.photoGrantModel.WithDB(tx).Grant(relatedPhoto.ID, grantRequest.UserID, grantRequest.Access, currentUser.ID)
+			return err
+		})
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, &app.JsendFailResponse{
+					Status: "fail",
+					Data: gin.H{
+						"user": "There's no user found related with provided user id",
+					},
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mengirimkan response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data:   photoGrantResponse(grant),
+		})
+	}
+}
+
+func (photoGrantController *PhotoGrantController) HandleFetchGrants() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan Fetch photo grants
+		// [x] Memperoleh photo dengan photo id dari middleware authorization
+		// [x] Mengambil seluruh grant dari photo
+		// [x] Mengirimkan response kembali ke client.
+
+		// Memperoleh photo dengan photo id dari middleware authorization
+		relatedPhoto := middlewares.RequestedPhoto.MustGet(c)
+
+		// Mengambil seluruh grant dari photo
+		grants, err := photoGrantController.photoGrantModel.GetByPhoto(relatedPhoto.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		grantsResponse := []app.PhotoGrantResponse{}
+		for i := range grants {
+			grantsResponse = append(grantsResponse, *photoGrantResponse(&grants[i]))
+		}
+
+		// Mengirimkan response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data:   grantsResponse,
+		})
+	}
+}
+
+func (photoGrantController *PhotoGrantController) HandleRevokeGrant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan Revoke photo grant
+		// [x] Memperoleh photo dengan photo id dari middleware authorization
+		// [x] Mengambil grant dari user sesuai id pada parameter
+		// [x] Menghapus grant sehingga user kehilangan aksesnya
+		// [x] Mengirimkan response kembali ke client.
+
+		// Memperoleh photo dengan photo id dari middleware authorization
+		relatedPhoto := middlewares.RequestedPhoto.MustGet(c)
+
+		parsedId, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"user_id": "Invalid user ID",
+				},
+			})
+			return
+		}
+
+		primaryModel := photoGrantController.photoGrantModel.WithDB(photoGrantController.db.Primary())
+
+		// Mengambil grant dari user pada photo
+		grant, err := primaryModel.GetByPhotoAndUser(relatedPhoto.ID, uint(parsedId))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, &app.JsendFailResponse{
+					Status: "fail",
+					Data: gin.H{
+						"grant": "There's no grant found for the user on this photo",
+					},
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Menghapus grant
+		revokedGrant, err := primaryModel.Revoke(grant)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mengirimkan response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data:   photoGrantResponse(revokedGrant),
+		})
+	}
+}
+
+func (photoGrantController *PhotoGrantController) HandleFetchSharedPhotos() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan Fetch shared photos
+		// [x] Memperoleh user dengan informasi token dari middleware
+		// [x] Mengambil seluruh photo yang dibagikan kepada user beserta aksesnya
+		// [x] Mengirimkan response kembali ke client.
+
+		// Memperoleh user dengan informasi token dari middleware
+		currentUser := c.MustGet("currentUser").(*models.User)
+
+		// Mengambil seluruh photo yang dibagikan kepada user
+		grants, err := photoGrantController.photoGrantModel.GetSharedWith(currentUser.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		photosResponse := []app.PhotoSharedResponse{}
+		for _, grant := range grants {
+			photosResponse = append(photosResponse, app.PhotoSharedResponse{
+				PhotoGeneralResponse: app.PhotoGeneralResponse{
+					ID:        grant.Photo.ID,
+					Title:     grant.Photo.Title,
+					Caption:   grant.Photo.Caption,
+					PhotoUrl:  grant.Photo.PhotoUrl,
+					UserID:    grant.Photo.UserID,
+					CreatedAt: grant.Photo.CreatedAt,
+					UpdatedAt: grant.Photo.UpdatedAt,
+				},
+				Access: grant.Access,
+			})
+		}
+
+		// Mengirimkan response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: gin.H{
+				"photos": photosResponse,
+			},
+		})
+	}
+}
@@ -0,0 +1,592 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/app"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/stores"
+	"gorm.io/gorm"
+)
+
+// recoveryCodeCount is how many recovery codes are given at once.
+const recoveryCodeCount = 10
+
+type ITwoFactorController interface {
+	HandleEnrollTOTP(totp helpers.ITOTP) gin.HandlerFunc
+	HandleConfirmTOTP(totp helpers.ITOTP, recoveryCode helpers.IRecoveryCode) gin.HandlerFunc
+	HandleDisableTOTP(hasher helpers.IHasher, totp helpers.ITOTP, recoveryCode helpers.IRecoveryCode, loginThrottle stores.ILoginThrottle) gin.HandlerFunc
+	HandleRegenerateRecoveryCodes(hasher helpers.IHasher, totp helpers.ITOTP, recoveryCode helpers.IRecoveryCode, loginThrottle stores.ILoginThrottle) gin.HandlerFunc
+	HandleLoginMFA(webToken helpers.IWebToken, refreshToken helpers.IRefreshToken, mfaChallenge helpers.IMFAChallengeToken, totp helpers.ITOTP, recoveryCode helpers.IRecoveryCode, revocationStore stores.IRevocationStore, loginThrottle stores.ILoginThrottle, maxAttempts int) gin.HandlerFunc
+}
+
+type TwoFactorController struct {
+	db                database.IDatabase
+	userModel         models.IUserModel
+	totpModel         models.ITOTPModel
+	recoveryCodeModel models.IRecoveryCodeModel
+	refreshTokenModel models.IRefreshTokenModel
+	validator         helpers.IValidator
+}
+
+func NewTwoFactorController(db database.IDatabase, userModel models.IUserModel, totpModel models.ITOTPModel, recoveryCodeModel models.IRecoveryCodeModel, refreshTokenModel models.IRefreshTokenModel, validator helpers.IValidator) ITwoFactorController {
+	return &TwoFactorController{
+		db:                db,
+		userModel:         userModel,
+		totpModel:         totpModel,
+		recoveryCodeModel: recoveryCodeModel,
+		refreshTokenModel: refreshTokenModel,
+		validator:         validator,
+	}
+}
+
+// getConfirmedTOTP mengembalikan nil apabila user belum mengaktifkan 2FA.
+func (twoFactorController *TwoFactorController) getConfirmedTOTP(userId uint) (*models.UserTOTP, error) {
+	userTOTP, err := twoFactorController.totpModel.WithDB(twoFactorController.db.Primary()).GetByUser(userId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if userTOTP.ConfirmedAt == nil {
+		return nil, nil
+	}
+	return userTOTP, nil
+}
+
+// verifySecondFactor menerima kode TOTP atau recovery code milik user, kode yang
+// telah diterima tidak dapat digunakan kembali.
+func (twoFactorController *TwoFactorController) verifySecondFactor(userTOTP *models.UserTOTP, code string, recoveryCodeStr string, totp helpers.ITOTP, recoveryCode helpers.IRecoveryCode) (bool, error) {
+	primaryTOTPModel := twoFactorController.totpModel.WithDB(twoFactorController.db.Primary())
+	if code != "" {
+		step, ok := totp.Validate(userTOTP.Secret, code, userTOTP.LastUsedStep)
+		if !ok {
+			return false, nil
+		}
+		return primaryTOTPModel.UseStep(userTOTP, step)
+	}
+
+	used, err := twoFactorController.recoveryCodeModel.WithDB(twoFactorController.db.Primary()).UseCode(userTOTP.UserID, recoveryCode.Hash(recoveryCodeStr))
+	if err != nil || !used {
+		return false, err
+	}
+	return true, primaryTOTPModel.ResetFailures(userTOTP)
+}
+
+// replaceRecoveryCodes membuat recovery code baru dan membatalkan seluruh recovery code sebelumnya.
+func replaceRecoveryCodes(recoveryCodeModel models.IRecoveryCodeModel, recoveryCode helpers.IRecoveryCode, userId uint) ([]string, error) {
+	codes, codeHashes, err := recoveryCode.Generate(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	if err := recoveryCodeModel.ReplaceCodes(userId, codeHashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// reauthenticate memeriksa kembali password dan faktor kedua user sebelum pengaturan 2FA diubah,
+// response dikirimkan ke client apabila pemeriksaan gagal. Percobaannya dibatasi bersama kode pada
+// login MFA sehingga access token yang dicuri tidak memberikan kesempatan menebak tanpa batas.
+func (twoFactorController *TwoFactorController) reauthenticate(c *gin.Context, hasher helpers.IHasher, totp helpers.ITOTP, recoveryCode helpers.IRecoveryCode, loginThrottle stores.ILoginThrottle) (*models.User, bool) {
+	currentUser := c.MustGet("currentUser").(*models.User)
+
+	var reauthRequest app.UserReauthRequest
+	if err := c.ShouldBindJSON(&reauthRequest); err != nil {
+		c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+			Status: "fail",
+			Data: gin.H{
+				"json": "Invalid json format",
+			},
+		})
+		return nil, false
+	}
+
+	// Memvalidasi request json, salah satu dari kode atau recovery code harus diisi
+	msg, _ := twoFactorController.validator.Validate(reauthRequest)
+
+	if reauthRequest.Code == "" && reauthRequest.RecoveryCode == "" {
+		msg["code"] = "code or recoveryCode is required"
+	}
+
+	if len(msg) != 0 {
+		c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+			Status: "fail",
+			Data:   msg,
+		})
+		return nil, false
+	}
+
+	// Percobaan dicatat sebelum password dan kode dicek, percobaan yang berhasil menghapusnya kembali
+	wait, err := loginThrottle.SecondFactorAttempt(currentUser.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return nil, false
+	}
+	if wait > 0 {
+		abortTooManyAttempts(c, wait)
+		return nil, false
+	}
+
+	if !hasher.CheckHash(currentUser.Password, reauthRequest.Password) {
+		c.JSON(http.StatusUnauthorized, &app.JsendFailResponse{
+			Status: "fail",
+			Data: gin.H{
+				"password": "Password doesn't match the current password",
+			},
+		})
+		return nil, false
+	}
+
+	userTOTP, err := twoFactorController.getConfirmedTOTP(currentUser.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return nil, false
+	}
+	if userTOTP == nil {
+		c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+			Status: "fail",
+			Data: gin.H{
+				"message": "Two-factor authentication isn't enabled",
+			},
+		})
+		return nil, false
+	}
+
+	verified, err := twoFactorController.verifySecondFactor(userTOTP, reauthRequest.Code, reauthRequest.RecoveryCode, totp, recoveryCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return nil, false
+	}
+	if !verified {
+		c.JSON(http.StatusUnauthorized, &app.JsendFailResponse{
+			Status: "fail",
+			Data: gin.H{
+				"code": "Code is invalid",
+			},
+		})
+		return nil, false
+	}
+	if err := loginThrottle.SecondFactorSuccess(currentUser.ID); err != nil {
+		log.Printf("reauthenticate: failed to reset the code attempts of user %d: %s", currentUser.ID, err.Error())
+	}
+
+	return currentUser, true
+}
+
+func (twoFactorController *TwoFactorController) HandleEnrollTOTP(totp helpers.ITOTP) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Enroll TOTP
+		// [x] Memperoleh user dengan informasi token dari middleware
+		// [x] Memastikan user belum mengaktifkan 2FA
+		// [x] Membuat secret baru, menggantikan pendaftaran yang belum dikonfirmasi
+		// [x] Mengirimkan secret dan otpauth uri kembali ke client.
+
+		// Memperoleh user dari auth middleware
+		currentUser := c.MustGet("currentUser").(*models.User)
+
+		userTOTP, err := twoFactorController.getConfirmedTOTP(currentUser.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if userTOTP != nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"message": "Two-factor authentication is already enabled",
+				},
+			})
+			return
+		}
+
+		// Membuat secret baru, 2FA belum aktif sampai dikonfirmasi dengan kode pertama
+		secret, err := totp.GenerateSecret()
+		if err == nil {
+			_, err = twoFactorController.totpModel.WithDB(twoFactorController.db.Primary()).SaveSecret(currentUser.ID, secret)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mengirimkan secret dan otpauth uri kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: &app.UserTOTPEnrollResponse{
+				Secret:     secret,
+				OtpauthURI: totp.URI(secret, currentUser.Email),
+			},
+		})
+	}
+}
+
+func (twoFactorController *TwoFactorController) HandleConfirmTOTP(totp helpers.ITOTP, recoveryCode helpers.IRecoveryCode) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Confirm TOTP
+		// [x] Memperoleh user dengan informasi token dari middleware
+		// [x] Memvalidasi request json
+		// [x] Mengambil pendaftaran TOTP yang belum dikonfirmasi
+		// [x] Memverifikasi kode pertama dari authenticator
+		// [x] Dalam satu transaksi: mengaktifkan 2FA dan membuat recovery code
+		// [x] Mengirimkan recovery code kembali ke client.
+
+		// Memperoleh user dari auth middleware
+		currentUser := c.MustGet("currentUser").(*models.User)
+
+		var confirmRequest app.UserTOTPConfirmRequest
+		if err := c.ShouldBindJSON(&confirmRequest); err != nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"json": "Invalid json format",
+				},
+			})
+			return
+		}
+
+		// Memvalidasi request json
+		msg, _ := twoFactorController.validator.Validate(confirmRequest)
+
+		if len(msg) != 0 {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data:   msg,
+			})
+			return
+		}
+
+		// Mengambil pendaftaran TOTP yang belum dikonfirmasi
+		userTOTP, err := twoFactorController.totpModel.WithDB(twoFactorController.db.Primary()).GetByUser(currentUser.ID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if userTOTP == nil || userTOTP.ConfirmedAt != nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"message": "There's no two-factor enrolment waiting for confirmation",
+				},
+			})
+			return
+		}
+
+		// Memverifikasi kode pertama, membuktikan authenticator telah menyimpan secret
+		step, ok := totp.Validate(userTOTP.Secret, confirmRequest.Code, userTOTP.LastUsedStep)
+		if !ok {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"code": "Code is invalid",
+				},
+			})
+			return
+		}
+
+		// Mengaktifkan 2FA dan membuat recovery code dalam satu transaksi
+		var codes []string
+		err = twoFactorController.db.WithTransaction(func(tx database.IDatabase) error {
+			if _, err := twoFactorController.totpModel.WithDB(tx).Confirm(userTOTP, step); err != nil {
+				return err
+			}
+			var err error
+			codes, err = replaceRecoveryCodes(twoFactorController.recoveryCodeModel.WithDB(tx), recoveryCode, currentUser.ID)
+			return err
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Recovery code hanya ditampilkan satu kali
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: &app.UserRecoveryCodesResponse{
+				RecoveryCodes: codes,
+			},
+		})
+	}
+}
+
+func (twoFactorController *TwoFactorController) HandleDisableTOTP(hasher helpers.IHasher, totp helpers.ITOTP, recoveryCode helpers.IRecoveryCode, loginThrottle stores.ILoginThrottle) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Disable TOTP
+		// [x] Memverifikasi kembali password dan kode TOTP atau recovery code
+		// [x] Dalam satu transaksi: menghapus secret TOTP dan recovery code milik user
+		// [x] Mengirimkan response kembali ke client.
+
+		currentUser, ok := twoFactorController.reauthenticate(c, hasher, totp, recoveryCode, loginThrottle)
+		if !ok {
+			return
+		}
+
+		// Menghapus secret TOTP dan recovery code milik user
+		err := twoFactorController.db.WithTransaction(func(tx database.IDatabase) error {
+			if err := twoFactorController.recoveryCodeModel.WithDB(tx).DeleteByUser(currentUser.ID); err != nil {
+				return err
+			}
+			return twoFactorController.totpModel.WithDB(tx).DeleteByUser(currentUser.ID)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mengirimkan response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: gin.H{
+				"message": "Two-factor authentication has been disabled",
+			},
+		})
+	}
+}
+
+func (twoFactorController *TwoFactorController) HandleRegenerateRecoveryCodes(hasher helpers.IHasher, totp helpers.ITOTP, recoveryCode helpers.IRecoveryCode, loginThrottle stores.ILoginThrottle) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Regenerate Recovery Codes
+		// [x] Memverifikasi kembali password dan kode TOTP atau recovery code
+		// [x] Membuat recovery code baru dan membatalkan recovery code sebelumnya
+		// [x] Mengirimkan recovery code kembali ke client.
+
+		currentUser, ok := twoFactorController.reauthenticate(c, hasher, totp, recoveryCode, loginThrottle)
+		if !ok {
+			return
+		}
+
+		// Membuat recovery code baru dan membatalkan recovery code sebelumnya
+		var codes []string
+		err := twoFactorController.db.WithTransaction(func(tx database.IDatabase) error {
+			var err error
+			codes, err = replaceRecoveryCodes(twoFactorController.recoveryCodeModel.WithDB(tx), recoveryCode, currentUser.ID)
+			return err
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Recovery code hanya ditampilkan satu kali
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: &app.UserRecoveryCodesResponse{
+				RecoveryCodes: codes,
+			},
+		})
+	}
+}
+
+func (twoFactorController *TwoFactorController) HandleLoginMFA(webToken helpers.IWebToken, refreshToken helpers.IRefreshToken, mfaChallenge helpers.IMFAChallengeToken, totp helpers.ITOTP, recoveryCode helpers.IRecoveryCode, revocationStore stores.IRevocationStore, loginThrottle stores.ILoginThrottle, maxAttempts int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Login MFA
+		// [x] Memvalidasi request berupa json
+		// [x] Memverifikasi mfa token yang diperoleh dari langkah password
+		// [x] Mengambil user dan TOTP terkait dengan id pada mfa token
+		// [x] Mencatat percobaan kode pada akun, akun yang masih harus menunggu ditolak
+		// [x] Memverifikasi kode TOTP atau recovery code
+		// [x] Membatalkan mfa token setelah terlalu banyak kode yang salah, kegagalan tetap dihitung pada mfa token berikutnya
+		// [x] Membatalkan mfa token sehingga hanya dapat digunakan satu kali
+		// [x] Membuat access token dan refresh token baru
+		// [x] Mengembalikan respon berupa access token dan refresh token
+
+		var mfaRequest app.UserLoginMFARequest
+		if err := c.ShouldBindJSON(&mfaRequest); err != nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"json": "Invalid json format",
+				},
+			})
+			return
+		}
+
+		// Memvalidasi request json, salah satu dari kode atau recovery code harus diisi
+		msg, _ := twoFactorController.validator.Validate(mfaRequest)
+
+		if mfaRequest.Code == "" && mfaRequest.RecoveryCode == "" {
+			msg["code"] = "code or recoveryCode is required"
+		}
+
+		if len(msg) != 0 {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data:   msg,
+			})
+			return
+		}
+
+		invalidChallengeResponse := &app.JsendFailResponse{
+			Status: "fail",
+			Data: gin.H{
+				"mfaToken": "MFA token is invalid or has expired, please login again",
+			},
+		}
+
+		// Memverifikasi mfa token, token yang telah digunakan dicatat pada revocation store
+		claims, err := mfaChallenge.ParseToken(mfaRequest.MFAToken)
+		if err != nil || revocationStore.IsRevoked(claims.ID, claims.UserID, claims.IssuedAt.Time) {
+			c.JSON(http.StatusUnauthorized, invalidChallengeResponse)
+			return
+		}
+
+		// Mengambil user dan TOTP terkait dengan id pada mfa token
+		currentUser, err := twoFactorController.userModel.WithDB(twoFactorController.db.Primary()).GetById(claims.UserID, false)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusUnauthorized, invalidChallengeResponse)
+				return
+			}
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if currentUser.DisabledAt != nil {
+			c.JSON(http.StatusForbidden, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"message": "This account has been disabled",
+				},
+			})
+			return
+		}
+
+		userTOTP, err := twoFactorController.getConfirmedTOTP(currentUser.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if userTOTP == nil {
+			c.JSON(http.StatusUnauthorized, invalidChallengeResponse)
+			return
+		}
+
+		// Percobaan kode dicatat pada akun, bukan pada mfa token, sehingga login ulang dengan password
+		// tidak memberikan kesempatan menebak yang baru
+		wait, err := loginThrottle.SecondFactorAttempt(currentUser.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if wait > 0 {
+			abortTooManyAttempts(c, wait)
+			return
+		}
+
+		// Memverifikasi kode TOTP atau recovery code
+		verified, err := twoFactorController.verifySecondFactor(userTOTP, mfaRequest.Code, mfaRequest.RecoveryCode, totp, recoveryCode)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if !verified {
+			primaryTOTPModel := twoFactorController.totpModel.WithDB(twoFactorController.db.Primary())
+			failedAttempts, err := primaryTOTPModel.RecordFailure(userTOTP)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+					Status:  "error",
+					Message: err.Error(),
+				})
+				return
+			}
+			if failedAttempts < maxAttempts {
+				c.JSON(http.StatusUnauthorized, &app.JsendFailResponse{
+					Status: "fail",
+					Data: gin.H{
+						"code": "Code is invalid",
+					},
+				})
+				return
+			}
+
+			// Setelah terlalu banyak kode yang salah mfa token dibatalkan dan password harus dimasukan kembali,
+			// kegagalan baru dihapus setelah kode yang benar
+			if err := revocationStore.Revoke(claims.ID, claims.UserID, claims.ExpiresAt.Time); err != nil {
+				c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+					Status:  "error",
+					Message: err.Error(),
+				})
+				return
+			}
+			c.JSON(http.StatusUnauthorized, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"mfaToken": "Too many invalid codes, please login again",
+				},
+			})
+			return
+		}
+
+		// Mfa token hanya dapat digunakan satu kali
+		if err := revocationStore.Revoke(claims.ID, claims.UserID, claims.ExpiresAt.Time); err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if err := loginThrottle.SecondFactorSuccess(currentUser.ID); err != nil {
+			log.Printf("login mfa: failed to reset the code attempts of user %d: %s", currentUser.ID, err.Error())
+		}
+
+		// Membuat access token dan refresh token, setiap login memulai family refresh token yang baru
+		authResponse, err := issueTokens(twoFactorController.refreshTokenModel.WithDB(twoFactorController.db.Primary()), webToken, refreshToken, currentUser.ID, "")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mengembalikan response berupa akses token dan refresh token kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data:   authResponse,
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/config"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/stores"
+)
+
+func TestDisableTOTPLimitsTheGuesses(t *testing.T) {
+	db := newTestDB(t)
+	cfg := config.Default()
+	user := createTestUser(t, db, "user@example.com")
+	totpModel := models.NewTOTPModel(db)
+	userTOTP, err := totpModel.SaveSecret(user.ID, "JBSWY3DPEHPK3PXP")
+	if err == nil {
+		_, err = totpModel.Confirm(userTOTP, 1)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	twoFactorController := NewTwoFactorController(db, models.NewUserModel(db), totpModel, models.NewRecoveryCodeModel(db), models.NewRefreshTokenModel(db), helpers.NewValidator())
+	loginThrottle := stores.NewLoginThrottle(stores.NewMemoryLoginAttempts(), stores.LoginThrottlePolicy{
+		MaxAttempts:     3,
+		IPMaxAttempts:   50,
+		LockoutDuration: time.Hour,
+		Window:          time.Hour,
+	})
+
+	route := gin.New()
+	route.DELETE("/2fa/totp", asUser(user), twoFactorController.HandleDisableTOTP(cfg.Password.Hasher(), helpers.NewTOTP("test"), helpers.NewRecoveryCode(), loginThrottle))
+
+	// A stolen access token doesn't allow guessing the password and the code without limit
+	for i := 0; i < 3; i++ {
+		if code := doJSON(t, route, http.MethodDelete, "/2fa/totp", gin.H{"password": "wrong-password", "code": "000000"}, nil); code != http.StatusUnauthorized {
+			t.Fatalf("guess %d: expected %d, got %d", i+1, http.StatusUnauthorized, code)
+		}
+	}
+	if code := doJSON(t, route, http.MethodDelete, "/2fa/totp", gin.H{"password": "wrong-password", "code": "000000"}, nil); code != http.StatusTooManyRequests {
+		t.Fatalf("guess over the limit: expected %d, got %d", http.StatusTooManyRequests, code)
+	}
+	confirmed, err := totpModel.GetByUser(user.ID)
+	if err != nil || confirmed == nil {
+		t.Fatalf("expected 2FA to stay enabled, got %v, err %v", confirmed, err)
+	}
+}
@@ -2,46 +2,168 @@ package controllers
 
 import (
 	"errors"
-	"fmt"
+	"log"
+	"math"
 	"net/http"
-	"os"
-	"strings"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/app"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
 	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/jobs"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/mailer"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/middlewares"
 	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/stores"
 	"gorm.io/gorm"
 )
 
+// errRefreshTokenReused is returned inside the refresh transaction when the
+// token was used by a concurrent request in the meantime.
+var errRefreshTokenReused = errors.New("refresh token has already been used")
+
 type IUserController interface {
-	HandleRegister(hasher helpers.IHasher, webToken helpers.IWebToken) gin.HandlerFunc
-	HandleLogin(hasher helpers.IHasher, webToken helpers.IWebToken) gin.HandlerFunc
-	HandleUpdate(hasher helpers.IHasher) gin.HandlerFunc
+	HandleRegister(hasher helpers.IHasher, webToken helpers.IWebToken, refreshToken helpers.IRefreshToken, verificationToken helpers.IEmailVerificationToken, mailSender mailer.IMailer, verifyURL string) gin.HandlerFunc
+	HandleLogin(hasher helpers.IHasher, webToken helpers.IWebToken, refreshToken helpers.IRefreshToken, mfaChallenge helpers.IMFAChallengeToken, loginThrottle stores.ILoginThrottle) gin.HandlerFunc
+	HandleRefresh(webToken helpers.IWebToken, refreshToken helpers.IRefreshToken) gin.HandlerFunc
+	HandleLogout(refreshToken helpers.IRefreshToken, revocationStore stores.IRevocationStore) gin.HandlerFunc
+	HandleLogoutAll(revocationStore stores.IRevocationStore) gin.HandlerFunc
+	HandleUpdate(hasher helpers.IHasher, revocationStore stores.IRevocationStore, verificationToken helpers.IEmailVerificationToken, mailSender mailer.IMailer, verifyURL string) gin.HandlerFunc
+	HandleForgotPassword(resetQueue jobs.IPasswordResetQueue, loginThrottle stores.ILoginThrottle) gin.HandlerFunc
+	HandleResetPassword(hasher helpers.IHasher, resetToken helpers.IOpaqueToken, revocationStore stores.IRevocationStore, loginThrottle stores.ILoginThrottle) gin.HandlerFunc
+	HandleVerifyEmail(verificationToken helpers.IEmailVerificationToken) gin.HandlerFunc
+	HandleResendVerification(verificationToken helpers.IEmailVerificationToken, mailSender mailer.IMailer, verifyURL string, loginThrottle stores.ILoginThrottle) gin.HandlerFunc
 	HandleDelete() gin.HandlerFunc
+	HandleRestore(hasher helpers.IHasher, loginThrottle stores.ILoginThrottle) gin.HandlerFunc
 }
 
 type UserController struct {
-	model     models.IUserModel
-	validator helpers.IValidator
+	db                 database.IDatabase
+	model              models.IUserModel
+	refreshTokenModel  models.IRefreshTokenModel
+	passwordResetModel models.IPasswordResetModel
+	totpModel          models.ITOTPModel
+	validator          helpers.IValidator
 }
 
-func NewUserController(model models.IUserModel, validator helpers.IValidator) IUserController {
+func NewUserController(db database.IDatabase, model models.IUserModel, refreshTokenModel models.IRefreshTokenModel, passwordResetModel models.IPasswordResetModel, totpModel models.ITOTPModel, validator helpers.IValidator) IUserController {
 	return &UserController{
-		model:     model,
-		validator: validator,
+		db:                 db,
+		model:              model,
+		refreshTokenModel:  refreshTokenModel,
+		passwordResetModel: passwordResetModel,
+		totpModel:          totpModel,
+		validator:          validator,
+	}
+}
+
+// abortTooManyAttempts menolak login selama wait dengan 429, respon sama untuk email yang
+// terdaftar maupun tidak.
+func abortTooManyAttempts(c *gin.Context, wait time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, &app.JsendFailResponse{
+		Status: "fail",
+		Data: gin.H{
+			"message": "Too many failed login attempts, please try again later",
+		},
+	})
+}
+
+// newDummyHash membuat hash pembanding untuk email yang tidak terdaftar sehingga waktu
+// respon sama dengan password yang salah, hash dibuat sekali saat pertama digunakan.
+func newDummyHash(hasher helpers.IHasher) func() string {
+	return sync.OnceValue(func() string {
+		hash, err := hasher.HashString("login-throttle-dummy-password")
+		if err != nil {
+			log.Printf("login: failed to hash the dummy password: %s", err.Error())
+		}
+		return hash
+	})
+}
+
+// hasConfirmedTOTP memeriksa apakah user telah mengaktifkan 2FA, login user tersebut
+// diselesaikan pada POST /users/login/mfa.
+func hasConfirmedTOTP(totpModel models.ITOTPModel, userId uint) (bool, error) {
+	userTOTP, err := totpModel.GetByUser(userId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return userTOTP.ConfirmedAt != nil, nil
+}
+
+// mfaChallengeResponse mengirimkan mfa token sebagai pengganti access token dan refresh token.
+func mfaChallengeResponse(c *gin.Context, mfaChallenge helpers.IMFAChallengeToken, userId uint) {
+	mfaToken, err := mfaChallenge.GenerateToken(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+		Status: "success",
+		Data: &app.UserMFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+		},
+	})
+}
+
+// issueTokens membuat akses token dan refresh token baru untuk user, familyId kosong
+// berarti login baru sehingga refresh token memulai family baru.
+func issueTokens(refreshTokenModel models.IRefreshTokenModel, webToken helpers.IWebToken, refreshToken helpers.IRefreshToken, userId uint, familyId string) (*app.UserAuthResponse, error) {
+	accessToken, err := webToken.GenerateToken(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if familyId == "" {
+		familyId, err = refreshToken.NewFamilyID()
+		if err != nil {
+			return nil, err
+		}
+	}
+	newRefreshToken, tokenHash, err := refreshToken.Generate()
+	if err != nil {
+		return nil, err
+	}
+	_, err = refreshTokenModel.CreateToken(userId, familyId, tokenHash, refreshToken.ExpiresAt())
+	if err != nil {
+		return nil, err
 	}
+
+	return &app.UserAuthResponse{
+		AccessToken:  accessToken,
+		RefreshToken: newRefreshToken,
+	}, nil
+}
+
+// sendVerificationEmail mengirimkan link verifikasi untuk email user saat ini.
+func (userController *UserController) sendVerificationEmail(verificationToken helpers.IEmailVerificationToken, mailSender mailer.IMailer, verifyURL string, user *models.User) error {
+	token, err := verificationToken.GenerateToken(user.ID, user.Email)
+	if err != nil {
+		return err
+	}
+	return mailSender.Send(mailer.EmailVerificationMessage(user.Email, token, verifyURL, verificationToken.Lifetime()))
 }
 
-func (userController *UserController) HandleRegister(hasher helpers.IHasher, webToken helpers.IWebToken) gin.HandlerFunc {
+func (userController *UserController) HandleRegister(hasher helpers.IHasher, webToken helpers.IWebToken, refreshToken helpers.IRefreshToken, verificationToken helpers.IEmailVerificationToken, mailSender mailer.IMailer, verifyURL string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// NOTE: Langkah Kasus Penggunaan User Register
 		// [x] Memvalidasi request berupa json
 		// [x] Memvalidasi apakah email atau attribut unik lain telah terpakai
 		// [x] Melakukan hash pada password
 		// [x] Menyimpan user pada database
-		// [x] Membuat access token dengan id user yang telah masuk pada database
-		// [x] Mengembalikan respon berupa access token
+		// [x] Membuat access token dan refresh token dengan id user yang telah masuk pada database
+		// [x] Mengirimkan link verifikasi ke email user
+		// [x] Mengembalikan respon berupa access token dan refresh token
 
 		var registerRequest app.UserRegisterRequest
 		if err := c.ShouldBindJSON(&registerRequest); err != nil {
@@ -69,8 +191,16 @@ func (userController *UserController) HandleRegister(hasher helpers.IHasher, web
 			return
 		}
 
-		// Mengecek email apakah sudah digunakan oleh user lain atau tidak
-		relatedUser, _ := userController.model.GetByEmail(registerRequest.Email, false)
+		// Pengecekan dan penyimpanan user dilakukan pada database primary, replica bisa saja
+		// belum memiliki user yang baru saja mendaftar dengan email yang sama.
+		primaryModel := userController.model.WithDB(userController.db.Primary())
+
+		// Mengecek email apakah sudah digunakan oleh user lain atau tidak, termasuk user yang berada
+		// pada trash karena user tersebut masih dapat direstore.
+		relatedUser, _ := primaryModel.GetByEmail(registerRequest.Email, false)
+		if relatedUser == nil {
+			relatedUser, _ = primaryModel.GetTrashedByEmail(registerRequest.Email)
+		}
 		if relatedUser != nil {
 			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
 				Status: "fail",
@@ -94,7 +224,7 @@ func (userController *UserController) HandleRegister(hasher helpers.IHasher, web
 		registerRequest.Password = hashedPassword
 
 		// Membuat user baru pada database sesuai dengan request user
-		newUser, err := userController.model.CreateUser(&registerRequest)
+		newUser, err := primaryModel.CreateUser(&registerRequest)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
 				Status:  "error",
@@ -103,8 +233,8 @@ func (userController *UserController) HandleRegister(hasher helpers.IHasher, web
 			return
 		}
 
-		// Membuat access token dengan informasi berupa id dari user yang telah dibuat
-		accessToken, err := webToken.GenerateToken(newUser.ID)
+		// Membuat access token dan refresh token dengan informasi berupa id dari user yang telah dibuat
+		authResponse, err := issueTokens(userController.refreshTokenModel.WithDB(userController.db.Primary()), webToken, refreshToken, newUser.ID, "")
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
 				Status:  "error",
@@ -113,23 +243,39 @@ func (userController *UserController) HandleRegister(hasher helpers.IHasher, web
 			return
 		}
 
-		// Mengembalikan response berupa json berisi akses token kembali ke client
+		// Kegagalan pengiriman email tidak membatalkan registrasi, user dapat meminta link baru
+		if err := userController.sendVerificationEmail(verificationToken, mailSender, verifyURL, newUser); err != nil {
+			log.Printf("email verification: failed to send email to user %d: %s", newUser.ID, err.Error())
+		}
+
+		// Mengembalikan response berupa json berisi akses token dan refresh token kembali ke client
 		c.JSON(http.StatusCreated, &app.JsendSuccessResponse{
 			Status: "success",
-			Data: &app.UserAuthResponse{
-				AccessToken: accessToken,
-			},
+			Data:   authResponse,
 		})
 	}
 }
 
-func (userController *UserController) HandleLogin(hasher helpers.IHasher, webToken helpers.IWebToken) gin.HandlerFunc {
+func (userController *UserController) HandleLogin(hasher helpers.IHasher, webToken helpers.IWebToken, refreshToken helpers.IRefreshToken, mfaChallenge helpers.IMFAChallengeToken, loginThrottle stores.ILoginThrottle) gin.HandlerFunc {
 	// NOTE: Langkah Kasus Penggunaan User Register
 	// [x] Memvalidasi request berupa json
+	// [x] Mencatat percobaan login sebelum password dicek, email atau alamat IP yang masih harus menunggu ditolak
 	// [x] Mengambil user terkait dengan email yang diperoleh dari request
 	// [x] Melakukan komparasi pada password user saat ini dan password dari request
-	// [x] Membuat access token baru dengan informasi berupa id dari user saat ini
-	// [x] Mengembalikan respon berupa access token
+	// [x] Melupakan percobaan login setelah berhasil, email yang tidak terdaftar diperlakukan sama seperti password yang salah
+	// [x] Mengganti hash password yang dibuat dengan algoritma atau parameter lama
+	// [x] Mengembalikan mfa token apabila user mengaktifkan 2FA, token dibuat setelah kode diverifikasi
+	// [x] Membuat access token dan refresh token baru dengan informasi berupa id dari user saat ini
+	// [x] Mengembalikan respon berupa access token dan refresh token
+
+	dummyHash := newDummyHash(hasher)
+
+	invalidCredentialResponse := &app.JsendFailResponse{
+		Status: "fail",
+		Data: gin.H{
+			"message": "Email and password provided doesn't match",
+		},
+	}
 
 	return func(c *gin.Context) {
 		var loginRequest app.UserLoginRequest
@@ -154,14 +300,148 @@ func (userController *UserController) HandleLogin(hasher helpers.IHasher, webTok
 			return
 		}
 
+		// Percobaan login email dicatat sebelum password dicek sehingga login yang dikirim bersamaan tidak lolos sebelum
+		// kegagalan pertama tercatat, email atau alamat IP yang gagal login harus menunggu sebelum mencoba kembali
+		wait, err := loginThrottle.Attempt(loginRequest.Email, c.ClientIP())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if wait > 0 {
+			abortTooManyAttempts(c, wait)
+			return
+		}
+
 		// Mengambil user terkait dengan email yang diperoleh dari request
 		currentUser, err := userController.model.GetByEmail(loginRequest.Email, false)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Melakukan pengecekan password user saat ini (terhash) dengan password dari request (plaintext),
+		// email yang tidak terdaftar dicek dengan hash pembanding sehingga tercatat seperti password yang salah
+		passwordHash := dummyHash()
+		if currentUser != nil {
+			passwordHash = currentUser.Password
+		}
+		if !hasher.CheckHash(passwordHash, loginRequest.Password) || currentUser == nil {
+			// Alamat IP hanya diperlambat oleh login yang gagal sehingga user lain di belakang alamat yang sama tetap dapat login
+			if err := loginThrottle.Failure(c.ClientIP()); err != nil {
+				log.Printf("login: failed to count the failed attempt: %s", err.Error())
+			}
+			c.JSON(http.StatusUnauthorized, invalidCredentialResponse)
+			return
+		}
+		if err := loginThrottle.Success(loginRequest.Email); err != nil {
+			log.Printf("login: failed to reset the failed attempts of user %d: %s", currentUser.ID, err.Error())
+		}
+
+		// Akun yang dinonaktifkan tidak dapat login
+		if currentUser.DisabledAt != nil {
+			c.JSON(http.StatusForbidden, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"message": "This account has been disabled",
+				},
+			})
+			return
+		}
+
+		// Password plaintext hanya tersedia saat login, sehingga hash lama diganti di sini tanpa menggagalkan login.
+		// Hash hanya diganti apabila masih sama dengan hash yang dicek, user dibaca dari replica sehingga password
+		// yang baru saja direset atau diubah tidak tertimpa kembali oleh password lama
+		if hasher.NeedsRehash(currentUser.Password) {
+			if hashedPassword, err := hasher.HashString(loginRequest.Password); err != nil {
+				log.Printf("login: failed to rehash the password of user %d: %s", currentUser.ID, err.Error())
+			} else if _, err := userController.model.WithDB(userController.db.Primary()).RehashPassword(currentUser, currentUser.Password, hashedPassword); err != nil {
+				log.Printf("login: failed to rehash the password of user %d: %s", currentUser.ID, err.Error())
+			}
+		}
+
+		// User dengan 2FA aktif menyelesaikan login pada POST /users/login/mfa dengan kode dari authenticator
+		mfaRequired, err := hasConfirmedTOTP(userController.totpModel.WithDB(userController.db.Primary()), currentUser.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if mfaRequired {
+			mfaChallengeResponse(c, mfaChallenge, currentUser.ID)
+			return
+		}
+
+		// membentuk akses token dan refresh token dengan informasi berupa Id dari pengguna saat ini,
+		// setiap login memulai family refresh token yang baru
+		authResponse, err := issueTokens(userController.refreshTokenModel.WithDB(userController.db.Primary()), webToken, refreshToken, currentUser.ID, "")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// mengambalikan response berupa akses token dan refresh token kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data:   authResponse,
+		})
+	}
+}
+
+func (userController *UserController) HandleRefresh(webToken helpers.IWebToken, refreshToken helpers.IRefreshToken) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan Token Refresh
+		// [x] Memvalidasi request berupa json
+		// [x] Mengambil refresh token terkait berdasarkan hash dari token pada request
+		// [x] Mencabut seluruh family apabila refresh token telah digunakan sebelumnya (reuse)
+		// [x] Memastikan refresh token belum dicabut atau kedaluwarsa dan user masih aktif
+		// [x] Dalam satu transaksi: menandai refresh token telah digunakan dan membuat token baru pada family yang sama
+		// [x] Mengembalikan respon berupa access token dan refresh token baru
+
+		var refreshRequest app.UserTokenRefreshRequest
+		if err := c.ShouldBindJSON(&refreshRequest); err != nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"json": "Invalid json format",
+				},
+			})
+			return
+		}
+
+		// Memvalidasi request json
+		msg, _ := userController.validator.Validate(refreshRequest)
+
+		if len(msg) != 0 {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data:   msg,
+			})
+			return
+		}
+
+		// Refresh token dibaca dari database primary, replica bisa saja belum mengetahui
+		// bahwa token tersebut baru saja digunakan.
+		primaryRefreshTokenModel := userController.refreshTokenModel.WithDB(userController.db.Primary())
+
+		// Mengambil refresh token berdasarkan hash dari token pada request
+		storedToken, err := primaryRefreshTokenModel.GetByHash(refreshToken.Hash(refreshRequest.RefreshToken))
 		if err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
-				c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				c.JSON(http.StatusUnauthorized, &app.JsendFailResponse{
 					Status: "fail",
 					Data: gin.H{
-						"message": "Email and password provided doesn't match",
+						"message": "Refresh token is invalid",
 					},
 				})
 				return
@@ -173,20 +453,193 @@ func (userController *UserController) HandleLogin(hasher helpers.IHasher, webTok
 			return
 		}
 
-		// Melakukan pengecekan password user saat ini (terhash) dengan password dari request (plaintext)
-		if !hasher.CheckHash(currentUser.Password, loginRequest.Password) {
+		// Refresh token yang digunakan kembali kemungkinan telah dicuri, seluruh token
+		// pada family yang sama dicabut sehingga pencuri maupun pemilik harus login ulang.
+		if storedToken.UsedAt != nil {
+			userController.revokeReusedFamily(c, primaryRefreshTokenModel, storedToken)
+			return
+		}
+
+		if storedToken.RevokedAt != nil {
+			c.JSON(http.StatusUnauthorized, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"message": "Refresh token has been revoked",
+				},
+			})
+			return
+		}
+
+		if time.Now().After(storedToken.ExpiresAt) {
 			c.JSON(http.StatusUnauthorized, &app.JsendFailResponse{
 				Status: "fail",
 				Data: gin.H{
-					"message": "Email and password provided doesn't match",
+					"message": "Refresh token has expired",
 				},
 			})
 			return
 		}
 
-		// membentuk akses token dengan informasi berupa Id dari pengguna saat ini
-		accessToken, err := webToken.GenerateToken(currentUser.ID)
+		// Memastikan pemilik refresh token masih ada dan tidak dinonaktifkan
+		currentUser, err := userController.model.WithDB(userController.db.Primary()).GetById(storedToken.UserID, false)
 		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusUnauthorized, &app.JsendFailResponse{
+					Status: "fail",
+					Data: gin.H{
+						"message": "Refresh token is invalid",
+					},
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if currentUser.DisabledAt != nil {
+			c.JSON(http.StatusForbidden, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"message": "This account has been disabled",
+				},
+			})
+			return
+		}
+
+		// Menandai refresh token telah digunakan dan membuat pasangan token baru pada family
+		// yang sama dalam satu transaksi.
+		var authResponse *app.UserAuthResponse
+		err = userController.db.WithTransaction(func(tx database.IDatabase) error {
+			txModel := userController.refreshTokenModel.WithDB(tx)
+
+			marked, err := txModel.MarkUsed(storedToken)
+			if err != nil {
+				return err
+			}
+			if !marked {
+				return errRefreshTokenReused
+			}
+
+			authResponse, err = issueTokens(txModel, webToken, refreshToken, currentUser.ID, storedToken.FamilyID)
+			return err
+		})
+		if errors.Is(err, errRefreshTokenReused) {
+			userController.revokeReusedFamily(c, primaryRefreshTokenModel, storedToken)
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mengembalikan response berupa access token dan refresh token baru
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data:   authResponse,
+		})
+	}
+}
+
+func (userController *UserController) revokeReusedFamily(c *gin.Context, refreshTokenModel models.IRefreshTokenModel, reusedToken *models.RefreshToken) {
+	if err := refreshTokenModel.RevokeFamily(reusedToken.FamilyID); err != nil {
+		c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+	log.Printf("refresh token reuse detected for user %d, token family revoked", reusedToken.UserID)
+
+	c.JSON(http.StatusUnauthorized, &app.JsendFailResponse{
+		Status: "fail",
+		Data: gin.H{
+			"message": "Refresh token has already been used, please log in again",
+		},
+	})
+}
+
+func (userController *UserController) HandleLogout(refreshToken helpers.IRefreshToken, revocationStore stores.IRevocationStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Logout
+		// [x] Memperoleh user dan claims token dari auth middleware
+		// [x] Mencabut akses token yang digunakan pada request
+		// [x] Mencabut family refresh token apabila refresh token disertakan pada request
+		// [x] Mengirimkan response kembali ke client.
+
+		currentUser := c.MustGet("currentUser").(*models.User)
+		currentClaims := c.MustGet("currentClaims").(*helpers.UserClaims)
+
+		// Body bersifat opsional, refresh token hanya dicabut apabila disertakan
+		var logoutRequest app.UserTokenRefreshRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&logoutRequest); err != nil {
+				c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+					Status: "fail",
+					Data: gin.H{
+						"json": "Invalid json format",
+					},
+				})
+				return
+			}
+		}
+
+		// Mencabut akses token saat ini berdasarkan jti sampai token tersebut kedaluwarsa
+		err := revocationStore.Revoke(currentClaims.ID, currentUser.ID, currentClaims.ExpiresAt.Time)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mencabut family dari refresh token milik user saat ini
+		if logoutRequest.RefreshToken != "" {
+			primaryRefreshTokenModel := userController.refreshTokenModel.WithDB(userController.db.Primary())
+			storedToken, err := primaryRefreshTokenModel.GetByHash(refreshToken.Hash(logoutRequest.RefreshToken))
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+					Status:  "error",
+					Message: err.Error(),
+				})
+				return
+			}
+			if storedToken != nil && storedToken.UserID == currentUser.ID {
+				if err := primaryRefreshTokenModel.RevokeFamily(storedToken.FamilyID); err != nil {
+					c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+						Status:  "error",
+						Message: err.Error(),
+					})
+					return
+				}
+			}
+		}
+
+		// Mengirimkan response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: gin.H{
+				"message": "Logged out",
+			},
+		})
+	}
+}
+
+func (userController *UserController) HandleLogoutAll(revocationStore stores.IRevocationStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Logout dari Seluruh Perangkat
+		// [x] Memperoleh user dari auth middleware
+		// [x] Mencabut seluruh akses token dan refresh token milik user
+		// [x] Mengirimkan response kembali ke client.
+
+		currentUser := c.MustGet("currentUser").(*models.User)
+
+		if err := userController.revokeAllTokens(currentUser.ID, revocationStore); err != nil {
 			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
 				Status:  "error",
 				Message: err.Error(),
@@ -194,31 +647,45 @@ func (userController *UserController) HandleLogin(hasher helpers.IHasher, webTok
 			return
 		}
 
-		// mengambalikan response berupa akses token kembali ke client
+		// Mengirimkan response kembali ke client
 		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
 			Status: "success",
-			Data: &app.UserAuthResponse{
-				AccessToken: accessToken,
+			Data: gin.H{
+				"message": "Logged out of all devices",
 			},
 		})
 	}
 }
 
-func (userController *UserController) HandleUpdate(hasher helpers.IHasher) gin.HandlerFunc {
+// revokeAllTokens mencabut seluruh akses token yang telah diterbitkan dan seluruh refresh token milik user.
+func (userController *UserController) revokeAllTokens(userId uint, revocationStore stores.IRevocationStore) error {
+	if err := revocationStore.RevokeAllForUser(userId); err != nil {
+		return err
+	}
+	return userController.refreshTokenModel.WithDB(userController.db.Primary()).RevokeByUser(userId)
+}
+
+func (userController *UserController) HandleUpdate(hasher helpers.IHasher, revocationStore stores.IRevocationStore, verificationToken helpers.IEmailVerificationToken, mailSender mailer.IMailer, verifyURL string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// NOTE: Langkah Kasus Penggunaan User Update
 		// [x] Memperoleh user dengan informasi token dari middleware
-		// [x] Memvalidasi request json
+		// [x] Memvalidasi request json, admin yang mengubah akun user lain hanya mengubah username dan email
 		// [x] Melakukan pengecekan antara password user saat ini dengan password lama yang dimasukan oleh user
 		// [x] Melakukan pengecekan email baru yang dimasukan oleh user
 		// [x] Melakukan hashing pada password baru yang dimasukan oleh user
 		// [x] Mengupdate user pada database
+		// [x] Mencabut seluruh token milik user apabila password berubah
+		// [x] Mengirimkan link verifikasi apabila email berubah
 		// [x] Mengambil informasi tentang photo yang yang terkait dengan user yang diupdate.
 		// [x] Membentuk response dari setiap photo yang terkait dengan user yang diupdate.
 		// [x] Mengirimkan response kembali ke client.
 
 		// Memperoleh user dari auth middleware
-		relatedUser := c.MustGet("requestedUser").(*models.User)
+		relatedUser := middlewares.RequestedUser.MustGet(c)
+		currentUser := c.MustGet("currentUser").(*models.User)
+
+		// Admin yang mengubah akun user lain tidak mengetahui password user tersebut, sehingga password tidak diubah
+		override := currentUser.ID != relatedUser.ID
 
 		var updateRequest app.UserUpdateRequest
 		if err := c.ShouldBindJSON(&updateRequest); err != nil {
@@ -232,10 +699,20 @@ func (userController *UserController) HandleUpdate(hasher helpers.IHasher) gin.H
 		}
 
 		// Memvalidasi request json dari user
-		msg, _ := userController.validator.Validate(updateRequest)
-
-		if updateRequest.NewPassword != updateRequest.ConfirmPassword {
-			msg["confirmPassword"] = "password must be matched with the new one"
+		var msg map[string]interface{}
+		if override {
+			msg, _ = userController.validator.Validate(app.AdminUserUpdateRequest{
+				Username: updateRequest.Username,
+				Email:    updateRequest.Email,
+			})
+			if updateRequest.OldPassword != "" || updateRequest.NewPassword != "" || updateRequest.ConfirmPassword != "" {
+				msg["newPassword"] = "the password of another user can't be changed, the user can reset it"
+			}
+		} else {
+			msg, _ = userController.validator.Validate(updateRequest)
+			if updateRequest.NewPassword != updateRequest.ConfirmPassword {
+				msg["confirmPassword"] = "password must be matched with the new one"
+			}
 		}
 
 		if len(msg) != 0 {
@@ -247,7 +724,7 @@ func (userController *UserController) HandleUpdate(hasher helpers.IHasher) gin.H
 		}
 
 		// Melakukan pengecekan antara password user saat ini dengan password lama yang dimasukan oleh user
-		if !hasher.CheckHash(relatedUser.Password, updateRequest.OldPassword) {
+		if !override && !hasher.CheckHash(currentUser.Password, updateRequest.OldPassword) {
 			c.JSON(http.StatusUnauthorized, &app.JsendFailResponse{
 				Status: "fail",
 				Data: gin.H{
@@ -257,9 +734,16 @@ func (userController *UserController) HandleUpdate(hasher helpers.IHasher) gin.H
 			return
 		}
 
+		// Pengecekan email, update dan pengambilan kembali user dilakukan pada database primary
+		// sehingga response berisi data yang baru saja diupdate.
+		primaryModel := userController.model.WithDB(userController.db.Primary())
+
 		// Melakukan pengecekan email apakah email baru yang dimasukan telah digunakan,
 		// Namun apabila email user saat ini sama dengan email yang ada pada request maka proses akan dilanjutkan
-		emailOwner, _ := userController.model.GetByEmail(updateRequest.Email, false)
+		emailOwner, _ := primaryModel.GetByEmail(updateRequest.Email, false)
+		if emailOwner == nil {
+			emailOwner, _ = primaryModel.GetTrashedByEmail(updateRequest.Email)
+		}
 		if emailOwner != nil {
 			if emailOwner.Email != relatedUser.Email {
 				c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
@@ -272,20 +756,26 @@ func (userController *UserController) HandleUpdate(hasher helpers.IHasher) gin.H
 			}
 		}
 
-		// Melakukan hashing pada password baru dari request
-		hashedPassword, err := hasher.HashString(updateRequest.NewPassword)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
-				Status:  "error",
-				Message: err.Error(),
-			})
-			return
-		}
+		passwordChanged := !override && updateRequest.NewPassword != updateRequest.OldPassword
+		emailChanged := updateRequest.Email != relatedUser.Email
 
-		updateRequest.NewPassword = hashedPassword
+		// Melakukan hashing pada password baru dari request, admin menyimpan kembali hash milik user
+		if override {
+			updateRequest.NewPassword = relatedUser.Password
+		} else {
+			hashedPassword, err := hasher.HashString(updateRequest.NewPassword)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+					Status:  "error",
+					Message: err.Error(),
+				})
+				return
+			}
+			updateRequest.NewPassword = hashedPassword
+		}
 
 		// Melakukan update pada user saat ini dengan informasi sesuai pada request
-		updatedUser, err := userController.model.UpdateUser(relatedUser, &updateRequest)
+		updatedUser, err := primaryModel.UpdateUser(relatedUser, &updateRequest)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
 				Status:  "fail",
@@ -294,8 +784,27 @@ func (userController *UserController) HandleUpdate(hasher helpers.IHasher) gin.H
 			return
 		}
 
+		// Token yang diterbitkan dengan password lama dicabut, termasuk token yang digunakan
+		// pada request ini sehingga user harus login kembali.
+		if passwordChanged {
+			if err := userController.revokeAllTokens(updatedUser.ID, revocationStore); err != nil {
+				c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+					Status:  "error",
+					Message: err.Error(),
+				})
+				return
+			}
+		}
+
+		// Email baru harus diverifikasi kembali, kegagalan pengiriman hanya dicatat pada log
+		if emailChanged {
+			if err := userController.sendVerificationEmail(verificationToken, mailSender, verifyURL, updatedUser); err != nil {
+				log.Printf("email verification: failed to send email to user %d: %s", updatedUser.ID, err.Error())
+			}
+		}
+
 		// mengambil seluruh photo yang terkait dengan user saat ini.
-		populatedUser, err := userController.model.GetById(updatedUser.ID, true)
+		populatedUser, err := primaryModel.GetById(updatedUser.ID, true)
 		if err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
@@ -331,12 +840,13 @@ func (userController *UserController) HandleUpdate(hasher helpers.IHasher) gin.H
 		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
 			Status: "success",
 			Data: &app.UserDetailGeneralResponse{
-				ID:        updatedUser.ID,
-				Username:  updatedUser.Username,
-				Email:     updatedUser.Email,
-				Photos:    &photosResponse,
-				CreatedAt: updatedUser.CreatedAt,
-				UpdatedAt: updatedUser.UpdatedAt,
+				ID:              updatedUser.ID,
+				Username:        updatedUser.Username,
+				Email:           updatedUser.Email,
+				EmailVerifiedAt: updatedUser.EmailVerifiedAt,
+				Photos:          &photosResponse,
+				CreatedAt:       updatedUser.CreatedAt,
+				UpdatedAt:       updatedUser.UpdatedAt,
 			},
 		})
 	}
@@ -346,18 +856,30 @@ func (userController *UserController) HandleDelete() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// NOTE: Langkah Kasus Penggunaan User Delete
 		// [x] Memperoleh user dengan informasi token dari middleware
-		// [x] Mengambil informasi tentang photo yang yang terkait dengan user yang akan dihapus.
-		// [x] Menghapus user terkait
-		// [x] Menghapus setiap file photo yang terkait dengan user saat ini.
+		// [x] Dalam satu transaksi: mengambil informasi tentang photo yang yang terkait dengan user
+		//     dan memindahkan user beserta photonya ke trash (soft delete).
 		// [x] Membentuk response dari setiap photo yang terkait dengan user yang dihapus.
 		// [x] Mengirimkan response kembali ke client.
 
 		// Memperoleh user dari token pada auth middleware
-		relatedUser := c.MustGet("requestedUser").(*models.User)
+		relatedUser := middlewares.RequestedUser.MustGet(c)
 
-		// Mengambil user dari database dan juga relasinya dengan photo dengan id dari user
-		// yang diperoleh dari middleware
-		populatedUser, err := userController.model.GetById(relatedUser.ID, true)
+		// Mengambil user beserta photonya dan memindahkan user ke trash dalam satu transaksi,
+		// file photo tetap disimpan sampai user dihapus permanen oleh purge job.
+		var populatedUser *models.User
+		var deletedUser *models.User
+		err := userController.db.WithTransaction(func(tx database.IDatabase) error {
+			txModel := userController.model.WithDB(tx)
+
+			var err error
+			populatedUser, err = txModel.GetById(relatedUser.ID, true)
+			if err != nil {
+				return err
+			}
+
+			deletedUser, err = txModel.DeleteUser(populatedUser)
+			return err
+		})
 		if err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
@@ -375,8 +897,72 @@ func (userController *UserController) HandleDelete() gin.HandlerFunc {
 			return
 		}
 
-		// Menghapus user yang diperoleh dari database
-		deletedUser, err := userController.model.DeleteUser(populatedUser)
+		// Membentuk response dari setiap photo yang telah dipindahkan ke trash.
+		photosResponse := []app.PhotoGeneralResponse{}
+		for _, photo := range populatedUser.Photos {
+			photosResponse = append(photosResponse, app.PhotoGeneralResponse{
+				ID:        photo.ID,
+				UserID:    photo.UserID,
+				Title:     photo.Title,
+				Caption:   photo.Caption,
+				PhotoUrl:  photo.PhotoUrl,
+				CreatedAt: photo.CreatedAt,
+				UpdatedAt: photo.UpdatedAt,
+			})
+		}
+
+		// Mengembalikan response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: &app.UserDetailGeneralResponse{
+				ID:              deletedUser.ID,
+				Username:        deletedUser.Username,
+				Email:           deletedUser.Email,
+				EmailVerifiedAt: deletedUser.EmailVerifiedAt,
+				Photos:          &photosResponse,
+				CreatedAt:       deletedUser.CreatedAt,
+				UpdatedAt:       deletedUser.UpdatedAt,
+			},
+		})
+	}
+}
+
+func (userController *UserController) HandleRestore(hasher helpers.IHasher, loginThrottle stores.ILoginThrottle) gin.HandlerFunc {
+	dummyHash := newDummyHash(hasher)
+
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Restore
+		// [x] Memvalidasi request berupa json
+		// [x] Mencatat percobaan seperti login, email atau alamat IP yang masih harus menunggu ditolak
+		// [x] Mengambil user pada trash dengan email yang diperoleh dari request
+		// [x] Melakukan komparasi pada password user dan password dari request
+		// [x] Mengeluarkan user beserta photo yang dihapus bersamanya dari trash
+		// [x] Mengirimkan response kembali ke client.
+
+		var restoreRequest app.UserLoginRequest
+		if err := c.ShouldBindJSON(&restoreRequest); err != nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"json": "Invalid json format",
+				},
+			})
+			return
+		}
+
+		// Memvalidasi request json
+		msg, _ := userController.validator.Validate(restoreRequest)
+
+		if len(msg) != 0 {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data:   msg,
+			})
+			return
+		}
+
+		// Password dapat ditebak melalui restore seperti melalui login, sehingga percobaannya dibatasi bersama
+		wait, err := loginThrottle.Attempt(restoreRequest.Email, c.ClientIP())
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
 				Status:  "error",
@@ -384,45 +970,399 @@ func (userController *UserController) HandleDelete() gin.HandlerFunc {
 			})
 			return
 		}
+		if wait > 0 {
+			abortTooManyAttempts(c, wait)
+			return
+		}
 
-		// Menghapus setiap photo yang berkaitan dengan user yang dihapus
-		photosResponse := []app.PhotoGeneralResponse{}
-		for _, photo := range populatedUser.Photos {
+		primaryModel := userController.model.WithDB(userController.db.Primary())
+
+		// Mengambil user pada trash dengan email yang diperoleh dari request
+		trashedUser, err := primaryModel.GetTrashedByEmail(restoreRequest.Email)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
 
-			// menghapus setiap file photo yang berkaitan dengan user yang dihapus
-			strSliceFileLoc := strings.Split(photo.PhotoUrl, "/")
-			oldFilename := strSliceFileLoc[len(strSliceFileLoc)-1]
-			err = os.Remove(fmt.Sprintf("./static/photos/%s", oldFilename))
+		// Melakukan pengecekan password user (terhash) dengan password dari request (plaintext),
+		// email yang tidak ada pada trash dicek dengan hash pembanding
+		passwordHash := dummyHash()
+		if trashedUser != nil {
+			passwordHash = trashedUser.Password
+		}
+		if !hasher.CheckHash(passwordHash, restoreRequest.Password) || trashedUser == nil {
+			if err := loginThrottle.Failure(c.ClientIP()); err != nil {
+				log.Printf("restore: failed to count the failed attempt: %s", err.Error())
+			}
+			c.JSON(http.StatusUnauthorized, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"message": "Email and password provided doesn't match any deleted account",
+				},
+			})
+			return
+		}
+		if err := loginThrottle.Success(restoreRequest.Email); err != nil {
+			log.Printf("restore: failed to reset the failed attempts of user %d: %s", trashedUser.ID, err.Error())
+		}
+
+		// Mengeluarkan user beserta photo yang dihapus bersamanya dari trash
+		restoredUser, err := primaryModel.RestoreUser(trashedUser)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mengirimkan response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: &app.UserGeneralResponse{
+				ID:        restoredUser.ID,
+				Username:  restoredUser.Username,
+				Email:     restoredUser.Email,
+				CreatedAt: restoredUser.CreatedAt,
+				UpdatedAt: restoredUser.UpdatedAt,
+			},
+		})
+	}
+}
+
+func (userController *UserController) HandleForgotPassword(resetQueue jobs.IPasswordResetQueue, loginThrottle stores.ILoginThrottle) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Forgot Password
+		// [x] Memvalidasi request berupa json
+		// [x] Membatasi permintaan reset dari email dan alamat IP yang sama
+		// [x] Memasukan email ke antrian, user diambil dan token reset dikirimkan di background
+		// [x] Mengirimkan response yang sama baik email terdaftar maupun tidak
+
+		var forgotRequest app.UserForgotPasswordRequest
+		if err := c.ShouldBindJSON(&forgotRequest); err != nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"json": "Invalid json format",
+				},
+			})
+			return
+		}
+
+		// Memvalidasi request json
+		msg, _ := userController.validator.Validate(forgotRequest)
+
+		if len(msg) != 0 {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data:   msg,
+			})
+			return
+		}
+
+		// Email yang tidak terdaftar juga dihitung sehingga batasnya tidak membedakan email yang terdaftar
+		wait, err := loginThrottle.ResetAttempt(forgotRequest.Email, c.ClientIP())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if wait > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"message": "Too many password reset requests, please try again later",
+				},
+			})
+			return
+		}
+
+		// Pengambilan user, pembuatan token dan pengiriman email dilakukan setelah response dikirim
+		// sehingga waktu response tidak membedakan email yang terdaftar dan yang tidak.
+		if !resetQueue.Enqueue(forgotRequest.Email) {
+			log.Printf("password reset: queue is full, request dropped")
+		}
+
+		c.JSON(http.StatusAccepted, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: gin.H{
+				"message": "If the email belongs to an account, a password reset link has been sent to it",
+			},
+		})
+	}
+}
+
+func (userController *UserController) HandleResetPassword(hasher helpers.IHasher, resetToken helpers.IOpaqueToken, revocationStore stores.IRevocationStore, loginThrottle stores.ILoginThrottle) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Reset Password
+		// [x] Memvalidasi request berupa json
+		// [x] Mengambil token reset berdasarkan hash dari token pada request
+		// [x] Memastikan token reset belum digunakan dan belum kedaluwarsa
+		// [x] Melakukan hashing pada password baru
+		// [x] Dalam satu transaksi: menandai token telah digunakan, mengupdate password dan membatalkan token reset lain
+		// [x] Mencabut seluruh token milik user
+		// [x] Membuka kunci login dari email user
+		// [x] Mengirimkan response kembali ke client.
+
+		var resetRequest app.UserResetPasswordRequest
+		if err := c.ShouldBindJSON(&resetRequest); err != nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"json": "Invalid json format",
+				},
+			})
+			return
+		}
+
+		// Memvalidasi request json
+		msg, _ := userController.validator.Validate(resetRequest)
+
+		if resetRequest.NewPassword != resetRequest.ConfirmPassword {
+			msg["confirmPassword"] = "password must be matched with the new one"
+		}
+
+		if len(msg) != 0 {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data:   msg,
+			})
+			return
+		}
+
+		invalidTokenResponse := &app.JsendFailResponse{
+			Status: "fail",
+			Data: gin.H{
+				"token": "Reset token is invalid or has expired",
+			},
+		}
+
+		// Mengambil token reset dari database primary berdasarkan hash dari token pada request
+		storedToken, err := userController.passwordResetModel.WithDB(userController.db.Primary()).GetByHash(resetToken.Hash(resetRequest.Token))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusBadRequest, invalidTokenResponse)
+				return
+			}
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if storedToken.UsedAt != nil || time.Now().After(storedToken.ExpiresAt) {
+			c.JSON(http.StatusBadRequest, invalidTokenResponse)
+			return
+		}
+
+		// Melakukan hashing pada password baru dari request
+		hashedPassword, err := hasher.HashString(resetRequest.NewPassword)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Token ditandai telah digunakan dan password diupdate dalam satu transaksi, request lain
+		// dengan token yang sama akan gagal pada MarkUsed.
+		var resetEmail string
+		err = userController.db.WithTransaction(func(tx database.IDatabase) error {
+			txResetModel := userController.passwordResetModel.WithDB(tx)
+			marked, err := txResetModel.MarkUsed(storedToken)
 			if err != nil {
+				return err
+			}
+			if !marked {
+				return gorm.ErrRecordNotFound
+			}
+
+			relatedUser, err := userController.model.WithDB(tx).GetById(storedToken.UserID, false)
+			if err != nil {
+				return err
+			}
+			if _, err := userController.model.WithDB(tx).UpdatePassword(relatedUser, hashedPassword); err != nil {
+				return err
+			}
+			resetEmail = relatedUser.Email
+			return txResetModel.InvalidateByUser(relatedUser.ID)
+		})
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusBadRequest, invalidTokenResponse)
+				return
+			}
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Token yang diterbitkan dengan password lama dicabut
+		if err := userController.revokeAllTokens(storedToken.UserID, revocationStore); err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// User yang terkunci karena login yang gagal dapat langsung login dengan password baru
+		if err := loginThrottle.Unlock(storedToken.UserID, resetEmail); err != nil {
+			log.Printf("password reset: failed to unlock the login of user %d: %s", storedToken.UserID, err.Error())
+		}
+
+		// Mengirimkan response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: gin.H{
+				"message": "Password has been reset, please login with the new password",
+			},
+		})
+	}
+}
+
+func (userController *UserController) HandleVerifyEmail(verificationToken helpers.IEmailVerificationToken) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Verify Email
+		// [x] Memverifikasi token dari query string
+		// [x] Mengambil user terkait dengan id pada token
+		// [x] Memastikan email pada token sama dengan email user saat ini
+		// [x] Menandai email user telah terverifikasi
+		// [x] Mengirimkan response kembali ke client.
+
+		invalidTokenResponse := &app.JsendFailResponse{
+			Status: "fail",
+			Data: gin.H{
+				"token": "Verification link is invalid or has expired",
+			},
+		}
+
+		tokenStr := c.Query("token")
+		if tokenStr == "" {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"token": "token is required",
+				},
+			})
+			return
+		}
+
+		// Memverifikasi signature dan masa berlaku token
+		claims, err := verificationToken.ParseToken(tokenStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, invalidTokenResponse)
+			return
+		}
+
+		primaryModel := userController.model.WithDB(userController.db.Primary())
+
+		// Mengambil user terkait dengan id pada token
+		relatedUser, err := primaryModel.GetById(claims.UserID, false)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusBadRequest, invalidTokenResponse)
+				return
+			}
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Link yang dikirim ke email lama tidak dapat memverifikasi email baru
+		if relatedUser.Email != claims.Email {
+			c.JSON(http.StatusBadRequest, invalidTokenResponse)
+			return
+		}
+
+		if relatedUser.EmailVerifiedAt == nil {
+			// Menandai email user telah terverifikasi
+			if _, err := primaryModel.MarkEmailVerified(relatedUser); err != nil {
 				c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
 					Status:  "error",
 					Message: err.Error(),
 				})
 				return
 			}
+		}
 
-			// Membentuk response dari setiap photo yang telah dihapus dari database.
-			photosResponse = append(photosResponse, app.PhotoGeneralResponse{
-				ID:        photo.ID,
-				UserID:    photo.UserID,
-				Title:     photo.Title,
-				Caption:   photo.Caption,
-				PhotoUrl:  photo.PhotoUrl,
-				CreatedAt: photo.CreatedAt,
-				UpdatedAt: photo.UpdatedAt,
+		// Mengirimkan response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: gin.H{
+				"message": "Email address has been verified",
+			},
+		})
+	}
+}
+
+func (userController *UserController) HandleResendVerification(verificationToken helpers.IEmailVerificationToken, mailSender mailer.IMailer, verifyURL string, loginThrottle stores.ILoginThrottle) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Resend Verification
+		// [x] Memperoleh user dengan informasi token dari middleware
+		// [x] Memastikan email user belum terverifikasi
+		// [x] Membatasi jumlah permintaan dari user dan alamat IP
+		// [x] Mengirimkan link verifikasi baru ke email user
+		// [x] Mengirimkan response kembali ke client.
+
+		// Memperoleh user dari auth middleware
+		currentUser := c.MustGet("currentUser").(*models.User)
+
+		if currentUser.EmailVerifiedAt != nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"email": "Email address is already verified",
+				},
 			})
+			return
 		}
 
-		// Mengembalikan response kembali ke client
-		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+		// Setiap permintaan mengirimkan email, sehingga jumlahnya dibatasi seperti permintaan reset password
+		wait, err := loginThrottle.ResendAttempt(currentUser.ID, c.ClientIP())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if wait > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"message": "Too many verification email requests, please try again later",
+				},
+			})
+			return
+		}
+
+		// Mengirimkan link verifikasi baru ke email user
+		if err := userController.sendVerificationEmail(verificationToken, mailSender, verifyURL, currentUser); err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mengirimkan response kembali ke client
+		c.JSON(http.StatusAccepted, &app.JsendSuccessResponse{
 			Status: "success",
-			Data: &app.UserDetailGeneralResponse{
-				ID:        deletedUser.ID,
-				Username:  deletedUser.Username,
-				Email:     deletedUser.Email,
-				Photos:    &photosResponse,
-				CreatedAt: deletedUser.CreatedAt,
-				UpdatedAt: deletedUser.UpdatedAt,
+			Data: gin.H{
+				"message": "A verification link has been sent to your email address",
 			},
 		})
 	}
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/config"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/jobs"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/mailer"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/middlewares"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/stores"
+)
+
+func TestAdminUpdateKeepsPasswordOfOtherUser(t *testing.T) {
+	db := newTestDB(t)
+	cfg := config.Default()
+	keySet := newTestKeySet(t)
+	revocationStore := stores.NewRevocationStore(models.NewTokenRevocationModel(db), time.Hour, 0)
+	userModel := models.NewUserModel(db)
+	userController := NewUserController(db, userModel, models.NewRefreshTokenModel(db), models.NewPasswordResetModel(db), models.NewTOTPModel(db), helpers.NewValidator())
+
+	admin := createTestUser(t, db, "admin@example.com")
+	user := createTestUser(t, db, "user@example.com")
+	passwordHash := user.Password
+	issuedAt := time.Now().Add(-time.Minute)
+
+	route := gin.New()
+	route.PUT("/users/:userId", asUser(admin), func(c *gin.Context) {
+		middlewares.RequestedUser.Set(c, user)
+	}, userController.HandleUpdate(cfg.Password.Hasher(), revocationStore, helpers.NewEmailVerificationToken(keySet, cfg.JWT.Issuer, time.Hour), mailer.NewLogMailer("noreply@example.com"), "http://localhost/verify"))
+
+	// The password of another user can't be set by the admin
+	if code := doJSON(t, route, http.MethodPut, "/users/1", gin.H{
+		"username":        "renamed",
+		"email":           "user@example.com",
+		"oldPassword":     "admin-password",
+		"newPassword":     "chosen-by-admin",
+		"confirmPassword": "chosen-by-admin",
+	}, nil); code != http.StatusBadRequest {
+		t.Fatalf("update with a password: expected %d, got %d", http.StatusBadRequest, code)
+	}
+
+	if code := doJSON(t, route, http.MethodPut, "/users/1", gin.H{
+		"username": "renamed",
+		"email":    "user@example.com",
+	}, nil); code != http.StatusOK {
+		t.Fatalf("update: expected %d, got %d", http.StatusOK, code)
+	}
+
+	updated, err := userModel.GetById(user.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Username != "renamed" {
+		t.Fatalf("expected the username to be updated, got %q", updated.Username)
+	}
+	if updated.Password != passwordHash {
+		t.Fatal("expected the password of the user to be kept")
+	}
+	if revocationStore.IsRevoked("", user.ID, issuedAt) {
+		t.Fatal("expected the tokens of the user to stay valid")
+	}
+}
+
+func newForgotPasswordTestRouter(t *testing.T, db database.IDatabase, mailDir string) (*gin.Engine, jobs.IPasswordResetQueue) {
+	userController := NewUserController(db, models.NewUserModel(db), models.NewRefreshTokenModel(db), models.NewPasswordResetModel(db), models.NewTOTPModel(db), helpers.NewValidator())
+	resetQueue := jobs.NewPasswordResetQueue(db, helpers.NewOpaqueToken(time.Hour), mailer.NewFileMailer("noreply@example.com", mailDir), "")
+	loginThrottle := stores.NewLoginThrottle(stores.NewMemoryLoginAttempts(), stores.LoginThrottlePolicy{
+		MaxAttempts:        5,
+		IPMaxAttempts:      50,
+		LockoutDuration:    time.Hour,
+		Window:             time.Hour,
+		ResetMaxRequests:   2,
+		ResetIPMaxRequests: 3,
+	})
+
+	route := gin.New()
+	route.POST("/password/forgot", userController.HandleForgotPassword(resetQueue, loginThrottle))
+	return route, resetQueue
+}
+
+func TestForgotPasswordSendsTheTokenInTheBackground(t *testing.T) {
+	db := newTestDB(t)
+	mailDir := t.TempDir()
+	route, resetQueue := newForgotPasswordTestRouter(t, db, mailDir)
+	createTestUser(t, db, "user@example.com")
+
+	// Both emails get the same answer, only the known one gets a token
+	for _, email := range []string{"user@example.com", "nobody@example.com"} {
+		if code := doJSON(t, route, http.MethodPost, "/password/forgot", gin.H{"email": email}, nil); code != http.StatusAccepted {
+			t.Fatalf("forgot %s: expected %d, got %d", email, http.StatusAccepted, code)
+		}
+	}
+	resetQueue.Start()
+	resetQueue.Stop()
+
+	files, err := os.ReadDir(mailDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || !strings.HasSuffix(files[0].Name(), "user_at_example.com.eml") {
+		t.Fatalf("expected a single email to user@example.com, got %v", files)
+	}
+	content, err := os.ReadFile(filepath.Join(mailDir, files[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "POST /users/password/reset") {
+		t.Fatalf("expected a reset token, got %s", content)
+	}
+}
+
+func TestForgotPasswordLimitsTheRequests(t *testing.T) {
+	db := newTestDB(t)
+	route, _ := newForgotPasswordTestRouter(t, db, t.TempDir())
+
+	for i := 0; i < 2; i++ {
+		if code := doJSON(t, route, http.MethodPost, "/password/forgot", gin.H{"email": "user@example.com"}, nil); code != http.StatusAccepted {
+			t.Fatalf("request %d: expected %d, got %d", i+1, http.StatusAccepted, code)
+		}
+	}
+	if code := doJSON(t, route, http.MethodPost, "/password/forgot", gin.H{"email": "user@example.com"}, nil); code != http.StatusTooManyRequests {
+		t.Fatalf("email over the limit: expected %d, got %d", http.StatusTooManyRequests, code)
+	}
+	// The refused request isn't charged to the address, which still has one left
+	if code := doJSON(t, route, http.MethodPost, "/password/forgot", gin.H{"email": "other@example.com"}, nil); code != http.StatusAccepted {
+		t.Fatalf("other email: expected %d, got %d", http.StatusAccepted, code)
+	}
+	if code := doJSON(t, route, http.MethodPost, "/password/forgot", gin.H{"email": "third@example.com"}, nil); code != http.StatusTooManyRequests {
+		t.Fatalf("address over the limit: expected %d, got %d", http.StatusTooManyRequests, code)
+	}
+}
+
+func TestResendVerificationLimitsTheRequests(t *testing.T) {
+	db := newTestDB(t)
+	cfg := config.Default()
+	user := createTestUser(t, db, "user@example.com")
+	userController := NewUserController(db, models.NewUserModel(db), models.NewRefreshTokenModel(db), models.NewPasswordResetModel(db), models.NewTOTPModel(db), helpers.NewValidator())
+	loginThrottle := stores.NewLoginThrottle(stores.NewMemoryLoginAttempts(), stores.LoginThrottlePolicy{
+		MaxAttempts:        5,
+		IPMaxAttempts:      50,
+		LockoutDuration:    time.Hour,
+		Window:             time.Hour,
+		ResetMaxRequests:   2,
+		ResetIPMaxRequests: 20,
+	})
+
+	route := gin.New()
+	route.POST("/email/verify/resend", asUser(user), userController.HandleResendVerification(helpers.NewEmailVerificationToken(newTestKeySet(t), cfg.JWT.Issuer, time.Hour), mailer.NewLogMailer("noreply@example.com"), "http://localhost/verify", loginThrottle))
+
+	for i := 0; i < 2; i++ {
+		if code := doJSON(t, route, http.MethodPost, "/email/verify/resend", nil, nil); code != http.StatusAccepted {
+			t.Fatalf("request %d: expected %d, got %d", i+1, http.StatusAccepted, code)
+		}
+	}
+	if code := doJSON(t, route, http.MethodPost, "/email/verify/resend", nil, nil); code != http.StatusTooManyRequests {
+		t.Fatalf("request over the limit: expected %d, got %d", http.StatusTooManyRequests, code)
+	}
+}
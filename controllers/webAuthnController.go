@@ -0,0 +1,559 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/app"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/stores"
+	"gorm.io/gorm"
+)
+
+type IWebAuthnController interface {
+	HandleBeginRegistration(relyingParty *webauthn.WebAuthn, sessionToken helpers.IWebAuthnSessionToken) gin.HandlerFunc
+	HandleFinishRegistration(relyingParty *webauthn.WebAuthn, sessionToken helpers.IWebAuthnSessionToken, revocationStore stores.IRevocationStore) gin.HandlerFunc
+	HandleFetchCredentials() gin.HandlerFunc
+	HandleDeleteCredential() gin.HandlerFunc
+	HandleBeginLogin(relyingParty *webauthn.WebAuthn, sessionToken helpers.IWebAuthnSessionToken) gin.HandlerFunc
+	HandleFinishLogin(relyingParty *webauthn.WebAuthn, sessionToken helpers.IWebAuthnSessionToken, webToken helpers.IWebToken, refreshToken helpers.IRefreshToken, revocationStore stores.IRevocationStore) gin.HandlerFunc
+}
+
+type WebAuthnController struct {
+	db                database.IDatabase
+	userModel         models.IUserModel
+	credentialModel   models.IWebAuthnCredentialModel
+	refreshTokenModel models.IRefreshTokenModel
+	validator         helpers.IValidator
+}
+
+func NewWebAuthnController(db database.IDatabase, userModel models.IUserModel, credentialModel models.IWebAuthnCredentialModel, refreshTokenModel models.IRefreshTokenModel, validator helpers.IValidator) IWebAuthnController {
+	return &WebAuthnController{
+		db:                db,
+		userModel:         userModel,
+		credentialModel:   credentialModel,
+		refreshTokenModel: refreshTokenModel,
+		validator:         validator,
+	}
+}
+
+// webAuthnUser menghubungkan user beserta passkey miliknya dengan interface webauthn.User,
+// user handle berupa id user sehingga user dapat ditemukan kembali pada login tanpa email.
+type webAuthnUser struct {
+	user        *models.User
+	credentials []models.WebAuthnCredential
+}
+
+func (wu *webAuthnUser) WebAuthnID() []byte {
+	return []byte(strconv.FormatUint(uint64(wu.user.ID), 10))
+}
+
+func (wu *webAuthnUser) WebAuthnName() string {
+	return wu.user.Email
+}
+
+func (wu *webAuthnUser) WebAuthnDisplayName() string {
+	return wu.user.Username
+}
+
+func (wu *webAuthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+func (wu *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(wu.credentials))
+	for _, credential := range wu.credentials {
+		credentials = append(credentials, credential.WebAuthn())
+	}
+	return credentials
+}
+
+// loadWebAuthnUser mengambil passkey milik user dari database primary.
+func (webAuthnController *WebAuthnController) loadWebAuthnUser(user *models.User) (*webAuthnUser, error) {
+	credentials, err := webAuthnController.credentialModel.WithDB(webAuthnController.db.Primary()).GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &webAuthnUser{
+		user:        user,
+		credentials: credentials,
+	}, nil
+}
+
+func credentialResponse(credential *models.WebAuthnCredential) *app.UserWebAuthnCredentialResponse {
+	transports := []string{}
+	if credential.Transports != "" {
+		transports = strings.Split(credential.Transports, ",")
+	}
+	return &app.UserWebAuthnCredentialResponse{
+		ID:             credential.ID,
+		Name:           credential.Name,
+		Transports:     transports,
+		BackupEligible: credential.BackupEligible,
+		BackupState:    credential.BackupState,
+		LastUsedAt:     credential.LastUsedAt,
+		CreatedAt:      credential.CreatedAt,
+	}
+}
+
+func (webAuthnController *WebAuthnController) HandleBeginRegistration(relyingParty *webauthn.WebAuthn, sessionToken helpers.IWebAuthnSessionToken) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Begin Passkey Registration
+		// [x] Memperoleh user dengan informasi token dari middleware
+		// [x] Membuat opsi registrasi, passkey yang telah terdaftar tidak dapat didaftarkan kembali
+		// [x] Menyimpan session ceremony pada session token
+		// [x] Mengirimkan opsi dan session token kembali ke client.
+
+		// Memperoleh user dari auth middleware
+		currentUser := c.MustGet("currentUser").(*models.User)
+
+		relatedUser, err := webAuthnController.loadWebAuthnUser(currentUser)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Membuat opsi registrasi, authenticator menolak membuat passkey kedua untuk user yang sama
+		exclusions := make([]protocol.CredentialDescriptor, 0, len(relatedUser.credentials))
+		for _, credential := range relatedUser.WebAuthnCredentials() {
+			exclusions = append(exclusions, credential.Descriptor())
+		}
+		creation, session, err := relyingParty.BeginRegistration(relatedUser, webauthn.WithExclusions(exclusions))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Session ceremony disimpan pada token yang ditandatangani sehingga tidak disimpan pada server
+		token, err := sessionToken.GenerateToken(helpers.WebAuthnRegistration, currentUser.ID, session)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mengirimkan opsi dan session token kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: &app.UserWebAuthnBeginResponse{
+				Options:      creation,
+				SessionToken: token,
+			},
+		})
+	}
+}
+
+func (webAuthnController *WebAuthnController) HandleFinishRegistration(relyingParty *webauthn.WebAuthn, sessionToken helpers.IWebAuthnSessionToken, revocationStore stores.IRevocationStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Finish Passkey Registration
+		// [x] Memperoleh user dengan informasi token dari middleware
+		// [x] Memvalidasi request json
+		// [x] Memverifikasi session token milik user saat ini
+		// [x] Memverifikasi attestation dari authenticator
+		// [x] Menyimpan passkey pada database
+		// [x] Membatalkan session token sehingga hanya dapat digunakan satu kali
+		// [x] Mengirimkan passkey kembali ke client.
+
+		// Memperoleh user dari auth middleware
+		currentUser := c.MustGet("currentUser").(*models.User)
+
+		var registerRequest app.UserWebAuthnRegisterRequest
+		if err := c.ShouldBindJSON(&registerRequest); err != nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"json": "Invalid json format",
+				},
+			})
+			return
+		}
+
+		// Memvalidasi request json
+		msg, _ := webAuthnController.validator.Validate(registerRequest)
+
+		if len(registerRequest.Credential) == 0 {
+			msg["credential"] = "credential is required"
+		}
+
+		if len(msg) != 0 {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data:   msg,
+			})
+			return
+		}
+
+		// Session token harus diterbitkan untuk user saat ini dan belum pernah digunakan
+		claims, err := sessionToken.ParseToken(helpers.WebAuthnRegistration, registerRequest.SessionToken)
+		if err != nil || claims.UserID != currentUser.ID || revocationStore.IsRevoked(claims.ID, claims.UserID, claims.IssuedAt.Time) {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"sessionToken": "Session token is invalid or has expired",
+				},
+			})
+			return
+		}
+
+		relatedUser, err := webAuthnController.loadWebAuthnUser(currentUser)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Memverifikasi attestation dari authenticator terhadap challenge pada session
+		parsedResponse, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(registerRequest.Credential))
+		var credential *webauthn.Credential
+		if err == nil {
+			credential, err = relyingParty.CreateCredential(relatedUser, claims.Session, parsedResponse)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"credential": "Passkey could not be verified",
+				},
+			})
+			return
+		}
+
+		// Passkey yang telah terdaftar tidak dapat didaftarkan kembali, termasuk oleh user lain
+		_, err = webAuthnController.credentialModel.WithDB(webAuthnController.db.Primary()).GetByCredentialID(credential.ID)
+		if err == nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"credential": "Passkey is already registered",
+				},
+			})
+			return
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Menyimpan passkey pada database
+		name := registerRequest.Name
+		if name == "" {
+			name = "Passkey"
+		}
+		newCredential, err := webAuthnController.credentialModel.WithDB(webAuthnController.db.Primary()).CreateCredential(currentUser.ID, name, credential)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Session token hanya dapat digunakan satu kali
+		if err := revocationStore.Revoke(claims.ID, claims.UserID, claims.ExpiresAt.Time); err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mengirimkan passkey kembali ke client
+		c.JSON(http.StatusCreated, &app.JsendSuccessResponse{
+			Status: "success",
+			Data:   credentialResponse(newCredential),
+		})
+	}
+}
+
+func (webAuthnController *WebAuthnController) HandleFetchCredentials() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Fetch Passkeys
+		// [x] Memperoleh user dengan informasi token dari middleware
+		// [x] Mengambil seluruh passkey milik user
+		// [x] Mengirimkan response kembali ke client.
+
+		// Memperoleh user dari auth middleware
+		currentUser := c.MustGet("currentUser").(*models.User)
+
+		// Mengambil seluruh passkey milik user
+		credentials, err := webAuthnController.credentialModel.GetByUser(currentUser.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		credentialsResponse := []app.UserWebAuthnCredentialResponse{}
+		for i := range credentials {
+			credentialsResponse = append(credentialsResponse, *credentialResponse(&credentials[i]))
+		}
+
+		// Mengirimkan response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data:   credentialsResponse,
+		})
+	}
+}
+
+func (webAuthnController *WebAuthnController) HandleDeleteCredential() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Delete Passkey
+		// [x] Memperoleh user dengan informasi token dari middleware
+		// [x] Mengambil passkey sesuai id pada parameter, passkey milik user lain dianggap tidak ada
+		// [x] Menghapus passkey dari database
+		// [x] Mengirimkan response kembali ke client.
+
+		// Memperoleh user dari auth middleware
+		currentUser := c.MustGet("currentUser").(*models.User)
+
+		parsedId, err := strconv.ParseUint(c.Param("credentialId"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"credential_id": "Invalid credential ID",
+				},
+			})
+			return
+		}
+
+		primaryModel := webAuthnController.credentialModel.WithDB(webAuthnController.db.Primary())
+
+		// Mengambil passkey sesuai id pada parameter
+		credential, err := primaryModel.GetById(uint(parsedId))
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if credential == nil || credential.UserID != currentUser.ID {
+			c.JSON(http.StatusNotFound, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"credential": "There's no passkey found related with provided credential id",
+				},
+			})
+			return
+		}
+
+		// Menghapus passkey dari database
+		deletedCredential, err := primaryModel.DeleteCredential(credential)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mengirimkan response kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data:   credentialResponse(deletedCredential),
+		})
+	}
+}
+
+func (webAuthnController *WebAuthnController) HandleBeginLogin(relyingParty *webauthn.WebAuthn, sessionToken helpers.IWebAuthnSessionToken) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Begin Passkey Login
+		// [x] Membuat opsi login tanpa daftar passkey, authenticator memilih passkey milik user
+		// [x] Menyimpan session ceremony pada session token
+		// [x] Mengirimkan opsi dan session token kembali ke client.
+
+		// Login tanpa email, user diketahui dari user handle pada jawaban authenticator
+		assertion, session, err := relyingParty.BeginDiscoverableLogin()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		token, err := sessionToken.GenerateToken(helpers.WebAuthnLogin, 0, session)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mengirimkan opsi dan session token kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data: &app.UserWebAuthnBeginResponse{
+				Options:      assertion,
+				SessionToken: token,
+			},
+		})
+	}
+}
+
+func (webAuthnController *WebAuthnController) HandleFinishLogin(relyingParty *webauthn.WebAuthn, sessionToken helpers.IWebAuthnSessionToken, webToken helpers.IWebToken, refreshToken helpers.IRefreshToken, revocationStore stores.IRevocationStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// NOTE: Langkah Kasus Penggunaan User Finish Passkey Login
+		// [x] Memvalidasi request json
+		// [x] Memverifikasi session token yang belum pernah digunakan
+		// [x] Mengambil user dari user handle dan memverifikasi assertion dengan passkey miliknya
+		// [x] Menolak passkey yang kemungkinan telah diduplikasi (sign count tidak bertambah)
+		// [x] Menolak passkey yang tidak memverifikasi user dengan PIN atau biometrik
+		// [x] Menyimpan sign count terbaru
+		// [x] Membatalkan session token sehingga hanya dapat digunakan satu kali
+		// [x] Membuat access token dan refresh token baru
+		// [x] Mengembalikan respon berupa access token dan refresh token
+
+		var loginRequest app.UserWebAuthnLoginRequest
+		if err := c.ShouldBindJSON(&loginRequest); err != nil {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"json": "Invalid json format",
+				},
+			})
+			return
+		}
+
+		// Memvalidasi request json
+		msg, _ := webAuthnController.validator.Validate(loginRequest)
+
+		if len(loginRequest.Credential) == 0 {
+			msg["credential"] = "credential is required"
+		}
+
+		if len(msg) != 0 {
+			c.JSON(http.StatusBadRequest, &app.JsendFailResponse{
+				Status: "fail",
+				Data:   msg,
+			})
+			return
+		}
+
+		// Session token yang telah digunakan dicatat pada revocation store
+		claims, err := sessionToken.ParseToken(helpers.WebAuthnLogin, loginRequest.SessionToken)
+		if err != nil || revocationStore.IsRevoked(claims.ID, claims.UserID, claims.IssuedAt.Time) {
+			c.JSON(http.StatusUnauthorized, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"sessionToken": "Session token is invalid or has expired",
+				},
+			})
+			return
+		}
+
+		// Mengambil user dari user handle dan memverifikasi assertion dengan passkey miliknya
+		var relatedUser *webAuthnUser
+		findUser := func(rawID, userHandle []byte) (webauthn.User, error) {
+			userId, err := strconv.ParseUint(string(userHandle), 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			user, err := webAuthnController.userModel.WithDB(webAuthnController.db.Primary()).GetById(uint(userId), false)
+			if err != nil {
+				return nil, err
+			}
+			relatedUser, err = webAuthnController.loadWebAuthnUser(user)
+			if err != nil {
+				return nil, err
+			}
+			return relatedUser, nil
+		}
+		parsedResponse, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(loginRequest.Credential))
+		var credential *webauthn.Credential
+		if err == nil {
+			credential, err = relyingParty.ValidateDiscoverableLogin(findUser, claims.Session, parsedResponse)
+		}
+		if err != nil || credential.Authenticator.CloneWarning {
+			c.JSON(http.StatusUnauthorized, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"credential": "Passkey could not be verified",
+				},
+			})
+			return
+		}
+
+		// Passkey tanpa verifikasi user hanya satu faktor dan akan melewati 2FA, sehingga ditolak
+		if !credential.Flags.UserVerified {
+			c.JSON(http.StatusUnauthorized, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"credential": "Passkey must verify the user with a PIN or biometrics",
+				},
+			})
+			return
+		}
+
+		// Akun yang dinonaktifkan tidak dapat login
+		if relatedUser.user.DisabledAt != nil {
+			c.JSON(http.StatusForbidden, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"message": "This account has been disabled",
+				},
+			})
+			return
+		}
+
+		// Menyimpan sign count terbaru dari authenticator
+		primaryModel := webAuthnController.credentialModel.WithDB(webAuthnController.db.Primary())
+		storedCredential, err := primaryModel.GetByCredentialID(credential.ID)
+		if err == nil {
+			_, err = primaryModel.UpdateAfterLogin(storedCredential, credential)
+		}
+		if err == nil {
+			// Session token hanya dapat digunakan satu kali
+			err = revocationStore.Revoke(claims.ID, relatedUser.user.ID, claims.ExpiresAt.Time)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Membuat access token dan refresh token, setiap login memulai family refresh token yang baru
+		authResponse, err := issueTokens(webAuthnController.refreshTokenModel.WithDB(webAuthnController.db.Primary()), webToken, refreshToken, relatedUser.user.ID, "")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		// Mengembalikan response berupa akses token dan refresh token kembali ke client
+		c.JSON(http.StatusOK, &app.JsendSuccessResponse{
+			Status: "success",
+			Data:   authResponse,
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package controllers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/app"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/config"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/stores"
+)
+
+// Authenticator data flags
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+// softAuthenticator is a passkey held in memory, it answers the ceremonies
+// like a browser and a platform authenticator would.
+type softAuthenticator struct {
+	t            *testing.T
+	origin       string
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T, origin string) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatal(err)
+	}
+	return &softAuthenticator{t: t, origin: origin, key: key, credentialID: credentialID}
+}
+
+type ceremonyOptions struct {
+	PublicKey struct {
+		Challenge string `json:"challenge"`
+		RP        struct {
+			ID string `json:"id"`
+		} `json:"rp"`
+		RPID string `json:"rpId"`
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+	} `json:"publicKey"`
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func (authenticator *softAuthenticator) clientData(ceremony string, challenge string) []byte {
+	clientData, err := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": challenge,
+		"origin":    authenticator.origin,
+	})
+	if err != nil {
+		authenticator.t.Fatal(err)
+	}
+	return clientData
+}
+
+func (authenticator *softAuthenticator) authenticatorData(rpID string, flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append(rpIDHash[:], flags)
+	authenticator.signCount++
+	data = binary.BigEndian.AppendUint32(data, authenticator.signCount)
+	return append(data, attested...)
+}
+
+// create answers navigator.credentials.create() with a "none" attestation.
+func (authenticator *softAuthenticator) create(options ceremonyOptions, flags byte) json.RawMessage {
+	t := authenticator.t
+	userHandle, err := base64.RawURLEncoding.DecodeString(options.PublicKey.User.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator.userHandle = userHandle
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  1,
+		XCoord: authenticator.key.X.FillBytes(make([]byte, 32)),
+		YCoord: authenticator.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attested := make([]byte, 16)
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(authenticator.credentialID)))
+	attested = append(attested, authenticator.credentialID...)
+	attested = append(attested, publicKey...)
+
+	attestationObject, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authenticator.authenticatorData(options.PublicKey.RP.ID, flags|flagAttested, attested),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return authenticator.credential(map[string]string{
+		"clientDataJSON":    b64(authenticator.clientData("webauthn.create", options.PublicKey.Challenge)),
+		"attestationObject": b64(attestationObject),
+	})
+}
+
+// get answers navigator.credentials.get() for a discoverable login.
+func (authenticator *softAuthenticator) get(options ceremonyOptions, flags byte) json.RawMessage {
+	t := authenticator.t
+	authData := authenticator.authenticatorData(options.PublicKey.RPID, flags, nil)
+	clientData := authenticator.clientData("webauthn.get", options.PublicKey.Challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, authenticator.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return authenticator.credential(map[string]string{
+		"clientDataJSON":    b64(clientData),
+		"authenticatorData": b64(authData),
+		"signature":         b64(signature),
+		"userHandle":        b64(authenticator.userHandle),
+	})
+}
+
+func (authenticator *softAuthenticator) credential(response map[string]string) json.RawMessage {
+	credential, err := json.Marshal(map[string]interface{}{
+		"id":       b64(authenticator.credentialID),
+		"rawId":    b64(authenticator.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		authenticator.t.Fatal(err)
+	}
+	return credential
+}
+
+func newWebAuthnTestRouter(t *testing.T, user *models.User) *gin.Engine {
+	db := newTestDB(t)
+	keySet := newTestKeySet(t)
+	cfg := config.Default()
+	relyingParty, err := cfg.WebAuthn.WebAuthn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionToken := helpers.NewWebAuthnSessionToken(keySet, cfg.JWT.Issuer, cfg.WebAuthn.SessionLifetime)
+	revocationStore := stores.NewRevocationStore(models.NewTokenRevocationModel(db), time.Hour, 0)
+	refreshToken := helpers.NewRefreshToken(time.Hour)
+
+	*user = *createTestUser(t, db, "passkey@example.com")
+	webAuthnController := NewWebAuthnController(db, models.NewUserModel(db), models.NewWebAuthnCredentialModel(db), models.NewRefreshTokenModel(db), helpers.NewValidator())
+
+	route := gin.New()
+	route.POST("/login/begin", webAuthnController.HandleBeginLogin(relyingParty, sessionToken))
+	route.POST("/login/finish", webAuthnController.HandleFinishLogin(relyingParty, sessionToken, newTestWebToken(t, keySet), refreshToken, revocationStore))
+	route.POST("/register/begin", asUser(user), webAuthnController.HandleBeginRegistration(relyingParty, sessionToken))
+	route.POST("/register/finish", asUser(user), webAuthnController.HandleFinishRegistration(relyingParty, sessionToken, revocationStore))
+	return route
+}
+
+// registerPasskey runs the registration ceremony and returns its status code.
+func registerPasskey(t *testing.T, route *gin.Engine, authenticator *softAuthenticator, flags byte) int {
+	var begin struct {
+		Options      ceremonyOptions `json:"options"`
+		SessionToken string          `json:"sessionToken"`
+	}
+	if code := doJSON(t, route, http.MethodPost, "/register/begin", gin.H{}, &begin); code != http.StatusOK {
+		t.Fatalf("register begin: status %d", code)
+	}
+	return doJSON(t, route, http.MethodPost, "/register/finish", &app.UserWebAuthnRegisterRequest{
+		SessionToken: begin.SessionToken,
+		Credential:   authenticator.create(begin.Options, flags),
+	}, nil)
+}
+
+// loginWithPasskey runs the login ceremony and returns its status code.
+func loginWithPasskey(t *testing.T, route *gin.Engine, authenticator *softAuthenticator, flags byte, tokens *app.UserAuthResponse) int {
+	var begin struct {
+		Options      ceremonyOptions `json:"options"`
+		SessionToken string          `json:"sessionToken"`
+	}
+	if code := doJSON(t, route, http.MethodPost, "/login/begin", gin.H{}, &begin); code != http.StatusOK {
+		t.Fatalf("login begin: status %d", code)
+	}
+	request := &app.UserWebAuthnLoginRequest{
+		SessionToken: begin.SessionToken,
+		Credential:   authenticator.get(begin.Options, flags),
+	}
+	if tokens == nil {
+		return doJSON(t, route, http.MethodPost, "/login/finish", request, nil)
+	}
+	return doJSON(t, route, http.MethodPost, "/login/finish", request, tokens)
+}
+
+func TestWebAuthnRegisterAndLoginWithUserVerification(t *testing.T) {
+	var user models.User
+	route := newWebAuthnTestRouter(t, &user)
+	authenticator := newSoftAuthenticator(t, "http://localhost:8080")
+
+	if code := registerPasskey(t, route, authenticator, flagUserPresent|flagUserVerified); code != http.StatusCreated {
+		t.Fatalf("register: expected %d, got %d", http.StatusCreated, code)
+	}
+
+	var tokens app.UserAuthResponse
+	if code := loginWithPasskey(t, route, authenticator, flagUserPresent|flagUserVerified, &tokens); code != http.StatusOK {
+		t.Fatalf("login: expected %d, got %d", http.StatusOK, code)
+	}
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("login: expected tokens, got %+v", tokens)
+	}
+}
+
+func TestWebAuthnRefusesPasskeyWithoutUserVerification(t *testing.T) {
+	var user models.User
+	route := newWebAuthnTestRouter(t, &user)
+
+	// A passkey without PIN or biometrics can't be registered
+	unverified := newSoftAuthenticator(t, "http://localhost:8080")
+	if code := registerPasskey(t, route, unverified, flagUserPresent); code != http.StatusBadRequest {
+		t.Fatalf("register without user verification: expected %d, got %d", http.StatusBadRequest, code)
+	}
+
+	// Nor used to sign in when it skips the verification
+	authenticator := newSoftAuthenticator(t, "http://localhost:8080")
+	if code := registerPasskey(t, route, authenticator, flagUserPresent|flagUserVerified); code != http.StatusCreated {
+		t.Fatalf("register: expected %d, got %d", http.StatusCreated, code)
+	}
+	if code := loginWithPasskey(t, route, authenticator, flagUserPresent, nil); code != http.StatusUnauthorized {
+		t.Fatalf("login without user verification: expected %d, got %d", http.StatusUnauthorized, code)
+	}
+}
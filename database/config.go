@@ -0,0 +1,112 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Config describes how to reach a database independently of its driver.
+// TLSMode is passed to the driver as is, so it takes the driver's own values
+// ("true", "false", "skip-verify", "preferred" for MySQL and "disable",
+// "require", "verify-ca", "verify-full" for Postgres). Pool limits left at
+// zero keep database/sql defaults and are ignored for SQLite. Replicas are
+// read-only copies of the primary, they are reached with the same driver and
+// only need the fields that differ from the primary, usually Host and Port.
+type Config struct {
+	Driver          string
+	Host            string
+	Port            int
+	Username        string
+	Password        string
+	Name            string
+	Path            string
+	TLSMode         string
+	TimeZone        string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	Replicas        []*Config
+}
+
+func CreateDB(config *Config) (IDatabase, error) {
+	db, err := NewDB(config)
+	if err != nil {
+		return nil, err
+	}
+	err = db.ConnectDB()
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// NewDB picks the implementation for config.Driver without connecting to it.
+func NewDB(config *Config) (IDatabase, error) {
+	switch config.Driver {
+	case "", DriverMySQL:
+		return NewMySQLDB(config), nil
+	case DriverPostgres:
+		return NewPostgresDB(config), nil
+	case DriverSQLite:
+		return NewSQLiteDB(config.Path), nil
+	default:
+		return nil, fmt.Errorf("database: unsupported driver %q", config.Driver)
+	}
+}
+
+// replica returns the full configuration of a replica, filling what it leaves empty from the primary.
+func (config *Config) replica(replica *Config) *Config {
+	merged := *config
+	merged.Replicas = nil
+	if replica.Host != "" {
+		merged.Host = replica.Host
+	}
+	if replica.Port != 0 {
+		merged.Port = replica.Port
+	}
+	if replica.Username != "" {
+		merged.Username = replica.Username
+	}
+	if replica.Password != "" {
+		merged.Password = replica.Password
+	}
+	if replica.Name != "" {
+		merged.Name = replica.Name
+	}
+	if replica.TLSMode != "" {
+		merged.TLSMode = replica.TLSMode
+	}
+	return &merged
+}
+
+func (config *Config) host() string {
+	if config.Host == "" {
+		return "127.0.0.1"
+	}
+	return config.Host
+}
+
+func (config *Config) configurePool(client *gorm.DB) error {
+	sqlDB, err := client.DB()
+	if err != nil {
+		return err
+	}
+	if config.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(config.MaxOpenConns)
+	}
+	if config.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(config.MaxIdleConns)
+	}
+	if config.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(config.ConnMaxLifetime)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package database
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrNotConnected = errors.New("database: not connected")
+
+// ErrReconnectInMemory is returned by ConnectDB for an in-memory database that
+// is already connected, a new connection would open a new empty database.
+var ErrReconnectInMemory = errors.New("database: an in-memory database can't be reconnected without losing its data")
+
+type IDatabase interface {
+	GetClient() *gorm.DB
+	GetReadClient() *gorm.DB
+	Primary() IDatabase
+	Ping() error
+	ConnectDB() error
+	ConnectWithRetry(policy *RetryPolicy) error
+	StartHealthCheck(interval time.Duration)
+	StopHealthCheck()
+	Status() Status
+	WithTransaction(fn func(tx IDatabase) error) error
+	MigrateDB(models ...interface{}) error
+	CloseDB() error
+}
+
+type Database struct {
+	dialector         func() gorm.Dialector
+	replicaDialectors []func() gorm.Dialector
+	configure         func(client *gorm.DB) error
+	// inMemory databases only live as long as their connection.
+	inMemory bool
+
+	mu       sync.RWMutex
+	client   *gorm.DB
+	replicas []*gorm.DB
+	next     uint32
+	status   Status
+	health   *healthCheck
+}
+
+// GetClient returns the primary, every write has to go through it.
+func (db *Database) GetClient() *gorm.DB {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.client
+}
+
+// GetReadClient spreads reads over the connected replicas round-robin and
+// falls back to the primary when there are none. Replicas can lag behind, use
+// Primary() for reads that must see a write made earlier in the same request.
+func (db *Database) GetReadClient() *gorm.DB {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if len(db.replicas) == 0 {
+		return db.client
+	}
+	next := atomic.AddUint32(&db.next, 1)
+	return db.replicas[next%uint32(len(db.replicas))]
+}
+
+func (db *Database) Primary() IDatabase {
+	return &primaryDatabase{IDatabase: db}
+}
+
+func (db *Database) Ping() error {
+	client := db.GetClient()
+	if client == nil {
+		return ErrNotConnected
+	}
+	test, err := client.DB()
+	if err != nil {
+		return err
+	}
+	err = test.Ping()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ConnectDB opens a new client and swaps it in, the previous client (if any)
+// is closed once the new one is ready so in-flight queries aren't cut short.
+func (db *Database) ConnectDB() error {
+	if db.inMemory && db.GetClient() != nil {
+		return ErrReconnectInMemory
+	}
+	client, err := gorm.Open(db.dialector(), &gorm.Config{})
+	if err != nil {
+		return err
+	}
+	if db.configure != nil {
+		err = db.configure(client)
+		if err != nil {
+			closeClients(client)
+			return err
+		}
+	}
+
+	db.mu.Lock()
+	previous := db.client
+	db.client = client
+	db.status = StatusUp
+	db.mu.Unlock()
+
+	closeClients(previous)
+	db.connectReplicas()
+	return nil
+}
+
+// connectReplicas (re)opens every replica. A replica that can't be reached is
+// left out of the rotation instead of failing, reads then go to the others or the primary.
+func (db *Database) connectReplicas() {
+	if len(db.replicaDialectors) == 0 {
+		return
+	}
+
+	replicas := []*gorm.DB{}
+	for i, dialector := range db.replicaDialectors {
+		replica, err := gorm.Open(dialector(), &gorm.Config{})
+		if err == nil && db.configure != nil {
+			if err = db.configure(replica); err != nil {
+				closeClients(replica)
+			}
+		}
+		if err != nil {
+			log.Printf("database: replica %d is unavailable: %s", i+1, err.Error())
+			continue
+		}
+		replicas = append(replicas, replica)
+	}
+
+	db.mu.Lock()
+	previous := db.replicas
+	db.replicas = replicas
+	db.mu.Unlock()
+
+	closeClients(previous...)
+}
+
+func closeClients(clients ...*gorm.DB) {
+	for _, client := range clients {
+		if client == nil {
+			continue
+		}
+		if sqlDB, err := client.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}
+}
+
+func (db *Database) MigrateDB(models ...interface{}) error {
+	client := db.GetClient()
+	if client == nil {
+		return ErrNotConnected
+	}
+	for _, model := range models {
+		err := client.AutoMigrate(model)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *Database) CloseDB() error {
+	db.StopHealthCheck()
+
+	client := db.GetClient()
+	if client == nil {
+		return nil
+	}
+	test, err := client.DB()
+	if err != nil {
+		return err
+	}
+	test.Close()
+
+	db.mu.Lock()
+	replicas := db.replicas
+	db.replicas = nil
+	db.mu.Unlock()
+	closeClients(replicas...)
+
+	db.setStatus(StatusDown)
+	return nil
+}
+
+// primaryDatabase is the view returned by Primary, it only differs from the
+// database it wraps by sending reads to the primary as well.
+type primaryDatabase struct {
+	IDatabase
+}
+
+func (db *primaryDatabase) GetReadClient() *gorm.DB {
+	return db.IDatabase.GetClient()
+}
+
+func (db *primaryDatabase) Primary() IDatabase {
+	return db
+}
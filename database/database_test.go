@@ -0,0 +1,34 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestConnectDBClosesClientThatFailsToConfigure(t *testing.T) {
+	errConfigure := errors.New("configure failed")
+	var opened *gorm.DB
+	db := &Database{
+		dialector: func() gorm.Dialector {
+			return sqlite.Open(t.TempDir() + "/test.db")
+		},
+		configure: func(client *gorm.DB) error {
+			opened = client
+			return errConfigure
+		},
+	}
+
+	if err := db.ConnectDB(); !errors.Is(err, errConfigure) {
+		t.Fatalf("expected %v, got %v", errConfigure, err)
+	}
+	sqlDB, err := opened.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sqlDB.Ping(); err == nil {
+		t.Fatal("expected the client to be closed")
+	}
+}
@@ -0,0 +1,165 @@
+package database
+
+import (
+	"log"
+	"time"
+)
+
+type Status string
+
+const (
+	// StatusDown means there has never been a working connection, or it was closed.
+	StatusDown Status = "down"
+	// StatusUp means the last connect or health check succeeded.
+	StatusUp Status = "up"
+	// StatusDegraded means a connection existed but the last health check
+	// failed, queries will most likely fail until it is re-established.
+	StatusDegraded Status = "degraded"
+)
+
+// RetryPolicy controls ConnectWithRetry. MaxAttempts of zero retries forever,
+// the delay starts at InitialBackoff and doubles after every failure up to MaxBackoff.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    10,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+	}
+}
+
+type healthCheck struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (db *Database) Status() Status {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if db.status == "" {
+		return StatusDown
+	}
+	return db.status
+}
+
+func (db *Database) setStatus(status Status) {
+	db.mu.Lock()
+	db.status = status
+	db.mu.Unlock()
+}
+
+func (db *Database) ConnectWithRetry(policy *RetryPolicy) error {
+	if policy == nil {
+		policy = DefaultRetryPolicy()
+	}
+	backoff := policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := db.ConnectDB()
+		if err == nil {
+			err = db.Ping()
+		}
+		if err == nil {
+			return nil
+		}
+		if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+			return err
+		}
+
+		log.Printf("database: connection attempt %d failed, retrying in %s: %s", attempt, backoff, err.Error())
+		time.Sleep(backoff)
+		backoff *= 2
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+// StartHealthCheck pings the database every interval in the background. When
+// a ping fails the database is marked degraded and a new client is opened with
+// ConnectDB on every following tick until it works again.
+func (db *Database) StartHealthCheck(interval time.Duration) {
+	db.StopHealthCheck()
+
+	check := &healthCheck{stop: make(chan struct{}), done: make(chan struct{})}
+	db.mu.Lock()
+	db.health = check
+	db.mu.Unlock()
+
+	go func() {
+		defer close(check.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-check.stop:
+				return
+			case <-ticker.C:
+				db.checkHealth()
+			}
+		}
+	}()
+}
+
+func (db *Database) StopHealthCheck() {
+	db.mu.Lock()
+	check := db.health
+	db.health = nil
+	db.mu.Unlock()
+
+	if check != nil {
+		close(check.stop)
+		<-check.done
+	}
+}
+
+func (db *Database) checkHealth() {
+	err := db.Ping()
+	if err == nil {
+		if db.Status() != StatusUp {
+			log.Print("database: connection recovered")
+			db.setStatus(StatusUp)
+		}
+		if !db.replicasHealthy() {
+			db.connectReplicas()
+		}
+		return
+	}
+
+	if db.Status() != StatusDegraded {
+		log.Printf("database: health check failed, marking as degraded: %s", err.Error())
+		db.setStatus(StatusDegraded)
+	}
+
+	err = db.ConnectDB()
+	if err == nil {
+		err = db.Ping()
+	}
+	if err != nil {
+		db.setStatus(StatusDegraded)
+		log.Printf("database: reconnect failed: %s", err.Error())
+		return
+	}
+	log.Print("database: reconnected")
+}
+
+func (db *Database) replicasHealthy() bool {
+	db.mu.RLock()
+	replicas := db.replicas
+	db.mu.RUnlock()
+
+	if len(replicas) < len(db.replicaDialectors) {
+		return false
+	}
+	for _, replica := range replicas {
+		sqlDB, err := replica.DB()
+		if err != nil || sqlDB.Ping() != nil {
+			return false
+		}
+	}
+	return true
+}
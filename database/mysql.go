@@ -1,80 +1,64 @@
 package database
 
 import (
-	"fmt"
+	"net"
+	"strconv"
 
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
-type IDatabase interface {
-	GetClient() *gorm.DB
-	Ping() error
-	ConnectDB() error
-	MigrateDB(models ...interface{}) error
-	CloseDB() error
-}
-
-type Database struct {
-	username string
-	password string
-	dbname   string
-	client   *gorm.DB
-}
-
-func CreateMySQLDB(username string, password string, dbname string) (IDatabase, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(127.0.0.1:3306)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		username, password, dbname)
-	client, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+func CreateMySQLDB(config *Config) (IDatabase, error) {
+	db := NewMySQLDB(config)
+	err := db.ConnectDB()
 	if err != nil {
 		return nil, err
 	}
-	db := &Database{username: username, password: password, dbname: dbname, client: client}
 	return db, nil
 }
 
-func (db *Database) GetClient() *gorm.DB {
-	return db.client
-}
-
-func (db *Database) Ping() error {
-	test, err := db.client.DB()
-	if err != nil {
-		return err
+// NewMySQLDB prepares the database without connecting, call ConnectDB or ConnectWithRetry on it.
+func NewMySQLDB(config *Config) IDatabase {
+	db := &Database{
+		dialector: func() gorm.Dialector {
+			return mysql.Open(mysqlDSN(config))
+		},
+		configure: config.configurePool,
 	}
-	err = test.Ping()
-	if err != nil {
-		return err
+	for _, replica := range config.Replicas {
+		replicaConfig := config.replica(replica)
+		db.replicaDialectors = append(db.replicaDialectors, func() gorm.Dialector {
+			return mysql.Open(mysqlDSN(replicaConfig))
+		})
 	}
-	return nil
+	return db
 }
 
-func (db *Database) ConnectDB() error {
-	dsn := fmt.Sprintf("%s:%s@tcp(127.0.0.1:3306)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		db.username, db.password, db.dbname)
-	client, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
-	if err != nil {
-		return err
+// mysqlDSN formats the DSN with the driver so credentials containing DSN
+// delimiters like @, / or ? are kept intact.
+func mysqlDSN(config *Config) string {
+	port := config.Port
+	if port == 0 {
+		port = 3306
 	}
-	db.client = client
-	return nil
-}
-
-func (db *Database) MigrateDB(models ...interface{}) error {
-	for _, model := range models {
-		err := db.client.AutoMigrate(model)
-		if err != nil {
-			return err
-		}
+	timeZone := config.TimeZone
+	if timeZone == "" {
+		timeZone = "Local"
 	}
-	return nil
-}
-
-func (db *Database) CloseDB() error {
-	test, err := db.client.DB()
-	if err != nil {
-		return err
+	tlsMode := config.TLSMode
+	if tlsMode == "" {
+		tlsMode = "false"
 	}
-	test.Close()
-	return nil
+	dsnConfig := mysqldriver.NewConfig()
+	dsnConfig.User = config.Username
+	dsnConfig.Passwd = config.Password
+	dsnConfig.Net = "tcp"
+	dsnConfig.Addr = net.JoinHostPort(config.host(), strconv.Itoa(port))
+	dsnConfig.DBName = config.Name
+	dsnConfig.ParseTime = true
+	dsnConfig.TLSConfig = tlsMode
+	// loc is left for the driver to parse so an unknown time zone is still reported by ConnectDB
+	dsnConfig.Params = map[string]string{"charset": "utf8mb4", "loc": timeZone}
+	return dsnConfig.FormatDSN()
 }
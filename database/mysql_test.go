@@ -0,0 +1,25 @@
+package database
+
+import (
+	"testing"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+func TestMySQLDSNKeepsCredentials(t *testing.T) {
+	dsn := mysqlDSN(&Config{Host: "db", Username: "user", Password: "p@ss/w:rd?&", Name: "app", TimeZone: "Asia/Jakarta"})
+
+	parsed, err := mysqldriver.ParseDSN(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.User != "user" || parsed.Passwd != "p@ss/w:rd?&" {
+		t.Fatalf("expected the credentials to be kept, got %q and %q", parsed.User, parsed.Passwd)
+	}
+	if parsed.Addr != "db:3306" || parsed.DBName != "app" {
+		t.Fatalf("expected db:3306/app, got %s/%s", parsed.Addr, parsed.DBName)
+	}
+	if parsed.Loc.String() != "Asia/Jakarta" || !parsed.ParseTime {
+		t.Fatalf("expected parseTime in Asia/Jakarta, got %t in %s", parsed.ParseTime, parsed.Loc)
+	}
+}
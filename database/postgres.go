@@ -0,0 +1,64 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func CreatePostgresDB(config *Config) (IDatabase, error) {
+	db := NewPostgresDB(config)
+	err := db.ConnectDB()
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// NewPostgresDB prepares the database without connecting, call ConnectDB or ConnectWithRetry on it.
+func NewPostgresDB(config *Config) IDatabase {
+	db := &Database{
+		dialector: func() gorm.Dialector {
+			return postgres.Open(postgresDSN(config))
+		},
+		configure: config.configurePool,
+	}
+	for _, replica := range config.Replicas {
+		replicaConfig := config.replica(replica)
+		db.replicaDialectors = append(db.replicaDialectors, func() gorm.Dialector {
+			return postgres.Open(postgresDSN(replicaConfig))
+		})
+	}
+	return db
+}
+
+func postgresDSN(config *Config) string {
+	port := config.Port
+	if port == 0 {
+		port = 5432
+	}
+	tlsMode := config.TLSMode
+	if tlsMode == "" {
+		tlsMode = "disable"
+	}
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		quoteKeyword(config.host()), port, quoteKeyword(config.Username), quoteKeyword(config.Password),
+		quoteKeyword(config.Name), quoteKeyword(tlsMode))
+	if config.TimeZone != "" {
+		dsn += fmt.Sprintf(" TimeZone=%s", quoteKeyword(config.TimeZone))
+	}
+	return dsn
+}
+
+// quoteKeyword quotes a value for a libpq keyword/value connection string,
+// so passwords containing spaces or quotes don't break the DSN.
+func quoteKeyword(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"strings"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// SQLiteInMemory can be passed to CreateSQLiteDB to get a throwaway database
+// that lives only as long as the process, which is handy for local runs and tests.
+const SQLiteInMemory = ":memory:"
+
+func CreateSQLiteDB(path string) (IDatabase, error) {
+	db := NewSQLiteDB(path)
+	err := db.ConnectDB()
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// NewSQLiteDB prepares the database without connecting, call ConnectDB or ConnectWithRetry on it.
+func NewSQLiteDB(path string) IDatabase {
+	inMemory := path == SQLiteInMemory || path == ""
+	db := &Database{
+		inMemory: inMemory,
+		dialector: func() gorm.Dialector {
+			dsn := path
+			if inMemory {
+				dsn = SQLiteInMemory
+			}
+			// SQLite ignores foreign keys unless asked to, the cascade on users.photos depends on it.
+			if strings.Contains(dsn, "?") {
+				dsn += "&_foreign_keys=on"
+			} else {
+				dsn += "?_foreign_keys=on"
+			}
+			return sqlite.Open(dsn)
+		},
+		configure: func(client *gorm.DB) error {
+			if !inMemory {
+				return nil
+			}
+			// Every connection to :memory: opens its own empty database, so the pool
+			// is pinned to a single connection that is never recycled.
+			sqlDB, err := client.DB()
+			if err != nil {
+				return err
+			}
+			sqlDB.SetMaxOpenConns(1)
+			sqlDB.SetMaxIdleConns(1)
+			sqlDB.SetConnMaxLifetime(0)
+			sqlDB.SetConnMaxIdleTime(0)
+			return nil
+		},
+	}
+	return db
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"errors"
+	"testing"
+)
+
+type note struct {
+	ID   uint
+	Text string
+}
+
+func TestSQLiteInMemoryRefusesToReconnect(t *testing.T) {
+	db, err := CreateSQLiteDB(SQLiteInMemory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.CloseDB()
+	if err := db.MigrateDB(&note{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.GetClient().Create(&note{Text: "kept"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.ConnectDB(); !errors.Is(err, ErrReconnectInMemory) {
+		t.Fatalf("expected %v, got %v", ErrReconnectInMemory, err)
+	}
+	var count int64
+	if err := db.GetClient().Model(&note{}).Count(&count).Error; err != nil || count != 1 {
+		t.Fatalf("expected the data to be kept, got %d rows, err %v", count, err)
+	}
+}
+
+func TestSQLiteFileReconnects(t *testing.T) {
+	db, err := CreateSQLiteDB(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.CloseDB()
+	if err := db.MigrateDB(&note{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.ConnectDB(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.GetClient().Create(&note{Text: "after reconnect"}).Error; err != nil {
+		t.Fatal(err)
+	}
+}
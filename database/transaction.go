@@ -0,0 +1,85 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrInTransaction = errors.New("database: operation not allowed inside a transaction")
+
+// txDatabase is the IDatabase handed to WithTransaction callbacks, its client
+// is the open transaction so models built on it take part in the transaction.
+type txDatabase struct {
+	parent IDatabase
+	tx     *gorm.DB
+}
+
+// WithTransaction runs fn in a transaction that is committed when fn returns
+// nil and rolled back when it returns an error or panics.
+func (db *Database) WithTransaction(fn func(tx IDatabase) error) error {
+	client := db.GetClient()
+	if client == nil {
+		return ErrNotConnected
+	}
+	return client.Transaction(func(tx *gorm.DB) error {
+		return fn(&txDatabase{parent: db, tx: tx})
+	})
+}
+
+func (db *txDatabase) GetClient() *gorm.DB {
+	return db.tx
+}
+
+// GetReadClient inside a transaction is the transaction itself, reads have to
+// see the writes made before them.
+func (db *txDatabase) GetReadClient() *gorm.DB {
+	return db.tx
+}
+
+func (db *txDatabase) Primary() IDatabase {
+	return db
+}
+
+func (db *txDatabase) Ping() error {
+	return db.parent.Ping()
+}
+
+func (db *txDatabase) ConnectDB() error {
+	return ErrInTransaction
+}
+
+func (db *txDatabase) ConnectWithRetry(policy *RetryPolicy) error {
+	return ErrInTransaction
+}
+
+func (db *txDatabase) StartHealthCheck(interval time.Duration) {}
+
+func (db *txDatabase) StopHealthCheck() {}
+
+func (db *txDatabase) Status() Status {
+	return db.parent.Status()
+}
+
+// WithTransaction inside a transaction uses a savepoint, so fn can fail
+// without rolling back the outer transaction.
+func (db *txDatabase) WithTransaction(fn func(tx IDatabase) error) error {
+	return db.tx.Transaction(func(tx *gorm.DB) error {
+		return fn(&txDatabase{parent: db.parent, tx: tx})
+	})
+}
+
+func (db *txDatabase) MigrateDB(models ...interface{}) error {
+	for _, model := range models {
+		err := db.tx.AutoMigrate(model)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *txDatabase) CloseDB() error {
+	return ErrInTransaction
+}
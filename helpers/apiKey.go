@@ -0,0 +1,55 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// APIKeyPrefix starts every API key so Guard can tell them apart from access
+// tokens, and secret scanners can find leaked ones.
+const APIKeyPrefix = "pak_"
+
+// Scopes an API key can be restricted to, a route accepts API keys only when
+// it requires one of them.
+const (
+	ScopePhotosRead  = "photos:read"
+	ScopePhotosWrite = "photos:write"
+	ScopeUsersWrite  = "users:write"
+)
+
+var APIKeyScopes = []string{ScopePhotosRead, ScopePhotosWrite, ScopeUsersWrite}
+
+type IAPIKey interface {
+	Generate() (key string, keyHash string, displayPrefix string, err error)
+	Hash(key string) string
+	IsAPIKey(token string) bool
+}
+
+// APIKey creates the long-lived keys users give to scripts instead of their
+// password. Like opaque tokens only their hash is stored.
+type APIKey struct{}
+
+func NewAPIKey() IAPIKey {
+	return &APIKey{}
+}
+
+// Generate returns a key of 256 bits and the start of it, enough for users to
+// recognize the key in a list without being able to use it.
+func (ak *APIKey) Generate() (string, string, string, error) {
+	secret, err := randomString(32)
+	if err != nil {
+		return "", "", "", err
+	}
+	key := APIKeyPrefix + secret
+	return key, ak.Hash(key), key[:len(APIKeyPrefix)+6], nil
+}
+
+func (ak *APIKey) Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (ak *APIKey) IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
@@ -0,0 +1,86 @@
+package helpers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// emailVerificationAudience keeps verification tokens and access tokens apart,
+// neither is accepted in place of the other.
+const emailVerificationAudience = "email-verification"
+
+type IEmailVerificationToken interface {
+	GenerateToken(userId uint, email string) (string, error)
+	ParseToken(tokenStr string) (*EmailVerificationClaims, error)
+	Lifetime() time.Duration
+}
+
+// EmailVerificationToken signs the links sent to verify an email address with
+// the access token keys, the token proves the user received an email at Email.
+type EmailVerificationToken struct {
+	keySet   *KeySet
+	issuer   string
+	lifetime time.Duration
+}
+
+type EmailVerificationClaims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+	UserID uint `json:"-"`
+}
+
+func NewEmailVerificationToken(keySet *KeySet, issuer string, lifetime time.Duration) IEmailVerificationToken {
+	return &EmailVerificationToken{
+		keySet:   keySet,
+		issuer:   issuer,
+		lifetime: lifetime,
+	}
+}
+
+func (evt *EmailVerificationToken) GenerateToken(userId uint, email string) (string, error) {
+	issuedAt := time.Now()
+	claims := &EmailVerificationClaims{
+		Email: email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(userId), 10),
+			Issuer:    evt.issuer,
+			Audience:  jwt.ClaimStrings{emailVerificationAudience},
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(issuedAt.Add(evt.lifetime)),
+		},
+	}
+	return evt.keySet.Sign(claims)
+}
+
+func (evt *EmailVerificationToken) ParseToken(tokenStr string) (*EmailVerificationClaims, error) {
+	claims := &EmailVerificationClaims{}
+	_, err := jwt.ParseWithClaims(tokenStr, claims, evt.keySet.Keyfunc,
+		jwt.WithIssuer(evt.issuer),
+		jwt.WithAudience(emailVerificationAudience),
+	)
+	if err != nil {
+		return nil, tokenError(err)
+	}
+
+	userId, err := strconv.ParseUint(claims.Subject, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: subject is not a user id", ErrTokenInvalidClaims)
+	}
+	claims.UserID = uint(userId)
+	return claims, nil
+}
+
+// Validate requires the claims GenerateToken always sets.
+func (claims *EmailVerificationClaims) Validate() error {
+	if claims.ExpiresAt == nil || claims.Subject == "" || claims.Email == "" {
+		return jwt.ErrTokenRequiredClaimMissing
+	}
+	return nil
+}
+
+func (evt *EmailVerificationToken) Lifetime() time.Duration {
+	return evt.lifetime
+}
@@ -0,0 +1,149 @@
+package helpers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Algorithms new passwords can be hashed with.
+const (
+	HashArgon2id = "argon2id"
+	HashBcrypt   = "bcrypt"
+)
+
+var errInvalidArgon2Hash = errors.New("hasher: invalid argon2id hash")
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+type HasherParams struct {
+	Algorithm  string
+	BcryptCost int
+	// Argon2Memory is in KiB.
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+}
+
+type IHasher interface {
+	HashString(plainText string) (string, error)
+	CheckHash(hashedText string, plainText string) bool
+	// NeedsRehash tells whether hashedText was made with another algorithm or
+	// other parameters than HashString uses now.
+	NeedsRehash(hashedText string) bool
+}
+
+// Hasher hashes with the algorithm of its params and checks the hashes of both
+// algorithms, each hash carries the algorithm and the parameters it was made
+// with.
+type Hasher struct {
+	params HasherParams
+}
+
+func NewHasher(params HasherParams) IHasher {
+	return &Hasher{
+		params: params,
+	}
+}
+
+func (h *Hasher) HashString(plainText string) (string, error) {
+	if h.params.Algorithm == HashBcrypt {
+		hashedBytes, err := bcrypt.GenerateFromPassword([]byte(plainText), h.params.BcryptCost)
+		return string(hashedBytes), err
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(plainText), salt, h.params.Argon2Iterations, h.params.Argon2Memory, h.params.Argon2Parallelism, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.params.Argon2Memory, h.params.Argon2Iterations, h.params.Argon2Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h *Hasher) CheckHash(hashedText string, plainText string) bool {
+	if !strings.HasPrefix(hashedText, "$argon2id$") {
+		err := bcrypt.CompareHashAndPassword([]byte(hashedText), []byte(plainText))
+		return err == nil
+	}
+
+	hash, err := parseArgon2Hash(hashedText)
+	if err != nil || hash.version != argon2.Version {
+		return false
+	}
+	key := argon2.IDKey([]byte(plainText), hash.salt, hash.iterations, hash.memory, hash.parallelism, uint32(len(hash.key)))
+	return subtle.ConstantTimeCompare(key, hash.key) == 1
+}
+
+func (h *Hasher) NeedsRehash(hashedText string) bool {
+	if !strings.HasPrefix(hashedText, "$argon2id$") {
+		cost, err := bcrypt.Cost([]byte(hashedText))
+		if err != nil {
+			return false
+		}
+		return h.params.Algorithm != HashBcrypt || cost != h.params.BcryptCost
+	}
+
+	hash, err := parseArgon2Hash(hashedText)
+	if err != nil {
+		return false
+	}
+	return h.params.Algorithm != HashArgon2id ||
+		hash.version != argon2.Version ||
+		hash.memory != h.params.Argon2Memory ||
+		hash.iterations != h.params.Argon2Iterations ||
+		hash.parallelism != h.params.Argon2Parallelism ||
+		len(hash.salt) != argon2SaltLength ||
+		len(hash.key) != argon2KeyLength
+}
+
+type argon2Hash struct {
+	version     int
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+// parseArgon2Hash reads $argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>, salt and
+// key in unpadded base64.
+func parseArgon2Hash(hashedText string) (*argon2Hash, error) {
+	parts := strings.Split(hashedText, "$")
+	if len(parts) != 6 || parts[1] != HashArgon2id {
+		return nil, errInvalidArgon2Hash
+	}
+
+	var hash argon2Hash
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &hash.version); err != nil {
+		return nil, errInvalidArgon2Hash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &hash.memory, &hash.iterations, &hash.parallelism); err != nil {
+		return nil, errInvalidArgon2Hash
+	}
+	if hash.iterations == 0 || hash.parallelism == 0 {
+		return nil, errInvalidArgon2Hash
+	}
+
+	var err error
+	if hash.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, errInvalidArgon2Hash
+	}
+	if hash.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, errInvalidArgon2Hash
+	}
+	if len(hash.key) == 0 {
+		return nil, errInvalidArgon2Hash
+	}
+	return &hash, nil
+}
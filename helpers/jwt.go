@@ -2,75 +2,133 @@ package helpers
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Errors returned by ParseToken, one per reason a token is refused.
+var (
+	ErrTokenMalformed        = errors.New("token is malformed")
+	ErrTokenSignatureInvalid = errors.New("token signature is invalid")
+	ErrTokenExpired          = errors.New("token is expired")
+	ErrTokenNotValidYet      = errors.New("token is not valid yet")
+	ErrTokenInvalidAudience  = errors.New("token has invalid audience")
+	ErrTokenInvalidIssuer    = errors.New("token has invalid issuer")
+	ErrTokenInvalidClaims    = errors.New("token has invalid claims")
+)
+
 type IWebToken interface {
 	GenerateToken(userId uint) (string, error)
 	ParseToken(tokenStr string) (*UserClaims, error)
-	IsTokenExpired(tokenStr string) (bool, error)
+	JWKS() *JWKSet
 }
 
 type WebToken struct {
 	expirationTimeInMinute int
-	tokenSecret            string
+	keySet                 *KeySet
+	issuer                 string
+	audience               string
+	leeway                 time.Duration
 }
 
+// UserClaims only carries registered claims, the user id is the subject and
+// the token id (jti) is what revoking a single token refers to. UserID is the
+// parsed subject, it isn't part of the token.
 type UserClaims struct {
-	ID uint
 	jwt.RegisteredClaims
+	UserID uint `json:"-"`
 }
 
-func NewWebToken(expirationTimeInMinute int, tokenSecret string) IWebToken {
+// NewWebToken issues tokens for audience signed as issuer, tokens are only
+// accepted with that issuer and audience. leeway is the clock skew tolerated
+// on exp, nbf and iat.
+func NewWebToken(expirationTimeInMinute int, keySet *KeySet, issuer string, audience string, leeway time.Duration) IWebToken {
 	return &WebToken{
 		expirationTimeInMinute: expirationTimeInMinute,
-		tokenSecret:            tokenSecret,
+		keySet:                 keySet,
+		issuer:                 issuer,
+		audience:               audience,
+		leeway:                 leeway,
 	}
 }
 
 func (wt *WebToken) GenerateToken(userId uint) (string, error) {
-	expirationTime := time.Now().Add(time.Duration(wt.expirationTimeInMinute) * time.Minute)
+	jti, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+
+	issuedAt := time.Now()
+	expirationTime := issuedAt.Add(time.Duration(wt.expirationTimeInMinute) * time.Minute)
 	claims := &UserClaims{
-		ID: userId,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   strconv.FormatUint(uint64(userId), 10),
+			Issuer:    wt.issuer,
+			Audience:  jwt.ClaimStrings{wt.audience},
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			NotBefore: jwt.NewNumericDate(issuedAt),
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(wt.tokenSecret))
-	if err != nil {
-		return "", err
-	}
-	return tokenString, err
+	return wt.keySet.Sign(claims)
 }
 
+// ParseToken verifies the signature and the claims in a single pass, the
+// error is one of the ErrToken errors above.
 func (wt *WebToken) ParseToken(tokenStr string) (*UserClaims, error) {
 	claims := &UserClaims{}
+	_, err := jwt.ParseWithClaims(tokenStr, claims, wt.keySet.Keyfunc,
+		jwt.WithLeeway(wt.leeway),
+		jwt.WithIssuedAt(),
+		jwt.WithIssuer(wt.issuer),
+		jwt.WithAudience(wt.audience),
+	)
+	if err != nil {
+		return nil, tokenError(err)
+	}
 
-	token, err := jwt.ParseWithClaims(tokenStr, claims, func(tkn *jwt.Token) (interface{}, error) {
-		return []byte(wt.tokenSecret), nil
-	})
+	userId, err := strconv.ParseUint(claims.Subject, 10, 32)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: subject is not a user id", ErrTokenInvalidClaims)
 	}
+	claims.UserID = uint(userId)
+	return claims, nil
+}
 
-	if claims, ok := token.Claims.(*UserClaims); ok && token.Valid {
-		return claims, nil
-	} else {
-		return nil, errors.New("token: Token invalid")
+// Validate is called by the jwt parser after the standard checks, exp, iat,
+// sub and jti are optional in the spec but required here.
+func (claims *UserClaims) Validate() error {
+	if claims.ExpiresAt == nil || claims.IssuedAt == nil || claims.Subject == "" || claims.ID == "" {
+		return jwt.ErrTokenRequiredClaimMissing
 	}
+	return nil
 }
 
-func (wt *WebToken) IsTokenExpired(tokenStr string) (bool, error) {
-	claims := &UserClaims{}
-	_, err := jwt.ParseWithClaims(tokenStr, claims, func(tkn *jwt.Token) (interface{}, error) {
-		return []byte(wt.tokenSecret), nil
-	})
-	if errors.Is(err, jwt.ErrTokenExpired) {
-		return true, nil
-	} else {
-		return false, err
+func (wt *WebToken) JWKS() *JWKSet {
+	return wt.keySet.JWKS()
+}
+
+// tokenError maps the errors of the jwt package to ours, the signature is
+// checked before the claims so a forged token never reports its claims.
+func tokenError(err error) error {
+	switch {
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return ErrTokenMalformed
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return ErrTokenSignatureInvalid
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return ErrTokenNotValidYet
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return ErrTokenInvalidAudience
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return ErrTokenInvalidIssuer
+	default:
+		return fmt.Errorf("%w: %s", ErrTokenInvalidClaims, err.Error())
 	}
 }
@@ -0,0 +1,235 @@
+package helpers
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is a key of the KeySet. Keys loaded from a public key, or added
+// with KeySet.AddVerifyKey, only verify.
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	// signKey is nil for verify only keys, verifyKey is the public key, or
+	// the secret for HS256.
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// KeySet signs with its active key and verifies with any of its keys, picked
+// by the kid header. Rotating means making a new key active while the old one
+// stays in the set until the tokens it signed have expired.
+type KeySet struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+func NewKeySet(active *SigningKey) (*KeySet, error) {
+	if active.signKey == nil {
+		return nil, fmt.Errorf("key %q can't sign, a private key is required", active.ID)
+	}
+	return &KeySet{
+		active: active,
+		keys:   map[string]*SigningKey{active.ID: active},
+	}, nil
+}
+
+// AddVerifyKey keeps accepting the tokens signed by key, it is never used to sign.
+func (keySet *KeySet) AddVerifyKey(key *SigningKey) error {
+	if _, ok := keySet.keys[key.ID]; ok {
+		return fmt.Errorf("duplicate key id %q", key.ID)
+	}
+	keySet.keys[key.ID] = &SigningKey{
+		ID:        key.ID,
+		Method:    key.Method,
+		verifyKey: key.verifyKey,
+	}
+	return nil
+}
+
+func (keySet *KeySet) Active() *SigningKey {
+	return keySet.active
+}
+
+func (keySet *KeySet) Get(kid string) (*SigningKey, bool) {
+	key, ok := keySet.keys[kid]
+	return key, ok
+}
+
+// Sign signs claims with the active key and names it in the kid header.
+func (keySet *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(keySet.active.Method, claims)
+	if keySet.active.ID != "" {
+		token.Header["kid"] = keySet.active.ID
+	}
+	return token.SignedString(keySet.active.signKey)
+}
+
+// Keyfunc picks the key named by the kid header, a token without kid can only
+// be verified by the HS256 key. The algorithm has to be the one of the key,
+// otherwise a public RSA key could be used as an HS256 secret.
+func (keySet *KeySet) Keyfunc(tkn *jwt.Token) (interface{}, error) {
+	kid, _ := tkn.Header["kid"].(string)
+	key, ok := keySet.Get(kid)
+	if !ok {
+		return nil, fmt.Errorf("token: unknown key id %q", kid)
+	}
+	if tkn.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("token: unexpected signing method %s", tkn.Method.Alg())
+	}
+	return key.verifyKey, nil
+}
+
+// JWKS lists the public keys of the set, HS256 secrets are never published.
+func (keySet *KeySet) JWKS() *JWKSet {
+	jwks := &JWKSet{Keys: []JWK{}}
+	for _, key := range keySet.keys {
+		if jwk, ok := key.JWK(); ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	return jwks
+}
+
+// NewHMACKey returns the HS256 key, its id is empty so tokens signed before
+// key ids were introduced, which have no kid header, still verify.
+func NewHMACKey(secret string) *SigningKey {
+	return &SigningKey{
+		Method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+}
+
+// LoadKeyFile reads a PEM encoded RSA or Ed25519 key, private (PKCS#1 or
+// PKCS#8) or public (PKIX or PKCS#1). The key id is the RFC 7638 thumbprint
+// of the public key so every instance loading the same file agrees on it.
+func LoadKeyFile(path string) (*SigningKey, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParseKeyPEM(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+func ParseKeyPEM(content []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.signKey, key.verifyKey = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.verifyKey = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.signKey, key.verifyKey = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.verifyKey = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, only RSA and Ed25519 keys are supported", parsed)
+	}
+	if rsaKey, ok := key.verifyKey.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, errors.New("RSA keys must be at least 2048 bits")
+	}
+
+	jwk, _ := key.JWK()
+	key.ID = jwk.thumbprint()
+	return key, nil
+}
+
+func (key *SigningKey) JWK() (JWK, bool) {
+	switch k := key.verifyKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: key.Method.Alg(),
+			Kid: key.ID,
+			N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Use: "sig",
+			Alg: key.Method.Alg(),
+			Kid: key.ID,
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(k),
+		}, true
+	default:
+		return JWK{}, false
+	}
+}
+
+// thumbprint hashes the required members of the JWK in lexicographic order.
+func (jwk JWK) thumbprint() string {
+	var members interface{}
+	if jwk.Kty == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	encoded, _ := json.Marshal(members)
+	sum := sha256.Sum256(encoded)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,85 @@
+package helpers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mfaChallengeAudience keeps challenge tokens from being used as access tokens.
+const mfaChallengeAudience = "mfa-challenge"
+
+type IMFAChallengeToken interface {
+	GenerateToken(userId uint) (string, error)
+	ParseToken(tokenStr string) (*MFAChallengeClaims, error)
+}
+
+// MFAChallengeToken is returned by the password step of the login of a user
+// with 2FA, it proves the password was right and is exchanged with a code for
+// the access and refresh tokens.
+type MFAChallengeToken struct {
+	keySet   *KeySet
+	issuer   string
+	lifetime time.Duration
+}
+
+type MFAChallengeClaims struct {
+	jwt.RegisteredClaims
+	UserID uint `json:"-"`
+}
+
+func NewMFAChallengeToken(keySet *KeySet, issuer string, lifetime time.Duration) IMFAChallengeToken {
+	return &MFAChallengeToken{
+		keySet:   keySet,
+		issuer:   issuer,
+		lifetime: lifetime,
+	}
+}
+
+func (mct *MFAChallengeToken) GenerateToken(userId uint) (string, error) {
+	jti, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+	issuedAt := time.Now()
+	claims := &MFAChallengeClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   strconv.FormatUint(uint64(userId), 10),
+			Issuer:    mct.issuer,
+			Audience:  jwt.ClaimStrings{mfaChallengeAudience},
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(issuedAt.Add(mct.lifetime)),
+		},
+	}
+	return mct.keySet.Sign(claims)
+}
+
+func (mct *MFAChallengeToken) ParseToken(tokenStr string) (*MFAChallengeClaims, error) {
+	claims := &MFAChallengeClaims{}
+	_, err := jwt.ParseWithClaims(tokenStr, claims, mct.keySet.Keyfunc,
+		jwt.WithIssuer(mct.issuer),
+		jwt.WithAudience(mfaChallengeAudience),
+	)
+	if err != nil {
+		return nil, tokenError(err)
+	}
+
+	userId, err := strconv.ParseUint(claims.Subject, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: subject is not a user id", ErrTokenInvalidClaims)
+	}
+	claims.UserID = uint(userId)
+	return claims, nil
+}
+
+// Validate requires the claims GenerateToken always sets, the jti lets a
+// challenge be redeemed only once.
+func (claims *MFAChallengeClaims) Validate() error {
+	if claims.ExpiresAt == nil || claims.IssuedAt == nil || claims.Subject == "" || claims.ID == "" {
+		return jwt.ErrTokenRequiredClaimMissing
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var ErrOIDCNonceMismatch = errors.New("id token nonce doesn't match the login")
+
+// OIDCIdentity is the user an identity provider vouches for in a verified ID
+// token, the issuer and subject together identify them.
+type OIDCIdentity struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+type IOIDCProvider interface {
+	AuthCodeURL(state string, nonce string, verifier string) (string, error)
+	Exchange(ctx context.Context, code string, verifier string, nonce string) (*OIDCIdentity, error)
+}
+
+// OIDCProvider signs users in with an external identity provider using the
+// authorization code flow with PKCE.
+type OIDCProvider struct {
+	issuerURL    string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	scopes       []string
+	client       *http.Client
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+func NewOIDCProvider(issuerURL string, clientID string, clientSecret string, redirectURL string, scopes []string) IOIDCProvider {
+	return &OIDCProvider{
+		issuerURL:    issuerURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+		scopes:       scopes,
+		client:       &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// discover fetches the discovery document on first use and keeps it, so a
+// provider that is down doesn't prevent the server from starting. The keys
+// of the provider are fetched and refreshed in the background of that
+// context, it must outlive the request.
+func (op *OIDCProvider) discover() (*oidc.Provider, error) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	if op.provider != nil {
+		return op.provider, nil
+	}
+	provider, err := oidc.NewProvider(oidc.ClientContext(context.Background(), op.client), op.issuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	op.provider = provider
+	return provider, nil
+}
+
+func (op *OIDCProvider) oauth2Config(provider *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     op.clientID,
+		ClientSecret: op.clientSecret,
+		RedirectURL:  op.redirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       op.scopes,
+	}
+}
+
+func (op *OIDCProvider) AuthCodeURL(state string, nonce string, verifier string) (string, error) {
+	provider, err := op.discover()
+	if err != nil {
+		return "", err
+	}
+	return op.oauth2Config(provider).AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange redeems the authorization code and verifies the signature,
+// issuer, audience and expiry of the ID token, then the nonce of the login.
+func (op *OIDCProvider) Exchange(ctx context.Context, code string, verifier string, nonce string) (*OIDCIdentity, error) {
+	provider, err := op.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = oidc.ClientContext(ctx, op.client)
+	token, err := op.oauth2Config(provider).Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("oidc code exchange: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("oidc code exchange: no id token in the response")
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: op.clientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("oidc id token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, ErrOIDCNonceMismatch
+	}
+
+	var claims struct {
+		Email             string `json:"email"`
+		EmailVerified     bool   `json:"email_verified"`
+		Name              string `json:"name"`
+		PreferredUsername string `json:"preferred_username"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("oidc id token: %w", err)
+	}
+	return &OIDCIdentity{
+		Issuer:            idToken.Issuer,
+		Subject:           idToken.Subject,
+		Email:             claims.Email,
+		EmailVerified:     claims.EmailVerified,
+		Name:              claims.Name,
+		PreferredUsername: claims.PreferredUsername,
+	}, nil
+}
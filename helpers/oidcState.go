@@ -0,0 +1,118 @@
+package helpers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+// OIDC flows a state token can be issued for, each one is its own audience
+// so a login can't finish the linking of an identity.
+const (
+	OIDCLogin = "oidc-login"
+	OIDCLink  = "oidc-link"
+)
+
+type IOIDCStateToken interface {
+	GenerateToken(flow string, userId uint) (string, *OIDCStateClaims, error)
+	ParseToken(flow string, tokenStr string) (*OIDCStateClaims, error)
+}
+
+// OIDCStateToken carries the state, nonce and PKCE verifier of a login with
+// the identity provider between its begin and finish requests. The client
+// keeps it while the user is at the provider, an intercepted redirect with
+// the code is useless without it.
+type OIDCStateToken struct {
+	keySet   *KeySet
+	issuer   string
+	lifetime time.Duration
+}
+
+// OIDCStateClaims has no subject for a login, linking an identity is done
+// by a signed in user.
+type OIDCStateClaims struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	jwt.RegisteredClaims
+	UserID uint `json:"-"`
+}
+
+func NewOIDCStateToken(keySet *KeySet, issuer string, lifetime time.Duration) IOIDCStateToken {
+	return &OIDCStateToken{
+		keySet:   keySet,
+		issuer:   issuer,
+		lifetime: lifetime,
+	}
+}
+
+func (ost *OIDCStateToken) GenerateToken(flow string, userId uint) (string, *OIDCStateClaims, error) {
+	jti, err := randomString(16)
+	if err != nil {
+		return "", nil, err
+	}
+	state, err := randomString(16)
+	if err != nil {
+		return "", nil, err
+	}
+	nonce, err := randomString(16)
+	if err != nil {
+		return "", nil, err
+	}
+
+	issuedAt := time.Now()
+	claims := &OIDCStateClaims{
+		State:    state,
+		Nonce:    nonce,
+		Verifier: oauth2.GenerateVerifier(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Issuer:    ost.issuer,
+			Audience:  jwt.ClaimStrings{flow},
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(issuedAt.Add(ost.lifetime)),
+		},
+		UserID: userId,
+	}
+	if userId != 0 {
+		claims.Subject = strconv.FormatUint(uint64(userId), 10)
+	}
+	token, err := ost.keySet.Sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, claims, nil
+}
+
+func (ost *OIDCStateToken) ParseToken(flow string, tokenStr string) (*OIDCStateClaims, error) {
+	claims := &OIDCStateClaims{}
+	_, err := jwt.ParseWithClaims(tokenStr, claims, ost.keySet.Keyfunc,
+		jwt.WithIssuer(ost.issuer),
+		jwt.WithAudience(flow),
+	)
+	if err != nil {
+		return nil, tokenError(err)
+	}
+
+	if claims.Subject != "" {
+		userId, err := strconv.ParseUint(claims.Subject, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: subject is not a user id", ErrTokenInvalidClaims)
+		}
+		claims.UserID = uint(userId)
+	}
+	return claims, nil
+}
+
+// Validate requires the claims GenerateToken always sets, the jti lets a
+// login be finished only once.
+func (claims *OIDCStateClaims) Validate() error {
+	if claims.ExpiresAt == nil || claims.IssuedAt == nil || claims.ID == "" ||
+		claims.State == "" || claims.Nonce == "" || claims.Verifier == "" {
+		return jwt.ErrTokenRequiredClaimMissing
+	}
+	return nil
+}
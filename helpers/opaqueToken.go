@@ -0,0 +1,74 @@
+package helpers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+type IOpaqueToken interface {
+	Generate() (token string, tokenHash string, err error)
+	Hash(token string) string
+	ExpiresAt() time.Time
+}
+
+type IRefreshToken interface {
+	IOpaqueToken
+	NewFamilyID() (string, error)
+}
+
+// OpaqueToken creates random tokens that mean nothing by themselves, like
+// refresh or password reset tokens. Only their hash is meant to be stored so
+// a leaked table can't be used to redeem any of them.
+type OpaqueToken struct {
+	lifetime time.Duration
+}
+
+func NewOpaqueToken(lifetime time.Duration) IOpaqueToken {
+	return &OpaqueToken{
+		lifetime: lifetime,
+	}
+}
+
+func (ot *OpaqueToken) Generate() (string, string, error) {
+	token, err := randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	return token, ot.Hash(token), nil
+}
+
+func (ot *OpaqueToken) Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (ot *OpaqueToken) ExpiresAt() time.Time {
+	return time.Now().Add(ot.lifetime)
+}
+
+// RefreshToken is an OpaqueToken that also belongs to a family, the tokens
+// obtained by rotating each other.
+type RefreshToken struct {
+	OpaqueToken
+}
+
+func NewRefreshToken(lifetime time.Duration) IRefreshToken {
+	return &RefreshToken{
+		OpaqueToken: OpaqueToken{lifetime: lifetime},
+	}
+}
+
+func (rt *RefreshToken) NewFamilyID() (string, error) {
+	return randomString(16)
+}
+
+func randomString(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
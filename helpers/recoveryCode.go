@@ -0,0 +1,45 @@
+package helpers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+type IRecoveryCode interface {
+	Generate(count int) (codes []string, codeHashes []string, err error)
+	Hash(code string) string
+}
+
+// RecoveryCode creates the one-time codes that replace a TOTP code when the
+// authenticator is lost. Like opaque tokens only their hash is stored.
+type RecoveryCode struct{}
+
+func NewRecoveryCode() IRecoveryCode {
+	return &RecoveryCode{}
+}
+
+// Generate returns count codes of 80 bits formatted as xxxx-xxxx-xxxx-xxxx.
+func (rc *RecoveryCode) Generate(count int) ([]string, []string, error) {
+	codes := make([]string, 0, count)
+	codeHashes := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		buf := make([]byte, 10)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		encoded := strings.ToLower(totpEncoding.EncodeToString(buf))
+		code := encoded[0:4] + "-" + encoded[4:8] + "-" + encoded[8:12] + "-" + encoded[12:16]
+		codes = append(codes, code)
+		codeHashes = append(codeHashes, rc.Hash(code))
+	}
+	return codes, codeHashes, nil
+}
+
+// Hash ignores case, spaces and dashes, the way users tend to retype codes.
+func (rc *RecoveryCode) Hash(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
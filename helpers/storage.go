@@ -0,0 +1,121 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type IStorage interface {
+	Save(file *multipart.FileHeader, filename string) error
+	Write(filename string, content io.Reader) error
+	Remove(filename string) error
+	StageRemoval(filename string) (*StagedRemoval, error)
+	FilenameFromUrl(photoUrl string) string
+	Path(filename string) string
+}
+
+type Storage struct {
+	dir string
+}
+
+// StagedRemoval is a file that has been moved aside instead of deleted, so a
+// failed database transaction can put it back with Rollback. Commit deletes it for good.
+type StagedRemoval struct {
+	original string
+	staged   string
+}
+
+func NewStorage(dir string) IStorage {
+	return &Storage{
+		dir: dir,
+	}
+}
+
+func (s *Storage) Save(file *multipart.FileHeader, filename string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	return s.Write(filename, src)
+}
+
+// Write copies content to a hidden file in the same folder and renames it to
+// filename once it's complete, a failed write leaves no partial file behind.
+func (s *Storage) Write(filename string, content io.Reader) error {
+	if err := os.MkdirAll(s.dir, 0750); err != nil {
+		return err
+	}
+
+	out, err := os.CreateTemp(s.dir, ".writing_*_"+filepath.Base(filename))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, content)
+	if err == nil {
+		err = out.Chmod(0640)
+	}
+	// Close reports the errors of the writes that were buffered
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(out.Name(), s.Path(filename))
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) Remove(filename string) error {
+	return os.Remove(s.Path(filename))
+}
+
+// StageRemoval moves the file to a hidden name in the same folder. A file
+// that is already gone is not an error, the returned removal is then a no-op.
+func (s *Storage) StageRemoval(filename string) (*StagedRemoval, error) {
+	original := s.Path(filename)
+	staged := s.Path(fmt.Sprintf(".removing_%d_%s", time.Now().UnixNano(), filename))
+	err := os.Rename(original, staged)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &StagedRemoval{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &StagedRemoval{original: original, staged: staged}, nil
+}
+
+func (s *Storage) FilenameFromUrl(photoUrl string) string {
+	strSliceFileLoc := strings.Split(photoUrl, "/")
+	return strSliceFileLoc[len(strSliceFileLoc)-1]
+}
+
+// Path returns where the file is stored, the name can't lead out of the directory.
+func (s *Storage) Path(filename string) string {
+	return filepath.Join(s.dir, filepath.Base(filename))
+}
+
+func (r *StagedRemoval) Commit() error {
+	if r.staged == "" {
+		return nil
+	}
+	return os.Remove(r.staged)
+}
+
+func (r *StagedRemoval) Rollback() error {
+	if r.staged == "" {
+		return nil
+	}
+	return os.Rename(r.staged, r.original)
+}
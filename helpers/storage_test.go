@@ -0,0 +1,51 @@
+package helpers
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// failingReader returns part of the content and then fails, like an upload
+// cut short.
+type failingReader struct {
+	read bool
+}
+
+func (reader *failingReader) Read(p []byte) (int, error) {
+	if reader.read {
+		return 0, errors.New("connection reset")
+	}
+	reader.read = true
+	return copy(p, "partial"), nil
+}
+
+func TestStorageWrite(t *testing.T) {
+	dir := t.TempDir()
+	storage := NewStorage(dir)
+
+	if err := storage.Write("photo.jpg", strings.NewReader("content")); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(dir + "/photo.jpg")
+	if err != nil || string(content) != "content" {
+		t.Fatalf("expected the content to be written, got %q, err %v", content, err)
+	}
+
+	// A failed write keeps the previous file and leaves nothing else behind
+	if err := storage.Write("photo.jpg", &failingReader{}); err == nil {
+		t.Fatal("expected the write to fail")
+	}
+	content, err = os.ReadFile(dir + "/photo.jpg")
+	if err != nil || string(content) != "content" {
+		t.Fatalf("expected the previous content to be kept, got %q, err %v", content, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected no temporary file to be left, got %v", entries)
+	}
+}
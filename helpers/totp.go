@@ -0,0 +1,108 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	totpDigits = 6
+	totpPeriod = 30 * time.Second
+	// totpSkew is how many periods before and after the current one are
+	// accepted, for clocks that drift and codes typed near the boundary.
+	totpSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type ITOTP interface {
+	GenerateSecret() (string, error)
+	URI(secret string, accountName string) string
+	Validate(secret string, code string, lastUsedStep int64) (step int64, ok bool)
+}
+
+// TOTP implements RFC 6238 time-based one-time passwords with the parameters
+// authenticator apps assume: HMAC-SHA1, 6 digits and a 30 seconds period.
+type TOTP struct {
+	issuer string
+}
+
+func NewTOTP(issuer string) ITOTP {
+	return &TOTP{
+		issuer: issuer,
+	}
+}
+
+// GenerateSecret returns a 160 bits secret encoded in base32 without padding.
+func (totp *TOTP) GenerateSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// URI returns the otpauth:// URI authenticator apps read from a QR code.
+func (totp *TOTP) URI(secret string, accountName string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", totp.issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(int(totpPeriod.Seconds())))
+	return (&url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + totp.issuer + ":" + accountName,
+		RawQuery: query.Encode(),
+	}).String()
+}
+
+// Validate checks code against the steps around now and returns the step it
+// matched. Steps up to lastUsedStep are refused so a code can't be replayed.
+func (totp *TOTP) Validate(secret string, code string, lastUsedStep int64) (int64, bool) {
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+
+	current := time.Now().Unix() / int64(totpPeriod.Seconds())
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastUsedStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(hotp(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// hotp computes the RFC 4226 code of counter.
+func hotp(key []byte, counter int64) string {
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulo := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%modulo)
+}
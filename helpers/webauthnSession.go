@@ -0,0 +1,97 @@
+package helpers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// WebAuthn ceremonies a session token can be issued for, each one is its own
+// audience so a registration session can't finish a login.
+const (
+	WebAuthnRegistration = "webauthn-registration"
+	WebAuthnLogin        = "webauthn-login"
+)
+
+type IWebAuthnSessionToken interface {
+	GenerateToken(ceremony string, userId uint, session *webauthn.SessionData) (string, error)
+	ParseToken(ceremony string, tokenStr string) (*WebAuthnSessionClaims, error)
+}
+
+// WebAuthnSessionToken carries the session data of a ceremony between its
+// begin and finish requests, signed so the server doesn't have to store it.
+type WebAuthnSessionToken struct {
+	keySet   *KeySet
+	issuer   string
+	lifetime time.Duration
+}
+
+// WebAuthnSessionClaims has no subject for a login, the user is only known
+// once the authenticator answers.
+type WebAuthnSessionClaims struct {
+	Session webauthn.SessionData `json:"session"`
+	jwt.RegisteredClaims
+	UserID uint `json:"-"`
+}
+
+func NewWebAuthnSessionToken(keySet *KeySet, issuer string, lifetime time.Duration) IWebAuthnSessionToken {
+	return &WebAuthnSessionToken{
+		keySet:   keySet,
+		issuer:   issuer,
+		lifetime: lifetime,
+	}
+}
+
+func (wst *WebAuthnSessionToken) GenerateToken(ceremony string, userId uint, session *webauthn.SessionData) (string, error) {
+	jti, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+	issuedAt := time.Now()
+	claims := &WebAuthnSessionClaims{
+		Session: *session,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Issuer:    wst.issuer,
+			Audience:  jwt.ClaimStrings{ceremony},
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(issuedAt.Add(wst.lifetime)),
+		},
+	}
+	if userId != 0 {
+		claims.Subject = strconv.FormatUint(uint64(userId), 10)
+	}
+	return wst.keySet.Sign(claims)
+}
+
+func (wst *WebAuthnSessionToken) ParseToken(ceremony string, tokenStr string) (*WebAuthnSessionClaims, error) {
+	claims := &WebAuthnSessionClaims{}
+	_, err := jwt.ParseWithClaims(tokenStr, claims, wst.keySet.Keyfunc,
+		jwt.WithIssuer(wst.issuer),
+		jwt.WithAudience(ceremony),
+	)
+	if err != nil {
+		return nil, tokenError(err)
+	}
+
+	if claims.Subject != "" {
+		userId, err := strconv.ParseUint(claims.Subject, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: subject is not a user id", ErrTokenInvalidClaims)
+		}
+		claims.UserID = uint(userId)
+	}
+	return claims, nil
+}
+
+// Validate requires the claims GenerateToken always sets, the jti lets a
+// session be finished only once.
+func (claims *WebAuthnSessionClaims) Validate() error {
+	if claims.ExpiresAt == nil || claims.IssuedAt == nil || claims.ID == "" || claims.Session.Challenge == "" {
+		return jwt.ErrTokenRequiredClaimMissing
+	}
+	return nil
+}
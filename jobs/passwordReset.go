@@ -0,0 +1,142 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/mailer"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"gorm.io/gorm"
+)
+
+// passwordResetQueueSize is how many requests can wait to be sent, further
+// requests are dropped until the queue catches up.
+const passwordResetQueueSize = 100
+
+type IPasswordResetQueue interface {
+	// Enqueue asks to email a reset token to the email, it returns false when
+	// the queue is full and the request is dropped.
+	Enqueue(email string) bool
+	Start()
+	Stop()
+	RunOnce(email string) error
+}
+
+// PasswordResetQueue looks up the users and emails their reset tokens after
+// the response has been sent, so the time POST /users/password/forgot takes
+// doesn't tell whether an email belongs to an account.
+type PasswordResetQueue struct {
+	db                 database.IDatabase
+	userModel          models.IUserModel
+	passwordResetModel models.IPasswordResetModel
+	resetToken         helpers.IOpaqueToken
+	mailSender         mailer.IMailer
+	resetURL           string
+	requests           chan string
+	stop               chan struct{}
+	done               chan struct{}
+}
+
+func NewPasswordResetQueue(db database.IDatabase, resetToken helpers.IOpaqueToken, mailSender mailer.IMailer, resetURL string) IPasswordResetQueue {
+	return &PasswordResetQueue{
+		db:                 db,
+		userModel:          models.NewUserModel(db.Primary()),
+		passwordResetModel: models.NewPasswordResetModel(db.Primary()),
+		resetToken:         resetToken,
+		mailSender:         mailSender,
+		resetURL:           resetURL,
+		requests:           make(chan string, passwordResetQueueSize),
+	}
+}
+
+func (queue *PasswordResetQueue) Enqueue(email string) bool {
+	select {
+	case queue.requests <- email:
+		return true
+	default:
+		return false
+	}
+}
+
+func (queue *PasswordResetQueue) Start() {
+	queue.stop = make(chan struct{})
+	queue.done = make(chan struct{})
+	go func() {
+		defer close(queue.done)
+		for {
+			select {
+			case email := <-queue.requests:
+				queue.run(email)
+			case <-queue.stop:
+				// Permintaan yang sudah diterima tetap dikirimkan sebelum server berhenti
+				for {
+					select {
+					case email := <-queue.requests:
+						queue.run(email)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+}
+
+// Stop waits for the queued requests to be sent.
+func (queue *PasswordResetQueue) Stop() {
+	if queue.stop == nil {
+		return
+	}
+	close(queue.stop)
+	<-queue.done
+	queue.stop = nil
+}
+
+func (queue *PasswordResetQueue) run(email string) {
+	if err := queue.RunOnce(email); err != nil {
+		log.Printf("password reset: %s", err.Error())
+	}
+}
+
+// RunOnce emails a new reset token to the user of the email, an unknown or
+// disabled user is skipped.
+func (queue *PasswordResetQueue) RunOnce(email string) error {
+	user, err := queue.userModel.GetByEmail(email, false)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.DisabledAt != nil {
+		return nil
+	}
+
+	// Hanya token reset terakhir yang dapat digunakan
+	expiresAt := queue.resetToken.ExpiresAt()
+	token, tokenHash, err := queue.resetToken.Generate()
+	if err != nil {
+		return fmt.Errorf("failed to create token for user %d: %w", user.ID, err)
+	}
+	err = queue.db.WithTransaction(func(tx database.IDatabase) error {
+		txModel := queue.passwordResetModel.WithDB(tx)
+		if err := txModel.InvalidateByUser(user.ID); err != nil {
+			return err
+		}
+		_, err := txModel.CreateToken(user.ID, tokenHash, expiresAt)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create token for user %d: %w", user.ID, err)
+	}
+
+	lifetime := time.Until(expiresAt).Round(time.Minute)
+	if err := queue.mailSender.Send(mailer.PasswordResetMessage(user.Email, token, queue.resetURL, lifetime)); err != nil {
+		return fmt.Errorf("failed to send email to user %d: %w", user.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+)
+
+type IPurgeJob interface {
+	Start(interval time.Duration)
+	Stop()
+	RunOnce() error
+}
+
+// PurgeJob permanently removes users and photos, rows and files, that have
+// been in the trash for longer than the retention period, along with expired
+// refresh tokens, token revocations and password reset tokens.
+type PurgeJob struct {
+	db                 database.IDatabase
+	userModel          models.IUserModel
+	photoModel         models.IPhotoModel
+	refreshTokenModel  models.IRefreshTokenModel
+	revocationModel    models.ITokenRevocationModel
+	passwordResetModel models.IPasswordResetModel
+	storage            helpers.IStorage
+	retention          time.Duration
+	tokenLeeway        time.Duration
+	stop               chan struct{}
+	done               chan struct{}
+}
+
+// NewPurgeJob keeps the token revocations for tokenLeeway after the tokens
+// they revoke expire, tokens are still accepted for that long.
+func NewPurgeJob(db database.IDatabase, storage helpers.IStorage, retention time.Duration, tokenLeeway time.Duration) IPurgeJob {
+	return &PurgeJob{
+		db:                 db,
+		userModel:          models.NewUserModel(db.Primary()),
+		photoModel:         models.NewPhotoModel(db.Primary()),
+		refreshTokenModel:  models.NewRefreshTokenModel(db.Primary()),
+		revocationModel:    models.NewTokenRevocationModel(db.Primary()),
+		passwordResetModel: models.NewPasswordResetModel(db.Primary()),
+		storage:            storage,
+		retention:          retention,
+		tokenLeeway:        tokenLeeway,
+	}
+}
+
+func (job *PurgeJob) Start(interval time.Duration) {
+	job.stop = make(chan struct{})
+	job.done = make(chan struct{})
+	go func() {
+		defer close(job.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := job.RunOnce(); err != nil {
+				log.Printf("purge: %s", err.Error())
+			}
+			select {
+			case <-job.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (job *PurgeJob) Stop() {
+	if job.stop == nil {
+		return
+	}
+	close(job.stop)
+	<-job.done
+	job.stop = nil
+}
+
+func (job *PurgeJob) RunOnce() error {
+	deletedBefore := time.Now().Add(-job.retention)
+
+	// User dihapus lebih dulu karena photo miliknya ikut terhapus melalui cascade.
+	users, err := job.userModel.GetTrashedBefore(deletedBefore)
+	if err != nil {
+		return err
+	}
+	for i := range users {
+		user := &users[i]
+		filenames := []string{}
+		for _, photo := range user.Photos {
+			filenames = append(filenames, job.storage.FilenameFromUrl(photo.PhotoUrl))
+		}
+		err := job.purge(filenames, func(tx database.IDatabase) error {
+			_, err := job.userModel.WithDB(tx).PurgeUser(user)
+			return err
+		})
+		if err != nil {
+			log.Printf("purge: failed to purge user %d: %s", user.ID, err.Error())
+		}
+	}
+
+	photos, err := job.photoModel.GetTrashedBefore(deletedBefore)
+	if err != nil {
+		return err
+	}
+	for i := range photos {
+		photo := &photos[i]
+		err := job.purge([]string{job.storage.FilenameFromUrl(photo.PhotoUrl)}, func(tx database.IDatabase) error {
+			_, err := job.photoModel.WithDB(tx).PurgePhoto(photo)
+			return err
+		})
+		if err != nil {
+			log.Printf("purge: failed to purge photo %d: %s", photo.ID, err.Error())
+		}
+	}
+
+	// Refresh token yang kedaluwarsa tidak dapat digunakan lagi, termasuk untuk mendeteksi reuse.
+	if _, err := job.refreshTokenModel.DeleteExpiredBefore(time.Now()); err != nil {
+		return err
+	}
+	// Pencabutan akses token tidak diperlukan lagi setelah token yang dicabut kedaluwarsa, termasuk leeway-nya.
+	if _, err := job.revocationModel.DeleteExpiredBefore(time.Now().Add(-job.tokenLeeway)); err != nil {
+		return err
+	}
+	if _, err := job.passwordResetModel.DeleteExpiredBefore(time.Now()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// purge deletes rows with fn and the given files in one transaction, the files
+// are moved aside first and only removed once the transaction has committed.
+func (job *PurgeJob) purge(filenames []string, fn func(tx database.IDatabase) error) error {
+	stagedFiles := []*helpers.StagedRemoval{}
+	err := job.db.WithTransaction(func(tx database.IDatabase) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		for _, filename := range filenames {
+			stagedFile, err := job.storage.StageRemoval(filename)
+			if err != nil {
+				return err
+			}
+			stagedFiles = append(stagedFiles, stagedFile)
+		}
+		return nil
+	})
+	if err != nil {
+		for _, stagedFile := range stagedFiles {
+			if restoreErr := stagedFile.Rollback(); restoreErr != nil {
+				log.Printf("purge: failed to restore file: %s", restoreErr.Error())
+			}
+		}
+		return err
+	}
+
+	for _, stagedFile := range stagedFiles {
+		if err := stagedFile.Commit(); err != nil {
+			log.Printf("purge: failed to remove file: %s", err.Error())
+		}
+	}
+	return nil
+}
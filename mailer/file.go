@@ -0,0 +1,38 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// FileMailer writes every message to its own .eml file, handy for local
+// development and for tests that need to read what was sent.
+type FileMailer struct {
+	from    string
+	dir     string
+	counter atomic.Uint64
+}
+
+func NewFileMailer(from string, dir string) IMailer {
+	return &FileMailer{
+		from: from,
+		dir:  dir,
+	}
+}
+
+func (fileMailer *FileMailer) Send(message *Message) error {
+	if err := validHeader(message.To, message.Subject); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(fileMailer.dir, 0750); err != nil {
+		return err
+	}
+
+	recipient := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(message.To)
+	filename := fmt.Sprintf("%d_%d_%s.eml", time.Now().UnixNano(), fileMailer.counter.Add(1), recipient)
+	return os.WriteFile(filepath.Join(fileMailer.dir, filename), encode(fileMailer.from, message), 0640)
+}
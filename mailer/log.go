@@ -0,0 +1,23 @@
+package mailer
+
+import "log"
+
+// LogMailer prints the messages to the log instead of sending them, meant for
+// local development.
+type LogMailer struct {
+	from string
+}
+
+func NewLogMailer(from string) IMailer {
+	return &LogMailer{
+		from: from,
+	}
+}
+
+func (logMailer *LogMailer) Send(message *Message) error {
+	if err := validHeader(message.To, message.Subject); err != nil {
+		return err
+	}
+	log.Printf("mailer: message to %s\n%s", message.To, encode(logMailer.from, message))
+	return nil
+}
@@ -0,0 +1,72 @@
+package mailer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Driver names accepted by New.
+const (
+	DriverLog  = "log"
+	DriverFile = "file"
+	DriverSMTP = "smtp"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type IMailer interface {
+	Send(message *Message) error
+}
+
+type Config struct {
+	Driver string
+	From   string
+	// FileDir is where DriverFile writes the messages.
+	FileDir      string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+}
+
+func New(config *Config) (IMailer, error) {
+	switch config.Driver {
+	case DriverLog:
+		return NewLogMailer(config.From), nil
+	case DriverFile:
+		return NewFileMailer(config.From, config.FileDir), nil
+	case DriverSMTP:
+		return NewSMTPMailer(config.From, config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword), nil
+	default:
+		return nil, fmt.Errorf("unsupported mail driver %q", config.Driver)
+	}
+}
+
+// encode renders the message as a plain text RFC 5322 message.
+func encode(from string, message *Message) []byte {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "From: %s\r\n", from)
+	fmt.Fprintf(&builder, "To: %s\r\n", message.To)
+	fmt.Fprintf(&builder, "Subject: %s\r\n", message.Subject)
+	fmt.Fprintf(&builder, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return []byte(builder.String())
+}
+
+// validHeader refuses header values that would let a caller inject headers.
+func validHeader(values ...string) error {
+	for _, value := range values {
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("mailer: header value %q contains a line break", value)
+		}
+	}
+	return nil
+}
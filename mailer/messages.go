@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PasswordResetMessage tells the user how to reset their password, with a
+// link to resetURL when the client has a page for it.
+func PasswordResetMessage(to string, token string, resetURL string, lifetime time.Duration) *Message {
+	var body strings.Builder
+	body.WriteString("Someone asked to reset the password of your account. If it wasn't you, ignore this email, your password stays the same.\n\n")
+	if resetURL != "" {
+		fmt.Fprintf(&body, "Pick a new password here: %s\n\n", withQuery(resetURL, "token", token))
+	}
+	fmt.Fprintf(&body, "Or send this token to POST /users/password/reset: %s\n\n", token)
+	fmt.Fprintf(&body, "It can be used once and expires in %s.\n", lifetime)
+	return &Message{
+		To:      to,
+		Subject: "Reset your password",
+		Body:    body.String(),
+	}
+}
+
+// EmailVerificationMessage asks the user to confirm that they own the address
+// by opening verifyURL with the signed token.
+func EmailVerificationMessage(to string, token string, verifyURL string, lifetime time.Duration) *Message {
+	var body strings.Builder
+	body.WriteString("Please confirm this email address for your account. If you didn't sign up or change your email, ignore this email.\n\n")
+	fmt.Fprintf(&body, "Verify your email here: %s\n\n", withQuery(verifyURL, "token", token))
+	fmt.Fprintf(&body, "The link expires in %s.\n", lifetime)
+	return &Message{
+		To:      to,
+		Subject: "Verify your email address",
+		Body:    body.String(),
+	}
+}
+
+func withQuery(rawURL string, key string, value string) string {
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+	}
+	return rawURL + separator + url.QueryEscape(key) + "=" + url.QueryEscape(value)
+}
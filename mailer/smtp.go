@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+)
+
+// SMTPMailer sends through an SMTP server, net/smtp upgrades the connection
+// with STARTTLS whenever the server offers it and only authenticates over TLS
+// or to localhost.
+type SMTPMailer struct {
+	from     string
+	address  string
+	host     string
+	username string
+	password string
+}
+
+func NewSMTPMailer(from string, host string, port int, username string, password string) IMailer {
+	return &SMTPMailer{
+		from:     from,
+		address:  net.JoinHostPort(host, strconv.Itoa(port)),
+		host:     host,
+		username: username,
+		password: password,
+	}
+}
+
+func (smtpMailer *SMTPMailer) Send(message *Message) error {
+	if err := validHeader(message.To, message.Subject); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if smtpMailer.username != "" {
+		auth = smtp.PlainAuth("", smtpMailer.username, smtpMailer.password, smtpMailer.host)
+	}
+	// MAIL_FROM may carry a display name, the envelope only takes the address
+	envelopeFrom, err := mail.ParseAddress(smtpMailer.from)
+	if err != nil {
+		return fmt.Errorf("mailer: invalid sender: %w", err)
+	}
+	err = smtp.SendMail(smtpMailer.address, auth, envelopeFrom.Address, []string{message.To}, encode(smtpMailer.from, message))
+	if err != nil {
+		return fmt.Errorf("mailer: %w", err)
+	}
+	return nil
+}
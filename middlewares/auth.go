@@ -3,38 +3,102 @@ package middlewares
 import (
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/app"
 	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
 	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/stores"
 	"gorm.io/gorm"
 )
 
 type IAuthMiddleware interface {
-	Guard() gin.HandlerFunc
-	Authorize(model interface{}) gin.HandlerFunc
+	Guard(options ...GuardOption) gin.HandlerFunc
+	RequirePermission(permission string) gin.HandlerFunc
+	Authorize(param string, options ...AuthorizeOption) gin.HandlerFunc
 }
 
 type AuthMiddleware struct {
-	userModel models.IUserModel
-	webToken  helpers.IWebToken
+	userModel       models.IUserModel
+	apiKeyModel     models.IAPIKeyModel
+	auditLogModel   models.IAuditLogModel
+	webToken        helpers.IWebToken
+	apiKey          helpers.IAPIKey
+	revocationStore stores.IRevocationStore
+	resources       *OwnershipRegistry
 }
 
-func NewAuthMiddleware(userModel models.IUserModel, webToken helpers.IWebToken) IAuthMiddleware {
+// NewAuthMiddleware expects apiKeyModel to read from the primary, an API key
+// read from a lagging replica would still authenticate after it's revoked.
+func NewAuthMiddleware(userModel models.IUserModel, apiKeyModel models.IAPIKeyModel, auditLogModel models.IAuditLogModel, webToken helpers.IWebToken, apiKey helpers.IAPIKey, revocationStore stores.IRevocationStore, resources *OwnershipRegistry) IAuthMiddleware {
 	return &AuthMiddleware{
-		userModel: userModel,
-		webToken:  webToken,
+		userModel:       userModel,
+		apiKeyModel:     apiKeyModel,
+		auditLogModel:   auditLogModel,
+		webToken:        webToken,
+		apiKey:          apiKey,
+		revocationStore: revocationStore,
+		resources:       resources,
 	}
 }
 
-func (authMW *AuthMiddleware) Guard() gin.HandlerFunc {
+// guardRealm is the realm of the WWW-Authenticate challenges sent by Guard.
+const guardRealm = "photo-api"
+
+// tokenErrorMessages maps the errors of ParseToken to the message returned to the client.
+var tokenErrorMessages = []struct {
+	err     error
+	message string
+}{
+	{helpers.ErrTokenMalformed, "Token provided is malformed"},
+	{helpers.ErrTokenSignatureInvalid, "Token signature is invalid"},
+	{helpers.ErrTokenExpired, "Token is expired, please login again"},
+	{helpers.ErrTokenNotValidYet, "Token is not valid yet"},
+	{helpers.ErrTokenInvalidAudience, "Token was issued for another audience"},
+	{helpers.ErrTokenInvalidIssuer, "Token was issued by another issuer"},
+	{helpers.ErrTokenInvalidClaims, "Token claims are invalid"},
+}
+
+// guardOptions holds the extra requirements a route puts on the authenticated user.
+type guardOptions struct {
+	requireVerifiedEmail bool
+	scopes               []string
+}
+
+type GuardOption func(*guardOptions)
+
+// RequireVerifiedEmail rejects users who haven't verified their email address yet.
+func RequireVerifiedEmail() GuardOption {
+	return func(options *guardOptions) {
+		options.requireVerifiedEmail = true
+	}
+}
+
+// RequireScope lets API keys with the scope use the route, routes without a
+// required scope only accept access tokens. Access tokens have every scope.
+func RequireScope(scope string) GuardOption {
+	return func(options *guardOptions) {
+		options.scopes = append(options.scopes, scope)
+	}
+}
+
+func (authMW *AuthMiddleware) Guard(options ...GuardOption) gin.HandlerFunc {
+	guard := &guardOptions{}
+	for _, option := range options {
+		option(guard)
+	}
+
 	return func(c *gin.Context) {
 		bearerToken := c.Request.Header.Get("Authorization")
 		if bearerToken == "" {
+			// Tanpa kredensial challenge tidak menyertakan kode error (RFC 6750 bagian 3.1)
+			c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q", guardRealm))
 			c.AbortWithStatusJSON(http.StatusUnauthorized, &app.JsendFailResponse{
 				Status: "fail",
 				Data: gin.H{
@@ -44,136 +108,216 @@ func (authMW *AuthMiddleware) Guard() gin.HandlerFunc {
 			return
 		}
 
-		if len(strings.Split(bearerToken, " ")) != 2 {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, &app.JsendFailResponse{
-				Status: "fail",
-				Data: gin.H{
-					"token": "Token provided is invalid",
-				},
-			})
+		scheme, tokenStr, found := strings.Cut(bearerToken, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || tokenStr == "" || strings.Contains(tokenStr, " ") {
+			abortUnauthorized(c, "invalid_request", "Authorization header must be Bearer <token>")
 			return
 		}
 
-		tokenStr := strings.Split(bearerToken, " ")[1]
+		// API key hanya diterima pada route yang membutuhkan scope tertentu
+		var userId uint
+		var claims *helpers.UserClaims
+		var apiKey *models.APIKey
+		if authMW.apiKey.IsAPIKey(tokenStr) {
+			var ok bool
+			if apiKey, ok = authMW.verifyAPIKey(c, tokenStr, guard.scopes); !ok {
+				return
+			}
+			userId = apiKey.UserID
+		} else {
+			var ok bool
+			if claims, ok = authMW.verifyAccessToken(c, tokenStr); !ok {
+				return
+			}
+			userId = claims.UserID
+		}
 
-		isExpired, err := authMW.webToken.IsTokenExpired(tokenStr)
+		currentUser, err := authMW.userModel.GetById(userId, false)
 		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				abortUnauthorized(c, "invalid_token", "There's no user found related to the token")
+				return
+			}
 			c.AbortWithStatusJSON(http.StatusInternalServerError, &app.JsendErrorResponse{
 				Status:  "error",
 				Message: err.Error(),
 			})
 			return
 		}
-		if isExpired {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, &app.JsendFailResponse{
+		if currentUser.DisabledAt != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, &app.JsendFailResponse{
 				Status: "fail",
 				Data: gin.H{
-					"token": "token is expired, please login again",
+					"message": "This account has been disabled",
 				},
 			})
 			return
 		}
-
-		claims, err := authMW.webToken.ParseToken(tokenStr)
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, &app.JsendErrorResponse{
-				Status:  "error",
-				Message: err.Error(),
-			})
-			return
-		}
-
-		currentUser, err := authMW.userModel.GetById(claims.ID, false)
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusNotFound, &app.JsendFailResponse{
+		if guard.requireVerifiedEmail && currentUser.EmailVerifiedAt == nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, &app.JsendFailResponse{
 				Status: "fail",
 				Data: gin.H{
-					"message": "There's no user found related to the token",
+					"message": "Please verify your email address first",
 				},
 			})
 			return
 		}
 		c.Set("currentUser", currentUser)
+		if apiKey != nil {
+			c.Set("currentAPIKey", apiKey)
+		} else {
+			c.Set("currentClaims", claims)
+		}
 		c.Next()
 	}
 }
 
-func (authMW *AuthMiddleware) Authorize(model interface{}) gin.HandlerFunc {
-	return func(c *gin.Context) {
-
-		currentUser := c.MustGet("currentUser").(*models.User)
-		var ownerId uint = 0
-		var castingStat bool = true
-		var parseError error = nil
-		var queryError error = nil
-		var errorResource string = "unknown"
-
-		switch true {
-		case c.Param("userId") != "":
-			userModel, ok := model.(models.IUserModel)
-			if !ok {
-				errorResource = "user"
-				castingStat = false
-				break
-			}
-			parsedId, err := strconv.ParseUint(c.Param("userId"), 10, 32)
-			if err != nil {
-				errorResource = "user"
-				parseError = err
+// verifyAccessToken memverifikasi signature kemudian seluruh claims access token,
+// token yang telah dicabut ditolak.
+func (authMW *AuthMiddleware) verifyAccessToken(c *gin.Context, tokenStr string) (*helpers.UserClaims, bool) {
+	claims, err := authMW.webToken.ParseToken(tokenStr)
+	if err != nil {
+		message := "Token provided is invalid"
+		for _, tokenError := range tokenErrorMessages {
+			if errors.Is(err, tokenError.err) {
+				message = tokenError.message
 				break
 			}
+		}
+		abortUnauthorized(c, "invalid_token", message)
+		return nil, false
+	}
 
-			requestedUser, err := userModel.GetById(uint(parsedId), false)
-			if err != nil {
-				errorResource = "user"
-				queryError = err
-				break
-			}
+	if authMW.revocationStore.IsRevoked(claims.ID, claims.UserID, claims.IssuedAt.Time) {
+		abortUnauthorized(c, "invalid_token", "Token has been revoked, please login again")
+		return nil, false
+	}
+	return claims, true
+}
 
-			c.Set("requestedUser", requestedUser)
+// verifyAPIKey mencari API key berdasarkan hash, key harus aktif dan memiliki seluruh
+// scope yang dibutuhkan route.
+func (authMW *AuthMiddleware) verifyAPIKey(c *gin.Context, key string, scopes []string) (*models.APIKey, bool) {
+	if len(scopes) == 0 {
+		abortForbidden(c, "", "API keys can't be used here, please login")
+		return nil, false
+	}
 
-			ownerId = requestedUser.ID
-		case c.Param("photoId") != "":
-			photoModel, ok := model.(models.IPhotoModel)
-			if !ok {
-				errorResource = "photo"
-				castingStat = false
-				break
-			}
-			parsedId, err := strconv.ParseUint(c.Param("photoId"), 10, 32)
-			if err != nil {
-				errorResource = "photo"
-				parseError = err
-				break
-			}
+	apiKey, err := authMW.apiKeyModel.GetByHash(authMW.apiKey.Hash(key))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			abortUnauthorized(c, "invalid_token", "API key provided is invalid")
+			return nil, false
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return nil, false
+	}
+	if !apiKey.IsActive(time.Now()) {
+		abortUnauthorized(c, "invalid_token", "API key is expired or has been revoked")
+		return nil, false
+	}
+	for _, scope := range scopes {
+		if !apiKey.HasScope(scope) {
+			abortForbidden(c, strings.Join(scopes, " "), fmt.Sprintf("API key doesn't have the %s scope", scope))
+			return nil, false
+		}
+	}
 
-			requestedPhoto, err := photoModel.GetById(uint(parsedId), false)
-			if err != nil {
-				errorResource = "photo"
-				queryError = err
-				break
-			}
+	// Waktu penggunaan terakhir hanya informasi untuk user, kegagalan tidak menolak request
+	if _, err := authMW.apiKeyModel.MarkUsed(apiKey); err != nil {
+		log.Printf("api key: failed to record the use of key %d: %s", apiKey.ID, err.Error())
+	}
+	return apiKey, true
+}
 
-			c.Set("requestedPhoto", requestedPhoto)
+// abortUnauthorized responds 401 with a Bearer challenge carrying the RFC 6750 error code.
+func abortUnauthorized(c *gin.Context, errorCode string, message string) {
+	c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q, error=%q, error_description=%q", guardRealm, errorCode, message))
+	c.AbortWithStatusJSON(http.StatusUnauthorized, &app.JsendFailResponse{
+		Status: "fail",
+		Data: gin.H{
+			"token": message,
+		},
+	})
+}
 
-			ownerId = requestedPhoto.UserID
-		default:
-			c.AbortWithStatusJSON(http.StatusInternalServerError, &app.JsendErrorResponse{
-				Status:  "error",
-				Message: "There is something wrong in the authorization middleware",
-			})
-			return
-		}
+// abortForbidden responds 403 with an insufficient_scope challenge listing the
+// scopes the route needs (RFC 6750 section 3.1).
+func abortForbidden(c *gin.Context, scope string, message string) {
+	challenge := fmt.Sprintf("Bearer realm=%q, error=\"insufficient_scope\", error_description=%q", guardRealm, message)
+	if scope != "" {
+		challenge += fmt.Sprintf(", scope=%q", scope)
+	}
+	c.Header("WWW-Authenticate", challenge)
+	c.AbortWithStatusJSON(http.StatusForbidden, &app.JsendFailResponse{
+		Status: "fail",
+		Data: gin.H{
+			"token": message,
+		},
+	})
+}
 
-		if !castingStat {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, &app.JsendErrorResponse{
-				Status:  "error",
-				Message: "There's something wrong in the authorization middleware",
+// RequirePermission rejects users whose role doesn't grant the permission, it
+// runs after Guard. API keys never carry the permissions of the role.
+func (authMW *AuthMiddleware) RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(*models.User)
+		_, viaAPIKey := c.Get("currentAPIKey")
+		if viaAPIKey || !currentUser.HasPermission(permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, &app.JsendFailResponse{
+				Status: "fail",
+				Data: gin.H{
+					"message": "Access denied, you don't have the permission to access this resource",
+				},
 			})
 			return
 		}
+		c.Next()
+	}
+}
+
+// authorizeOptions holds who else than the owner may access the resource.
+type authorizeOptions struct {
+	accesses []string
+}
+
+type AuthorizeOption func(*authorizeOptions)
 
-		if parseError != nil {
+// AllowAccess lets the users granted one of the accesses on the resource go
+// further, the resolver of the resource has to implement IAccessResolver.
+func AllowAccess(accesses ...string) AuthorizeOption {
+	return func(options *authorizeOptions) {
+		options.accesses = append(options.accesses, accesses...)
+	}
+}
+
+// Authorize loads the resource named by the route param with its registered
+// resolver and lets only its owner, a user granted an allowed access or a role
+// with the permission of the resolver go further.
+func (authMW *AuthMiddleware) Authorize(param string, options ...AuthorizeOption) gin.HandlerFunc {
+	resolver, ok := authMW.resources.Get(param)
+	if !ok {
+		panic(fmt.Sprintf("authorize: no ownership resolver registered for param %s", param))
+	}
+	authorize := &authorizeOptions{}
+	for _, option := range options {
+		option(authorize)
+	}
+	accessResolver, shared := resolver.(IAccessResolver)
+	if len(authorize.accesses) != 0 && !shared {
+		panic(fmt.Sprintf("authorize: the %s resolver of param %s can't be shared", resolver.Resource(), param))
+	}
+
+	return func(c *gin.Context) {
+
+		currentUser := c.MustGet("currentUser").(*models.User)
+		errorResource := resolver.Resource()
+
+		parsedId, err := strconv.ParseUint(c.Param(param), 10, 32)
+		if err != nil {
 			c.AbortWithStatusJSON(http.StatusBadRequest, &app.JsendFailResponse{
 				Status: "fail",
 				Data: gin.H{
@@ -183,6 +327,7 @@ func (authMW *AuthMiddleware) Authorize(model interface{}) gin.HandlerFunc {
 			return
 		}
 
+		resourceId, ownerId, queryError := resolver.Resolve(c, uint(parsedId))
 		if queryError != nil {
 			if errors.Is(queryError, gorm.ErrRecordNotFound) {
 				c.AbortWithStatusJSON(http.StatusNotFound, &app.JsendFailResponse{
@@ -203,6 +348,45 @@ func (authMW *AuthMiddleware) Authorize(model interface{}) gin.HandlerFunc {
 		if currentUser.ID == ownerId {
 			c.Next()
 			return
+		}
+
+		// User yang diberi akses oleh pemilik resource tidak dicatat pada audit log
+		if len(authorize.accesses) != 0 {
+			access, err := accessResolver.Access(resourceId, currentUser.ID)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+					Status:  "error",
+					Message: err.Error(),
+				})
+				return
+			}
+			if access != "" && slices.Contains(authorize.accesses, access) {
+				c.Next()
+				return
+			}
+		}
+
+		// Role dengan permission terkait dapat mengakses resource milik user lain, setiap akses
+		// dicatat pada audit log. Akses melalui API key tidak membawa permission dari role.
+		if _, viaAPIKey := c.Get("currentAPIKey"); !viaAPIKey && currentUser.HasPermission(resolver.Permission()) {
+			_, err := authMW.auditLogModel.Record(&models.AuditLog{
+				ActorID:    currentUser.ID,
+				Action:     models.AuditAuthorizeOverride,
+				Resource:   errorResource,
+				ResourceID: resourceId,
+				OwnerID:    ownerId,
+				Method:     c.Request.Method,
+				Path:       c.Request.URL.Path,
+			})
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, &app.JsendErrorResponse{
+					Status:  "error",
+					Message: err.Error(),
+				})
+				return
+			}
+			c.Next()
+			return
 		} else {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, &app.JsendFailResponse{
 				Status: "fail",
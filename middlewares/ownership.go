@@ -0,0 +1,135 @@
+package middlewares
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+)
+
+// ContextKey names a value of type T stored in the gin context, so handlers
+// get it back without repeating the key and the type assertion.
+type ContextKey[T any] string
+
+func (key ContextKey[T]) Set(c *gin.Context, value T) {
+	c.Set(string(key), value)
+}
+
+func (key ContextKey[T]) MustGet(c *gin.Context) T {
+	return c.MustGet(string(key)).(T)
+}
+
+// Keys of the entities loaded by Authorize.
+const (
+	RequestedUser  ContextKey[*models.User]  = "requestedUser"
+	RequestedPhoto ContextKey[*models.Photo] = "requestedPhoto"
+)
+
+// IOwnershipResolver loads the resource named by a route param and tells who
+// owns it, Authorize uses it to decide who may access the resource.
+type IOwnershipResolver interface {
+	// Param is the route param holding the id of the resource.
+	Param() string
+	// Resource names the resource in error messages and in the audit log.
+	Resource() string
+	// Permission lets a role access the resource of other users.
+	Permission() string
+	// Resolve loads the resource with the id, stores it in the context and
+	// returns its id and the id of its owner.
+	Resolve(c *gin.Context, id uint) (resourceId uint, ownerId uint, err error)
+}
+
+// ownership is the IOwnershipResolver of an entity of type T.
+type ownership[T any] struct {
+	param      string
+	resource   string
+	permission string
+	key        ContextKey[T]
+	load       func(id uint) (T, error)
+	identify   func(entity T) (id uint, ownerId uint)
+}
+
+// NewOwnership returns the resolver of the entities loaded with load, identify
+// returns the id of an entity and the id of its owner.
+func NewOwnership[T any](param string, resource string, permission string, key ContextKey[T], load func(id uint) (T, error), identify func(entity T) (uint, uint)) IOwnershipResolver {
+	return &ownership[T]{
+		param:      param,
+		resource:   resource,
+		permission: permission,
+		key:        key,
+		load:       load,
+		identify:   identify,
+	}
+}
+
+func (resolver *ownership[T]) Param() string {
+	return resolver.param
+}
+
+func (resolver *ownership[T]) Resource() string {
+	return resolver.resource
+}
+
+func (resolver *ownership[T]) Permission() string {
+	return resolver.permission
+}
+
+func (resolver *ownership[T]) Resolve(c *gin.Context, id uint) (uint, uint, error) {
+	entity, err := resolver.load(id)
+	if err != nil {
+		return 0, 0, err
+	}
+	resolver.key.Set(c, entity)
+	resourceId, ownerId := resolver.identify(entity)
+	return resourceId, ownerId, nil
+}
+
+// IAccessResolver is implemented by the resolvers of resources their owner can
+// share, Access returns what the user was granted on the resource, or "" when
+// nothing was.
+type IAccessResolver interface {
+	Access(resourceId uint, userId uint) (string, error)
+}
+
+type sharedOwnership[T any] struct {
+	*ownership[T]
+	access func(resourceId uint, userId uint) (string, error)
+}
+
+// NewSharedOwnership is NewOwnership for resources shared with grants, access
+// returns the grant of a user on a resource.
+func NewSharedOwnership[T any](param string, resource string, permission string, key ContextKey[T], load func(id uint) (T, error), identify func(entity T) (uint, uint), access func(resourceId uint, userId uint) (string, error)) IOwnershipResolver {
+	return &sharedOwnership[T]{
+		ownership: NewOwnership(param, resource, permission, key, load, identify).(*ownership[T]),
+		access:    access,
+	}
+}
+
+func (resolver *sharedOwnership[T]) Access(resourceId uint, userId uint) (string, error) {
+	return resolver.access(resourceId, userId)
+}
+
+// OwnershipRegistry holds the resolvers by route param.
+type OwnershipRegistry struct {
+	resolvers map[string]IOwnershipResolver
+}
+
+func NewOwnershipRegistry() *OwnershipRegistry {
+	return &OwnershipRegistry{
+		resolvers: map[string]IOwnershipResolver{},
+	}
+}
+
+// Register panics when the param already has a resolver, like a route
+// registered twice it's a programming error.
+func (registry *OwnershipRegistry) Register(resolver IOwnershipResolver) {
+	if _, ok := registry.resolvers[resolver.Param()]; ok {
+		panic(fmt.Sprintf("ownership: param %s registered twice", resolver.Param()))
+	}
+	registry.resolvers[resolver.Param()] = resolver
+}
+
+func (registry *OwnershipRegistry) Get(param string) (IOwnershipResolver, bool) {
+	resolver, ok := registry.resolvers[param]
+	return resolver, ok
+}
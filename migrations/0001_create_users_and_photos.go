@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The structs below are a snapshot of models.User and models.Photo at the time
+// of this migration, the models package keeps evolving so it can't be used here.
+
+type user0001 struct {
+	ID        uint        `gorm:"primaryKey"`
+	Username  string      `gorm:"not null"`
+	Email     string      `gorm:"unique;not null"`
+	Password  string      `gorm:"not null"`
+	Photos    []photo0001 `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (user0001) TableName() string {
+	return "users"
+}
+
+type photo0001 struct {
+	ID        uint `gorm:"primaryKey"`
+	Title     string
+	Caption   string
+	PhotoUrl  string
+	UserID    uint
+	User      user0001
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (photo0001) TableName() string {
+	return "photos"
+}
+
+func init() {
+	register(&Migration{
+		Version: 1,
+		Name:    "create_users_and_photos",
+		Up: func(tx *gorm.DB) error {
+			// Databases created by the old AutoMigrate boot already have this schema,
+			// they only need the migration recorded.
+			if tx.Migrator().HasTable(&user0001{}) && tx.Migrator().HasTable(&photo0001{}) {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&user0001{}, &photo0001{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&photo0001{}, &user0001{})
+		},
+	})
+}
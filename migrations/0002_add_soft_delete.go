@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+type user0002 struct {
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (user0002) TableName() string {
+	return "users"
+}
+
+type photo0002 struct {
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (photo0002) TableName() string {
+	return "photos"
+}
+
+func init() {
+	register(&Migration{
+		Version: 2,
+		Name:    "add_soft_delete",
+		Up: func(tx *gorm.DB) error {
+			for _, table := range []interface{}{&user0002{}, &photo0002{}} {
+				if err := tx.Migrator().AddColumn(table, "DeletedAt"); err != nil {
+					return err
+				}
+				if err := tx.Migrator().CreateIndex(table, "DeletedAt"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, table := range []interface{}{&photo0002{}, &user0002{}} {
+				// SQLite drops a column by rebuilding the table, which loses its indexes
+				if tx.Migrator().HasIndex(table, "DeletedAt") {
+					if err := tx.Migrator().DropIndex(table, "DeletedAt"); err != nil {
+						return err
+					}
+				}
+				if err := tx.Migrator().DropColumn(table, "DeletedAt"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
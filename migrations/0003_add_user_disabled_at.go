@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type user0003 struct {
+	DisabledAt *time.Time
+}
+
+func (user0003) TableName() string {
+	return "users"
+}
+
+func init() {
+	register(&Migration{
+		Version: 3,
+		Name:    "add_user_disabled_at",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&user0003{}, "DisabledAt")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&user0003{}, "DisabledAt")
+		},
+	})
+}
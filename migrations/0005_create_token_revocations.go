@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type tokenRevocation0005 struct {
+	ID           uint    `gorm:"primaryKey"`
+	JTI          *string `gorm:"column:jti;size:64;unique"`
+	UserID       uint    `gorm:"not null;index"`
+	User         user0001
+	IssuedBefore *time.Time
+	ExpiresAt    time.Time `gorm:"index"`
+	CreatedAt    time.Time
+}
+
+func (tokenRevocation0005) TableName() string {
+	return "token_revocations"
+}
+
+func init() {
+	register(&Migration{
+		Version: 5,
+		Name:    "create_token_revocations",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&tokenRevocation0005{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&tokenRevocation0005{})
+		},
+	})
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type user0007 struct {
+	EmailVerifiedAt *time.Time
+}
+
+func (user0007) TableName() string {
+	return "users"
+}
+
+func init() {
+	register(&Migration{
+		Version: 7,
+		Name:    "add_user_email_verified_at",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&user0007{}, "EmailVerifiedAt"); err != nil {
+				return err
+			}
+			// Accounts created before verification existed keep access to everything.
+			return tx.Exec("UPDATE users SET email_verified_at = created_at").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&user0007{}, "EmailVerifiedAt")
+		},
+	})
+}
@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type userTOTP0008 struct {
+	ID             uint `gorm:"primaryKey"`
+	UserID         uint `gorm:"not null;unique"`
+	User           user0001
+	Secret         string `gorm:"not null"`
+	LastUsedStep   int64  `gorm:"not null;default:0"`
+	FailedAttempts int    `gorm:"not null;default:0"`
+	ConfirmedAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (userTOTP0008) TableName() string {
+	return "user_totps"
+}
+
+type recoveryCode0008 struct {
+	ID        uint `gorm:"primaryKey"`
+	UserID    uint `gorm:"not null;index"`
+	User      user0001
+	CodeHash  string `gorm:"size:64;not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (recoveryCode0008) TableName() string {
+	return "recovery_codes"
+}
+
+func init() {
+	register(&Migration{
+		Version: 8,
+		Name:    "create_two_factor_tables",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&userTOTP0008{}, &recoveryCode0008{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&recoveryCode0008{}, &userTOTP0008{})
+		},
+	})
+}
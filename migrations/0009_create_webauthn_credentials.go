@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type webAuthnCredential0009 struct {
+	ID              uint `gorm:"primaryKey"`
+	UserID          uint `gorm:"not null;index"`
+	User            user0001
+	CredentialID    string `gorm:"size:255;unique;not null"`
+	Name            string `gorm:"not null"`
+	PublicKey       []byte `gorm:"not null"`
+	AttestationType string
+	Transports      string
+	AAGUID          []byte
+	SignCount       uint32 `gorm:"not null;default:0"`
+	UserVerified    bool   `gorm:"not null;default:false"`
+	BackupEligible  bool   `gorm:"not null;default:false"`
+	BackupState     bool   `gorm:"not null;default:false"`
+	LastUsedAt      *time.Time
+	CreatedAt       time.Time
+}
+
+func (webAuthnCredential0009) TableName() string {
+	return "webauthn_credentials"
+}
+
+func init() {
+	register(&Migration{
+		Version: 9,
+		Name:    "create_webauthn_credentials",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&webAuthnCredential0009{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&webAuthnCredential0009{})
+		},
+	})
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type externalIdentity0010 struct {
+	ID         uint `gorm:"primaryKey"`
+	UserID     uint `gorm:"not null;index"`
+	User       user0001
+	Issuer     string `gorm:"size:255;not null;uniqueIndex:idx_external_identities_issuer_subject"`
+	Subject    string `gorm:"size:255;not null;uniqueIndex:idx_external_identities_issuer_subject"`
+	Email      string
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+func (externalIdentity0010) TableName() string {
+	return "external_identities"
+}
+
+func init() {
+	register(&Migration{
+		Version: 10,
+		Name:    "create_external_identities",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&externalIdentity0010{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&externalIdentity0010{})
+		},
+	})
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type apiKey0011 struct {
+	ID         uint `gorm:"primaryKey"`
+	UserID     uint `gorm:"not null;index"`
+	User       user0001
+	Name       string `gorm:"not null"`
+	KeyHash    string `gorm:"size:64;unique;not null"`
+	Prefix     string `gorm:"size:16;not null"`
+	Scopes     string `gorm:"not null"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+func (apiKey0011) TableName() string {
+	return "api_keys"
+}
+
+func init() {
+	register(&Migration{
+		Version: 11,
+		Name:    "create_api_keys",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&apiKey0011{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&apiKey0011{})
+		},
+	})
+}
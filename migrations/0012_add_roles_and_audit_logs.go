@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type user0012 struct {
+	Role string `gorm:"size:32;not null;default:user"`
+}
+
+func (user0012) TableName() string {
+	return "users"
+}
+
+type auditLog0012 struct {
+	ID         uint   `gorm:"primaryKey"`
+	ActorID    uint   `gorm:"not null;index"`
+	Action     string `gorm:"size:64;not null"`
+	Resource   string `gorm:"size:64;not null"`
+	ResourceID uint   `gorm:"not null"`
+	OwnerID    uint   `gorm:"not null"`
+	Method     string `gorm:"size:16"`
+	Path       string
+	Detail     string
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+func (auditLog0012) TableName() string {
+	return "audit_logs"
+}
+
+func init() {
+	register(&Migration{
+		Version: 12,
+		Name:    "add_roles_and_audit_logs",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&user0012{}, "Role"); err != nil {
+				return err
+			}
+			return tx.Migrator().CreateTable(&auditLog0012{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&auditLog0012{}); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&user0012{}, "Role")
+		},
+	})
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type photoGrant0013 struct {
+	ID        uint      `gorm:"primaryKey"`
+	PhotoID   uint      `gorm:"not null;uniqueIndex:idx_photo_grants_photo_user"`
+	Photo     photo0001 `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_photo_grants_photo_user;index"`
+	User      user0001  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Access    string    `gorm:"size:16;not null"`
+	GrantedBy uint      `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (photoGrant0013) TableName() string {
+	return "photo_grants"
+}
+
+func init() {
+	register(&Migration{
+		Version: 13,
+		Name:    "create_photo_grants",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&photoGrant0013{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&photoGrant0013{})
+		},
+	})
+}
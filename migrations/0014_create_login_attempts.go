@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type loginAttempt0014 struct {
+	ID           uint      `gorm:"primaryKey"`
+	Identifier   string    `gorm:"size:320;unique;not null"`
+	Failures     int       `gorm:"not null;default:0"`
+	LastFailedAt time.Time `gorm:"not null;index"`
+	BlockedUntil *time.Time
+}
+
+func (loginAttempt0014) TableName() string {
+	return "login_attempts"
+}
+
+func init() {
+	register(&Migration{
+		Version: 14,
+		Name:    "create_login_attempts",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&loginAttempt0014{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&loginAttempt0014{})
+		},
+	})
+}
@@ -0,0 +1,247 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"gorm.io/gorm"
+)
+
+// Migration is a single numbered schema change. Up and Down run inside a
+// transaction together with the bookkeeping row in schema_migrations, keep in
+// mind that MySQL commits DDL implicitly so a failing step may need manual cleanup there.
+type Migration struct {
+	Version uint
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+type MigrationStatus struct {
+	Version   uint
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+type SchemaMigration struct {
+	Version   uint `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// schemaMigrationLock holds at most one row, inserting it is how a process
+// takes the migration lock since a second insert violates the primary key.
+type schemaMigrationLock struct {
+	ID       uint `gorm:"primaryKey;autoIncrement:false"`
+	LockedBy string
+	LockedAt time.Time
+}
+
+func (schemaMigrationLock) TableName() string {
+	return "schema_migrations_lock"
+}
+
+var registered []*Migration
+
+func register(migration *Migration) {
+	for _, m := range registered {
+		if m.Version == migration.Version {
+			panic(fmt.Sprintf("migrations: version %d registered twice", migration.Version))
+		}
+	}
+	registered = append(registered, migration)
+	sort.Slice(registered, func(i, j int) bool {
+		return registered[i].Version < registered[j].Version
+	})
+}
+
+type IMigrator interface {
+	Up() ([]*Migration, error)
+	Down(steps int) ([]*Migration, error)
+	Status() ([]*MigrationStatus, error)
+}
+
+type Migrator struct {
+	db          database.IDatabase
+	owner       string
+	lockTimeout time.Duration
+	staleAfter  time.Duration
+}
+
+func NewMigrator(db database.IDatabase) IMigrator {
+	hostname, _ := os.Hostname()
+	return &Migrator{
+		db:          db,
+		owner:       fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+		lockTimeout: time.Minute,
+		staleAfter:  15 * time.Minute,
+	}
+}
+
+// Up applies every pending migration in version order and returns the ones it applied.
+func (migrator *Migrator) Up() ([]*Migration, error) {
+	applied := []*Migration{}
+	err := migrator.withLock(func(client *gorm.DB) error {
+		history, err := migrator.history(client)
+		if err != nil {
+			return err
+		}
+		for _, migration := range registered {
+			if _, ok := history[migration.Version]; ok {
+				continue
+			}
+			err := apply(client, func(tx *gorm.DB) error {
+				if err := migration.Up(tx); err != nil {
+					return err
+				}
+				return tx.Create(&SchemaMigration{
+					Version:   migration.Version,
+					Name:      migration.Name,
+					AppliedAt: time.Now(),
+				}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migrations: applying %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns the ones it reverted.
+func (migrator *Migrator) Down(steps int) ([]*Migration, error) {
+	reverted := []*Migration{}
+	err := migrator.withLock(func(client *gorm.DB) error {
+		history, err := migrator.history(client)
+		if err != nil {
+			return err
+		}
+		for i := len(registered) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := registered[i]
+			if _, ok := history[migration.Version]; !ok {
+				continue
+			}
+			err := apply(client, func(tx *gorm.DB) error {
+				if err := migration.Down(tx); err != nil {
+					return err
+				}
+				return tx.Delete(&SchemaMigration{Version: migration.Version}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migrations: reverting %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+func (migrator *Migrator) Status() ([]*MigrationStatus, error) {
+	client := migrator.db.GetClient()
+	if err := migrator.prepare(client); err != nil {
+		return nil, err
+	}
+	history, err := migrator.history(client)
+	if err != nil {
+		return nil, err
+	}
+	statuses := []*MigrationStatus{}
+	for _, migration := range registered {
+		status := &MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if row, ok := history[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &row.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// apply runs a step in a transaction. SQLite changes a column by copying the
+// table and dropping the old one, with foreign keys on the drop would cascade
+// to the rows referencing it, so they are turned off for the connection, which
+// SQLite only allows outside of a transaction.
+func apply(client *gorm.DB, step func(tx *gorm.DB) error) error {
+	if client.Dialector.Name() != "sqlite" {
+		return client.Transaction(step)
+	}
+	return client.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("PRAGMA foreign_keys = OFF").Error; err != nil {
+			return err
+		}
+		defer conn.Exec("PRAGMA foreign_keys = ON")
+		return conn.Transaction(step)
+	})
+}
+
+func (migrator *Migrator) history(client *gorm.DB) (map[uint]SchemaMigration, error) {
+	var rows []SchemaMigration
+	if err := client.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	history := map[uint]SchemaMigration{}
+	for _, row := range rows {
+		history[row.Version] = row
+	}
+	return history, nil
+}
+
+// prepare creates the bookkeeping tables. Two replicas can race on this, so a
+// failed create is only an error when the table still isn't there afterwards.
+func (migrator *Migrator) prepare(client *gorm.DB) error {
+	for _, table := range []interface{}{&SchemaMigration{}, &schemaMigrationLock{}} {
+		if client.Migrator().HasTable(table) {
+			continue
+		}
+		if err := client.Migrator().CreateTable(table); err != nil && !client.Migrator().HasTable(table) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (migrator *Migrator) withLock(fn func(client *gorm.DB) error) error {
+	client := migrator.db.GetClient()
+	if err := migrator.prepare(client); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(migrator.lockTimeout)
+	for {
+		err := client.Create(&schemaMigrationLock{ID: 1, LockedBy: migrator.owner, LockedAt: time.Now()}).Error
+		if err == nil {
+			break
+		}
+
+		// A process that died while migrating leaves its row behind, take it over once it is old enough.
+		current := &schemaMigrationLock{}
+		result := client.First(current, 1)
+		if result.Error == nil && time.Since(current.LockedAt) > migrator.staleAfter {
+			client.Where("id = ? AND locked_by = ?", 1, current.LockedBy).Delete(&schemaMigrationLock{})
+			continue
+		}
+		if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return result.Error
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("migrations: timed out waiting for the lock held by %s", current.LockedBy)
+		}
+		time.Sleep(time.Second)
+	}
+	defer client.Where("id = ? AND locked_by = ?", 1, migrator.owner).Delete(&schemaMigrationLock{})
+
+	return fn(client)
+}
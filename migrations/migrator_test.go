@@ -0,0 +1,83 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+)
+
+func TestMigratorUpAndDownOnSQLite(t *testing.T) {
+	db, err := database.CreateSQLiteDB(database.SQLiteInMemory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.CloseDB()
+	migrator := NewMigrator(db)
+
+	applied, err := migrator.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(registered) {
+		t.Fatalf("expected %d migrations to be applied, got %d", len(registered), len(applied))
+	}
+	if applied, err := migrator.Up(); err != nil || len(applied) != 0 {
+		t.Fatalf("expected nothing left to apply, got %d, err %v", len(applied), err)
+	}
+
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if !status.Applied {
+			t.Fatalf("expected %04d_%s to be applied", status.Version, status.Name)
+		}
+	}
+
+	// Every migration can be reverted and applied again
+	reverted, err := migrator.Down(len(registered))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reverted) != len(registered) {
+		t.Fatalf("expected %d migrations to be reverted, got %d", len(registered), len(reverted))
+	}
+	if db.GetClient().Migrator().HasTable("users") {
+		t.Fatal("expected the users table to be dropped")
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigratorDownKeepsReferencingRowsOnSQLite(t *testing.T) {
+	db, err := database.CreateSQLiteDB(database.SQLiteInMemory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.CloseDB()
+	migrator := NewMigrator(db)
+	if _, err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+	client := db.GetClient()
+	if err := client.Exec("INSERT INTO users (username, email, password, role) VALUES ('user', 'user@example.com', 'hash', 'user')").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Exec("INSERT INTO photos (title, caption, photo_url, user_id) VALUES ('title', 'caption', 'url', 1)").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Reverting the roles drops a column of users, which SQLite does by rebuilding the table
+	if _, err := migrator.Down(3); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	if err := client.Table("photos").Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("expected the photo to be kept, got %d photos", count)
+	}
+}
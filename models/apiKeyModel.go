@@ -0,0 +1,140 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+)
+
+// apiKeyUsageInterval is how stale LastUsedAt may get, a script calling the
+// API in a loop shouldn't cause a write on every request.
+const apiKeyUsageInterval = time.Minute
+
+// APIKey lets scripts act as a user within its scopes, it's kept after being
+// revoked so the user still sees when it was last used.
+type APIKey struct {
+	ID      uint   `gorm:"primaryKey"`
+	UserID  uint   `gorm:"not null;index"`
+	User    User   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Name    string `gorm:"not null"`
+	KeyHash string `gorm:"size:64;unique;not null"`
+	// Prefix is the start of the key, shown to the user to recognize it.
+	Prefix string `gorm:"size:16;not null"`
+	// Scopes is a space separated list, like the scope of an OAuth token.
+	Scopes     string `gorm:"not null"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+type IAPIKeyModel interface {
+	WithDB(db database.IDatabase) IAPIKeyModel
+	CreateKey(userId uint, name string, keyHash string, prefix string, scopes []string, expiresAt *time.Time) (*APIKey, error)
+	GetByHash(keyHash string) (*APIKey, error)
+	GetByUser(userId uint) ([]APIKey, error)
+	GetById(apiKeyId uint) (*APIKey, error)
+	MarkUsed(apiKey *APIKey) (*APIKey, error)
+	RevokeKey(apiKey *APIKey) (*APIKey, error)
+}
+
+type APIKeyModel struct {
+	db database.IDatabase
+}
+
+func NewAPIKeyModel(db database.IDatabase) IAPIKeyModel {
+	return &APIKeyModel{
+		db: db,
+	}
+}
+
+func (apiKeyModel *APIKeyModel) WithDB(db database.IDatabase) IAPIKeyModel {
+	return NewAPIKeyModel(db)
+}
+
+func (apiKeyModel *APIKeyModel) CreateKey(userId uint, name string, keyHash string, prefix string, scopes []string, expiresAt *time.Time) (*APIKey, error) {
+	newKey := &APIKey{
+		UserID:    userId,
+		Name:      name,
+		KeyHash:   keyHash,
+		Prefix:    prefix,
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: expiresAt,
+	}
+	result := apiKeyModel.db.GetClient().Omit("User").Create(newKey)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return newKey, nil
+}
+
+func (apiKeyModel *APIKeyModel) GetByHash(keyHash string) (*APIKey, error) {
+	apiKey := &APIKey{}
+	result := apiKeyModel.db.GetReadClient().Where("key_hash = ?", keyHash).First(apiKey)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return apiKey, nil
+}
+
+func (apiKeyModel *APIKeyModel) GetByUser(userId uint) ([]APIKey, error) {
+	apiKeys := []APIKey{}
+	result := apiKeyModel.db.GetReadClient().Where("user_id = ?", userId).Order("id").Find(&apiKeys)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return apiKeys, nil
+}
+
+func (apiKeyModel *APIKeyModel) GetById(apiKeyId uint) (*APIKey, error) {
+	apiKey := &APIKey{}
+	result := apiKeyModel.db.GetReadClient().First(apiKey, apiKeyId)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return apiKey, nil
+}
+
+// MarkUsed records the use of the key at most once per apiKeyUsageInterval.
+func (apiKeyModel *APIKeyModel) MarkUsed(apiKey *APIKey) (*APIKey, error) {
+	usedAt := time.Now()
+	if apiKey.LastUsedAt != nil && usedAt.Sub(*apiKey.LastUsedAt) < apiKeyUsageInterval {
+		return apiKey, nil
+	}
+	result := apiKeyModel.db.GetClient().Model(apiKey).Update("last_used_at", usedAt)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	apiKey.LastUsedAt = &usedAt
+	return apiKey, nil
+}
+
+func (apiKeyModel *APIKeyModel) RevokeKey(apiKey *APIKey) (*APIKey, error) {
+	revokedAt := time.Now()
+	result := apiKeyModel.db.GetClient().Model(apiKey).Update("revoked_at", revokedAt)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	apiKey.RevokedAt = &revokedAt
+	return apiKey, nil
+}
+
+func (apiKey *APIKey) ScopeList() []string {
+	return strings.Fields(apiKey.Scopes)
+}
+
+func (apiKey *APIKey) HasScope(scope string) bool {
+	return slices.Contains(apiKey.ScopeList(), scope)
+}
+
+// IsActive reports whether the key can still be used, it's neither revoked
+// nor expired.
+func (apiKey *APIKey) IsActive(now time.Time) bool {
+	return apiKey.RevokedAt == nil && (apiKey.ExpiresAt == nil || now.Before(*apiKey.ExpiresAt))
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+)
+
+// Actions recorded in the audit log.
+const (
+	// AuditAuthorizeOverride is a privileged user accessing a resource they
+	// don't own.
+	AuditAuthorizeOverride = "authorize.override"
+	AuditRoleChange        = "role.change"
+	AuditLoginUnlock       = "login.unlock"
+)
+
+// AuditLog records what a privileged user did to a resource of another user.
+// It has no foreign keys so the entries outlive the users they mention.
+type AuditLog struct {
+	ID         uint   `gorm:"primaryKey"`
+	ActorID    uint   `gorm:"not null;index"`
+	Action     string `gorm:"size:64;not null"`
+	Resource   string `gorm:"size:64;not null"`
+	ResourceID uint   `gorm:"not null"`
+	OwnerID    uint   `gorm:"not null"`
+	Method     string `gorm:"size:16"`
+	Path       string
+	Detail     string
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+type IAuditLogModel interface {
+	WithDB(db database.IDatabase) IAuditLogModel
+	Record(entry *AuditLog) (*AuditLog, error)
+	GetRecent(limit int) ([]AuditLog, error)
+}
+
+type AuditLogModel struct {
+	db database.IDatabase
+}
+
+func NewAuditLogModel(db database.IDatabase) IAuditLogModel {
+	return &AuditLogModel{
+		db: db,
+	}
+}
+
+func (auditLogModel *AuditLogModel) WithDB(db database.IDatabase) IAuditLogModel {
+	return NewAuditLogModel(db)
+}
+
+func (auditLogModel *AuditLogModel) Record(entry *AuditLog) (*AuditLog, error) {
+	result := auditLogModel.db.GetClient().Create(entry)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return entry, nil
+}
+
+func (auditLogModel *AuditLogModel) GetRecent(limit int) ([]AuditLog, error) {
+	entries := []AuditLog{}
+	result := auditLogModel.db.GetReadClient().Order("id DESC").Limit(limit).Find(&entries)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return entries, nil
+}
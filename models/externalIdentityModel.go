@@ -0,0 +1,105 @@
+package models
+
+import (
+	"time"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+)
+
+// ExternalIdentity links an account at an OpenID Connect provider, the
+// issuer and subject of its ID tokens, to a user.
+type ExternalIdentity struct {
+	ID      uint   `gorm:"primaryKey"`
+	UserID  uint   `gorm:"not null;index"`
+	User    User   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Issuer  string `gorm:"size:255;not null;uniqueIndex:idx_external_identities_issuer_subject"`
+	Subject string `gorm:"size:255;not null;uniqueIndex:idx_external_identities_issuer_subject"`
+	// Email is the address the provider reported when the identity was
+	// linked, only shown to the user.
+	Email      string
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+type IExternalIdentityModel interface {
+	WithDB(db database.IDatabase) IExternalIdentityModel
+	CreateIdentity(userId uint, issuer string, subject string, email string) (*ExternalIdentity, error)
+	GetBySubject(issuer string, subject string) (*ExternalIdentity, error)
+	GetByUser(userId uint) ([]ExternalIdentity, error)
+	GetById(identityId uint) (*ExternalIdentity, error)
+	MarkUsed(identity *ExternalIdentity) (*ExternalIdentity, error)
+	DeleteIdentity(identity *ExternalIdentity) (*ExternalIdentity, error)
+}
+
+type ExternalIdentityModel struct {
+	db database.IDatabase
+}
+
+func NewExternalIdentityModel(db database.IDatabase) IExternalIdentityModel {
+	return &ExternalIdentityModel{
+		db: db,
+	}
+}
+
+func (externalIdentityModel *ExternalIdentityModel) WithDB(db database.IDatabase) IExternalIdentityModel {
+	return NewExternalIdentityModel(db)
+}
+
+func (externalIdentityModel *ExternalIdentityModel) CreateIdentity(userId uint, issuer string, subject string, email string) (*ExternalIdentity, error) {
+	newIdentity := &ExternalIdentity{
+		UserID:  userId,
+		Issuer:  issuer,
+		Subject: subject,
+		Email:   email,
+	}
+	result := externalIdentityModel.db.GetClient().Omit("User").Create(newIdentity)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return newIdentity, nil
+}
+
+func (externalIdentityModel *ExternalIdentityModel) GetBySubject(issuer string, subject string) (*ExternalIdentity, error) {
+	identity := &ExternalIdentity{}
+	result := externalIdentityModel.db.GetReadClient().Where("issuer = ? AND subject = ?", issuer, subject).First(identity)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return identity, nil
+}
+
+func (externalIdentityModel *ExternalIdentityModel) GetByUser(userId uint) ([]ExternalIdentity, error) {
+	identities := []ExternalIdentity{}
+	result := externalIdentityModel.db.GetReadClient().Where("user_id = ?", userId).Order("id").Find(&identities)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return identities, nil
+}
+
+func (externalIdentityModel *ExternalIdentityModel) GetById(identityId uint) (*ExternalIdentity, error) {
+	identity := &ExternalIdentity{}
+	result := externalIdentityModel.db.GetReadClient().First(identity, identityId)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return identity, nil
+}
+
+func (externalIdentityModel *ExternalIdentityModel) MarkUsed(identity *ExternalIdentity) (*ExternalIdentity, error) {
+	usedAt := time.Now()
+	result := externalIdentityModel.db.GetClient().Model(identity).Update("last_used_at", usedAt)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	identity.LastUsedAt = &usedAt
+	return identity, nil
+}
+
+func (externalIdentityModel *ExternalIdentityModel) DeleteIdentity(identity *ExternalIdentity) (*ExternalIdentity, error) {
+	result := externalIdentityModel.db.GetClient().Delete(identity)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return identity, nil
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginAttempt counts the logins of an identifier, like email:<address> or
+// ip:<address>, that weren't followed by a successful one. Attempts are counted
+// before the password is checked, LastFailedAt is when the last one was.
+// BlockedUntil is when the identifier can try again.
+type LoginAttempt struct {
+	ID           uint      `gorm:"primaryKey"`
+	Identifier   string    `gorm:"size:320;unique;not null"`
+	Failures     int       `gorm:"not null;default:0"`
+	LastFailedAt time.Time `gorm:"not null;index"`
+	BlockedUntil *time.Time
+}
+
+type ILoginAttemptModel interface {
+	WithDB(db database.IDatabase) ILoginAttemptModel
+	GetByIdentifier(identifier string) (*LoginAttempt, error)
+	Create(attempt *LoginAttempt) (bool, error)
+	Swap(attempt *LoginAttempt, failures int) (bool, error)
+	DeleteByIdentifier(identifier string) error
+	DeleteExpiredBefore(lastFailedBefore time.Time, now time.Time) (int64, error)
+}
+
+type LoginAttemptModel struct {
+	db database.IDatabase
+}
+
+func NewLoginAttemptModel(db database.IDatabase) ILoginAttemptModel {
+	return &LoginAttemptModel{
+		db: db,
+	}
+}
+
+func (loginAttemptModel *LoginAttemptModel) WithDB(db database.IDatabase) ILoginAttemptModel {
+	return NewLoginAttemptModel(db)
+}
+
+// GetByIdentifier returns nil without an error when the identifier has no failed attempt.
+func (loginAttemptModel *LoginAttemptModel) GetByIdentifier(identifier string) (*LoginAttempt, error) {
+	attempt := &LoginAttempt{}
+	result := loginAttemptModel.db.GetClient().Where("identifier = ?", identifier).First(attempt)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return attempt, nil
+}
+
+// Create returns false when the identifier already has an attempt, a
+// concurrent login on another instance created it first.
+func (loginAttemptModel *LoginAttemptModel) Create(attempt *LoginAttempt) (bool, error) {
+	result := loginAttemptModel.db.GetClient().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "identifier"}},
+		DoNothing: true,
+	}).Create(attempt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Swap replaces the attempt of its identifier only while it still has the
+// failures it was read with, it returns false when a concurrent login changed
+// it first.
+func (loginAttemptModel *LoginAttemptModel) Swap(attempt *LoginAttempt, failures int) (bool, error) {
+	result := loginAttemptModel.db.GetClient().Model(&LoginAttempt{}).
+		Where("identifier = ? AND failures = ?", attempt.Identifier, failures).
+		Updates(map[string]interface{}{
+			"failures":       attempt.Failures,
+			"last_failed_at": attempt.LastFailedAt,
+			"blocked_until":  attempt.BlockedUntil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (loginAttemptModel *LoginAttemptModel) DeleteByIdentifier(identifier string) error {
+	result := loginAttemptModel.db.GetClient().Where("identifier = ?", identifier).Delete(&LoginAttempt{})
+	return result.Error
+}
+
+// DeleteExpiredBefore removes the attempts that failed last before
+// lastFailedBefore and aren't blocked anymore at now.
+func (loginAttemptModel *LoginAttemptModel) DeleteExpiredBefore(lastFailedBefore time.Time, now time.Time) (int64, error) {
+	result := loginAttemptModel.db.GetClient().
+		Where("last_failed_at < ? AND (blocked_until IS NULL OR blocked_until < ?)", lastFailedBefore, now).
+		Delete(&LoginAttempt{})
+	return result.RowsAffected, result.Error
+}
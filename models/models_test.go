@@ -0,0 +1,50 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/app"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/migrations"
+)
+
+// newTestDB returns an in-memory SQLite database with every migration applied.
+func newTestDB(t *testing.T) database.IDatabase {
+	t.Helper()
+	db, err := database.CreateSQLiteDB(database.SQLiteInMemory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.CloseDB() })
+	if _, err := migrations.NewMigrator(db).Up(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func createTestUser(t *testing.T, userModel IUserModel, email string) *User {
+	t.Helper()
+	user, err := userModel.CreateUser(&app.UserRegisterRequest{
+		Username: "user",
+		Email:    email,
+		Password: "hash",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func createTestPhoto(t *testing.T, photoModel IPhotoModel, userId uint, title string) *Photo {
+	t.Helper()
+	photo, err := photoModel.CreatePhoto(&app.FormPhotoCreationRequest{
+		Title:    title,
+		Caption:  "caption",
+		PhotoUrl: "http://localhost/public/" + title + ".jpg",
+		UserID:   userId,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return photo
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+)
+
+// PasswordResetToken only stores the sha256 of the token sent by email, a
+// token can be redeemed once, until it expires or a newer one is requested.
+type PasswordResetToken struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	User      User   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	TokenHash string `gorm:"size:64;unique;not null"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type IPasswordResetModel interface {
+	WithDB(db database.IDatabase) IPasswordResetModel
+	CreateToken(userId uint, tokenHash string, expiresAt time.Time) (*PasswordResetToken, error)
+	GetByHash(tokenHash string) (*PasswordResetToken, error)
+	MarkUsed(token *PasswordResetToken) (bool, error)
+	InvalidateByUser(userId uint) error
+	DeleteExpiredBefore(expiredBefore time.Time) (int64, error)
+}
+
+type PasswordResetModel struct {
+	db database.IDatabase
+}
+
+func NewPasswordResetModel(db database.IDatabase) IPasswordResetModel {
+	return &PasswordResetModel{
+		db: db,
+	}
+}
+
+func (passwordResetModel *PasswordResetModel) WithDB(db database.IDatabase) IPasswordResetModel {
+	return NewPasswordResetModel(db)
+}
+
+func (passwordResetModel *PasswordResetModel) CreateToken(userId uint, tokenHash string, expiresAt time.Time) (*PasswordResetToken, error) {
+	newToken := &PasswordResetToken{
+		UserID:    userId,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+	}
+
+	result := passwordResetModel.db.GetClient().Omit("User").Create(newToken)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return newToken, nil
+}
+
+func (passwordResetModel *PasswordResetModel) GetByHash(tokenHash string) (*PasswordResetToken, error) {
+	token := &PasswordResetToken{}
+	result := passwordResetModel.db.GetReadClient().Where("token_hash = ?", tokenHash).First(token)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return token, nil
+}
+
+// MarkUsed returns false when the token had already been used, so two
+// concurrent resets with the same token can't both succeed.
+func (passwordResetModel *PasswordResetModel) MarkUsed(token *PasswordResetToken) (bool, error) {
+	usedAt := time.Now()
+	result := passwordResetModel.db.GetClient().Model(&PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", token.ID).
+		UpdateColumn("used_at", usedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	token.UsedAt = &usedAt
+	return true, nil
+}
+
+// InvalidateByUser marks every unused token of the user as used.
+func (passwordResetModel *PasswordResetModel) InvalidateByUser(userId uint) error {
+	return passwordResetModel.db.GetClient().Model(&PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userId).
+		UpdateColumn("used_at", time.Now()).Error
+}
+
+func (passwordResetModel *PasswordResetModel) DeleteExpiredBefore(expiredBefore time.Time) (int64, error) {
+	result := passwordResetModel.db.GetClient().Where("expires_at < ?", expiredBefore).Delete(&PasswordResetToken{})
+	return result.RowsAffected, result.Error
+}
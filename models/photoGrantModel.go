@@ -0,0 +1,119 @@
+package models
+
+import (
+	"time"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"gorm.io/gorm/clause"
+)
+
+// Access the owner of a photo can grant to another user.
+const (
+	PhotoAccessViewer = "viewer"
+	PhotoAccessEditor = "editor"
+)
+
+// PhotoAccesses returns every access, an editor can view too.
+func PhotoAccesses() []string {
+	return []string{PhotoAccessViewer, PhotoAccessEditor}
+}
+
+func IsPhotoAccess(access string) bool {
+	return access == PhotoAccessViewer || access == PhotoAccessEditor
+}
+
+// PhotoGrant shares a photo with another user, a user has at most one grant
+// per photo. Only the owner can delete the photo whatever the grant.
+type PhotoGrant struct {
+	ID        uint   `gorm:"primaryKey"`
+	PhotoID   uint   `gorm:"not null;uniqueIndex:idx_photo_grants_photo_user"`
+	Photo     Photo  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	UserID    uint   `gorm:"not null;uniqueIndex:idx_photo_grants_photo_user;index"`
+	User      User   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Access    string `gorm:"size:16;not null"`
+	GrantedBy uint   `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type IPhotoGrantModel interface {
+	WithDB(db database.IDatabase) IPhotoGrantModel
+	Grant(photoId uint, userId uint, access string, grantedBy uint) (*PhotoGrant, error)
+	GetByPhoto(photoId uint) ([]PhotoGrant, error)
+	GetByPhotoAndUser(photoId uint, userId uint) (*PhotoGrant, error)
+	GetSharedWith(userId uint) ([]PhotoGrant, error)
+	Revoke(grant *PhotoGrant) (*PhotoGrant, error)
+}
+
+type PhotoGrantModel struct {
+	db database.IDatabase
+}
+
+func NewPhotoGrantModel(db database.IDatabase) IPhotoGrantModel {
+	return &PhotoGrantModel{
+		db: db,
+	}
+}
+
+func (photoGrantModel *PhotoGrantModel) WithDB(db database.IDatabase) IPhotoGrantModel {
+	return NewPhotoGrantModel(db)
+}
+
+// Grant creates the grant of the user on the photo, or replaces the access of
+// the existing one.
+func (photoGrantModel *PhotoGrantModel) Grant(photoId uint, userId uint, access string, grantedBy uint) (*PhotoGrant, error) {
+	grant := &PhotoGrant{
+		PhotoID:   photoId,
+		UserID:    userId,
+		Access:    access,
+		GrantedBy: grantedBy,
+	}
+	result := photoGrantModel.db.GetClient().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "photo_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"access", "granted_by", "updated_at"}),
+	}).Create(grant)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return photoGrantModel.GetByPhotoAndUser(photoId, userId)
+}
+
+func (photoGrantModel *PhotoGrantModel) GetByPhoto(photoId uint) ([]PhotoGrant, error) {
+	grants := []PhotoGrant{}
+	result := photoGrantModel.db.GetReadClient().Preload("User").Where("photo_id = ?", photoId).Order("id").Find(&grants)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return grants, nil
+}
+
+func (photoGrantModel *PhotoGrantModel) GetByPhotoAndUser(photoId uint, userId uint) (*PhotoGrant, error) {
+	grant := &PhotoGrant{}
+	result := photoGrantModel.db.GetReadClient().Preload("User").Where("photo_id = ? AND user_id = ?", photoId, userId).First(grant)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return grant, nil
+}
+
+// GetSharedWith returns the grants of the user on photos that aren't in the
+// trash, with their photo.
+func (photoGrantModel *PhotoGrantModel) GetSharedWith(userId uint) ([]PhotoGrant, error) {
+	grants := []PhotoGrant{}
+	result := photoGrantModel.db.GetReadClient().Preload("Photo").
+		Joins("JOIN photos ON photos.id = photo_grants.photo_id AND photos.deleted_at IS NULL").
+		Where("photo_grants.user_id = ?", userId).
+		Order("photo_grants.id DESC").Find(&grants)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return grants, nil
+}
+
+func (photoGrantModel *PhotoGrantModel) Revoke(grant *PhotoGrant) (*PhotoGrant, error) {
+	result := photoGrantModel.db.GetClient().Delete(grant)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return grant, nil
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/app"
@@ -17,15 +18,23 @@ type Photo struct {
 	User      User
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
 
 type IPhotoModel interface {
+	WithDB(db database.IDatabase) IPhotoModel
 	CreatePhoto(photo *app.FormPhotoCreationRequest) (*Photo, error)
-	GetAllPhoto() ([]Photo, error)
+	GetVisibleTo(userId uint) ([]Photo, error)
+	GetByFilename(filename string) (*Photo, error)
 	GetOwner(userId uint) (*User, error)
 	GetById(photoId uint, detailed bool) (*Photo, error)
 	UpdatePhoto(photo *Photo, updateBody *app.FormPhotoUpdateRequest) (*Photo, error)
 	DeletePhoto(photo *Photo) (*Photo, error)
+	GetTrashedByOwner(userId uint) ([]Photo, error)
+	GetTrashedById(photoId uint) (*Photo, error)
+	GetTrashedBefore(deletedBefore time.Time) ([]Photo, error)
+	RestorePhoto(photo *Photo) (*Photo, error)
+	PurgePhoto(photo *Photo) (*Photo, error)
 }
 
 type PhotoModel struct {
@@ -38,6 +47,12 @@ func NewPhotoModel(db database.IDatabase) IPhotoModel {
 	}
 }
 
+// WithDB returns a copy of the model that runs its queries on db, pass the tx
+// given by IDatabase.WithTransaction to make the model part of the transaction.
+func (photoModel *PhotoModel) WithDB(db database.IDatabase) IPhotoModel {
+	return NewPhotoModel(db)
+}
+
 func (photoModel *PhotoModel) CreatePhoto(photo *app.FormPhotoCreationRequest) (*Photo, error) {
 	newPhoto := &Photo{
 		Title:    photo.Title,
@@ -55,7 +70,7 @@ func (photoModel *PhotoModel) CreatePhoto(photo *app.FormPhotoCreationRequest) (
 }
 
 func (photoModel *PhotoModel) GetOwner(userId uint) (*User, error) {
-	client := photoModel.db.GetClient()
+	client := photoModel.db.GetReadClient()
 	owner := &User{}
 	result := client.First(&owner, userId)
 	if result.Error != nil {
@@ -64,15 +79,30 @@ func (photoModel *PhotoModel) GetOwner(userId uint) (*User, error) {
 	return owner, nil
 }
 
-func (photoModel *PhotoModel) GetAllPhoto() ([]Photo, error) {
+// GetVisibleTo returns the photos of the user and the ones shared with them,
+// newest first.
+func (photoModel *PhotoModel) GetVisibleTo(userId uint) ([]Photo, error) {
 	var photos []Photo
-	result := photoModel.db.GetClient().Order("created_at desc").Find(&photos)
+	client := photoModel.db.GetReadClient()
+	sharedPhotoIds := client.Model(&PhotoGrant{}).Select("photo_id").Where("user_id = ?", userId)
+	result := client.Where("user_id = ? OR id IN (?)", userId, sharedPhotoIds).Order("created_at desc").Find(&photos)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return photos, nil
 }
 
+// GetByFilename returns the photo whose url points to the stored file.
+func (photoModel *PhotoModel) GetByFilename(filename string) (*Photo, error) {
+	photo := &Photo{}
+	pattern := "%/" + strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(filename)
+	result := photoModel.db.GetReadClient().Where("photo_url LIKE ? ESCAPE '!'", pattern).First(photo)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return photo, nil
+}
+
 func (photoModel *PhotoModel) UpdatePhoto(photo *Photo, updateBody *app.FormPhotoUpdateRequest) (*Photo, error) {
 	client := photoModel.db.GetClient()
 
@@ -89,7 +119,7 @@ func (photoModel *PhotoModel) UpdatePhoto(photo *Photo, updateBody *app.FormPhot
 }
 
 func (photoModel *PhotoModel) GetById(photoId uint, detailed bool) (*Photo, error) {
-	client := photoModel.db.GetClient()
+	client := photoModel.db.GetReadClient()
 
 	photo := &Photo{}
 	var result *gorm.DB
@@ -113,3 +143,50 @@ func (photoModel *PhotoModel) DeletePhoto(photo *Photo) (*Photo, error) {
 
 	return photo, nil
 }
+
+func (photoModel *PhotoModel) GetTrashedByOwner(userId uint) ([]Photo, error) {
+	var photos []Photo
+	result := photoModel.db.GetReadClient().Unscoped().
+		Where("user_id = ? AND deleted_at IS NOT NULL", userId).
+		Order("deleted_at desc").Find(&photos)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return photos, nil
+}
+
+func (photoModel *PhotoModel) GetTrashedById(photoId uint) (*Photo, error) {
+	photo := &Photo{}
+	result := photoModel.db.GetReadClient().Unscoped().Where("deleted_at IS NOT NULL").First(photo, photoId)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return photo, nil
+}
+
+func (photoModel *PhotoModel) GetTrashedBefore(deletedBefore time.Time) ([]Photo, error) {
+	var photos []Photo
+	result := photoModel.db.GetReadClient().Unscoped().Where("deleted_at < ?", deletedBefore).Find(&photos)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return photos, nil
+}
+
+func (photoModel *PhotoModel) RestorePhoto(photo *Photo) (*Photo, error) {
+	result := photoModel.db.GetClient().Unscoped().Model(photo).UpdateColumn("deleted_at", nil)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	photo.DeletedAt = gorm.DeletedAt{}
+	return photo, nil
+}
+
+// PurgePhoto removes the row for good, unlike DeletePhoto which only moves it to the trash.
+func (photoModel *PhotoModel) PurgePhoto(photo *Photo) (*Photo, error) {
+	result := photoModel.db.GetClient().Unscoped().Delete(photo)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return photo, nil
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/app"
+	"gorm.io/gorm"
+)
+
+func TestPhotoModelLifecycle(t *testing.T) {
+	db := newTestDB(t)
+	photoModel := NewPhotoModel(db)
+	user := createTestUser(t, NewUserModel(db), "user@example.com")
+	photo := createTestPhoto(t, photoModel, user.ID, "photo")
+
+	detailed, err := photoModel.GetById(photo.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detailed.User.Email != "user@example.com" {
+		t.Fatalf("expected the owner to be loaded, got %+v", detailed.User)
+	}
+
+	if _, err := photoModel.UpdatePhoto(detailed, &app.FormPhotoUpdateRequest{Title: "renamed", Caption: "caption", PhotoUrl: detailed.PhotoUrl}); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := photoModel.GetById(photo.ID, false)
+	if err != nil || updated.Title != "renamed" {
+		t.Fatalf("expected the photo to be updated, got %+v, err %v", updated, err)
+	}
+
+	if _, err := photoModel.DeletePhoto(updated); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := photoModel.GetById(photo.ID, false); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected the photo to be in the trash, got %v", err)
+	}
+	trashed, err := photoModel.GetTrashedByOwner(user.ID)
+	if err != nil || len(trashed) != 1 {
+		t.Fatalf("expected one trashed photo, got %d, err %v", len(trashed), err)
+	}
+
+	if _, err := photoModel.RestorePhoto(&trashed[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := photoModel.GetById(photo.ID, false); err != nil {
+		t.Fatalf("expected the photo to be restored, got %v", err)
+	}
+}
+
+func TestPhotoModelOnlyShowsOwnAndSharedPhotos(t *testing.T) {
+	db := newTestDB(t)
+	userModel := NewUserModel(db)
+	photoModel := NewPhotoModel(db)
+	owner := createTestUser(t, userModel, "owner@example.com")
+	viewer := createTestUser(t, userModel, "viewer@example.com")
+	stranger := createTestUser(t, userModel, "stranger@example.com")
+	shared := createTestPhoto(t, photoModel, owner.ID, "shared")
+	createTestPhoto(t, photoModel, owner.ID, "private")
+	if _, err := NewPhotoGrantModel(db).Grant(shared.ID, viewer.ID, PhotoAccessViewer, owner.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		user   *User
+		photos int
+	}{{owner, 2}, {viewer, 1}, {stranger, 0}} {
+		photos, err := photoModel.GetVisibleTo(test.user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(photos) != test.photos {
+			t.Fatalf("%s: expected %d photos, got %d", test.user.Email, test.photos, len(photos))
+		}
+	}
+
+	found, err := photoModel.GetByFilename("shared.jpg")
+	if err != nil || found.ID != shared.ID {
+		t.Fatalf("expected the shared photo, got %+v, err %v", found, err)
+	}
+	// The underscore isn't a wildcard
+	if _, err := photoModel.GetByFilename("s_ared.jpg"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected no photo, got err %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+)
+
+// RecoveryCode only stores the sha256 of a code shown to the user once, each
+// code can replace a TOTP code a single time.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	User      User   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CodeHash  string `gorm:"size:64;not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type IRecoveryCodeModel interface {
+	WithDB(db database.IDatabase) IRecoveryCodeModel
+	ReplaceCodes(userId uint, codeHashes []string) error
+	UseCode(userId uint, codeHash string) (bool, error)
+	CountUnused(userId uint) (int64, error)
+	DeleteByUser(userId uint) error
+}
+
+type RecoveryCodeModel struct {
+	db database.IDatabase
+}
+
+func NewRecoveryCodeModel(db database.IDatabase) IRecoveryCodeModel {
+	return &RecoveryCodeModel{
+		db: db,
+	}
+}
+
+func (recoveryCodeModel *RecoveryCodeModel) WithDB(db database.IDatabase) IRecoveryCodeModel {
+	return NewRecoveryCodeModel(db)
+}
+
+// ReplaceCodes drops every code of the user, used or not, for the new ones.
+func (recoveryCodeModel *RecoveryCodeModel) ReplaceCodes(userId uint, codeHashes []string) error {
+	if err := recoveryCodeModel.DeleteByUser(userId); err != nil {
+		return err
+	}
+
+	codes := make([]RecoveryCode, 0, len(codeHashes))
+	for _, codeHash := range codeHashes {
+		codes = append(codes, RecoveryCode{
+			UserID:   userId,
+			CodeHash: codeHash,
+		})
+	}
+	return recoveryCodeModel.db.GetClient().Omit("User").Create(&codes).Error
+}
+
+// UseCode marks an unused code of the user as used, it returns false when
+// there is no such code, so two requests can't both redeem the same one.
+func (recoveryCodeModel *RecoveryCodeModel) UseCode(userId uint, codeHash string) (bool, error) {
+	result := recoveryCodeModel.db.GetClient().Model(&RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, codeHash).
+		UpdateColumn("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (recoveryCodeModel *RecoveryCodeModel) CountUnused(userId uint) (int64, error) {
+	var count int64
+	result := recoveryCodeModel.db.GetReadClient().Model(&RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userId).
+		Count(&count)
+	return count, result.Error
+}
+
+func (recoveryCodeModel *RecoveryCodeModel) DeleteByUser(userId uint) error {
+	return recoveryCodeModel.db.GetClient().Where("user_id = ?", userId).Delete(&RecoveryCode{}).Error
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"time"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+)
+
+// RefreshToken only stores the sha256 of the token handed to the client. Every
+// token obtained by rotating another shares its FamilyID, which is what gets
+// revoked when a token is used twice.
+type RefreshToken struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	User      User   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	FamilyID  string `gorm:"size:64;not null;index"`
+	TokenHash string `gorm:"size:64;unique;not null"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+type IRefreshTokenModel interface {
+	WithDB(db database.IDatabase) IRefreshTokenModel
+	CreateToken(userId uint, familyId string, tokenHash string, expiresAt time.Time) (*RefreshToken, error)
+	GetByHash(tokenHash string) (*RefreshToken, error)
+	MarkUsed(token *RefreshToken) (bool, error)
+	RevokeFamily(familyId string) error
+	RevokeByUser(userId uint) error
+	DeleteExpiredBefore(expiredBefore time.Time) (int64, error)
+}
+
+type RefreshTokenModel struct {
+	db database.IDatabase
+}
+
+func NewRefreshTokenModel(db database.IDatabase) IRefreshTokenModel {
+	return &RefreshTokenModel{
+		db: db,
+	}
+}
+
+func (refreshTokenModel *RefreshTokenModel) WithDB(db database.IDatabase) IRefreshTokenModel {
+	return NewRefreshTokenModel(db)
+}
+
+func (refreshTokenModel *RefreshTokenModel) CreateToken(userId uint, familyId string, tokenHash string, expiresAt time.Time) (*RefreshToken, error) {
+	newToken := &RefreshToken{
+		UserID:    userId,
+		FamilyID:  familyId,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+	}
+
+	result := refreshTokenModel.db.GetClient().Omit("User").Create(newToken)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return newToken, nil
+}
+
+func (refreshTokenModel *RefreshTokenModel) GetByHash(tokenHash string) (*RefreshToken, error) {
+	token := &RefreshToken{}
+	result := refreshTokenModel.db.GetReadClient().Where("token_hash = ?", tokenHash).First(token)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return token, nil
+}
+
+// MarkUsed returns false when the token had already been used, e.g. by a
+// concurrent refresh that won the race.
+func (refreshTokenModel *RefreshTokenModel) MarkUsed(token *RefreshToken) (bool, error) {
+	usedAt := time.Now()
+	result := refreshTokenModel.db.GetClient().Model(&RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", token.ID).
+		UpdateColumn("used_at", usedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	token.UsedAt = &usedAt
+	return true, nil
+}
+
+func (refreshTokenModel *RefreshTokenModel) RevokeFamily(familyId string) error {
+	return refreshTokenModel.db.GetClient().Model(&RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyId).
+		UpdateColumn("revoked_at", time.Now()).Error
+}
+
+func (refreshTokenModel *RefreshTokenModel) RevokeByUser(userId uint) error {
+	return refreshTokenModel.db.GetClient().Model(&RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		UpdateColumn("revoked_at", time.Now()).Error
+}
+
+func (refreshTokenModel *RefreshTokenModel) DeleteExpiredBefore(expiredBefore time.Time) (int64, error) {
+	result := refreshTokenModel.db.GetClient().Where("expires_at < ?", expiredBefore).Delete(&RefreshToken{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,39 @@
+package models
+
+import "slices"
+
+// Roles a user can have, every user starts as RoleUser.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Permissions granted by the roles, an owner never needs one to access their
+// own resources.
+const (
+	PermissionManagePhotos = "photos:manage"
+	PermissionManageUsers  = "users:manage"
+	PermissionManageRoles  = "roles:manage"
+	PermissionReadAuditLog = "audit:read"
+)
+
+var rolePermissions = map[string][]string{
+	RoleUser:      {},
+	RoleModerator: {PermissionManagePhotos},
+	RoleAdmin:     {PermissionManagePhotos, PermissionManageUsers, PermissionManageRoles, PermissionReadAuditLog},
+}
+
+// Roles returns every role, in order of privilege.
+func Roles() []string {
+	return []string{RoleUser, RoleModerator, RoleAdmin}
+}
+
+func IsRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+func (user *User) HasPermission(permission string) bool {
+	return slices.Contains(rolePermissions[user.Role], permission)
+}
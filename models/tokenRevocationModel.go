@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+)
+
+// TokenRevocation either revokes the access token with the given JTI, or,
+// when JTI is nil, every access token of the user issued up to IssuedBefore.
+// The row is useless once ExpiresAt and the leeway the tokens are verified
+// with have passed since the tokens it revokes are rejected by then.
+type TokenRevocation struct {
+	ID           uint    `gorm:"primaryKey"`
+	JTI          *string `gorm:"column:jti;size:64;unique"`
+	UserID       uint    `gorm:"not null;index"`
+	User         User    `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	IssuedBefore *time.Time
+	ExpiresAt    time.Time `gorm:"index"`
+	CreatedAt    time.Time
+}
+
+type ITokenRevocationModel interface {
+	WithDB(db database.IDatabase) ITokenRevocationModel
+	RevokeToken(jti string, userId uint, expiresAt time.Time) (*TokenRevocation, error)
+	RevokeIssuedBefore(userId uint, issuedBefore time.Time, expiresAt time.Time) (*TokenRevocation, error)
+	GetActive(now time.Time) ([]TokenRevocation, error)
+	DeleteExpiredBefore(expiredBefore time.Time) (int64, error)
+}
+
+type TokenRevocationModel struct {
+	db database.IDatabase
+}
+
+func NewTokenRevocationModel(db database.IDatabase) ITokenRevocationModel {
+	return &TokenRevocationModel{
+		db: db,
+	}
+}
+
+func (tokenRevocationModel *TokenRevocationModel) WithDB(db database.IDatabase) ITokenRevocationModel {
+	return NewTokenRevocationModel(db)
+}
+
+func (tokenRevocationModel *TokenRevocationModel) RevokeToken(jti string, userId uint, expiresAt time.Time) (*TokenRevocation, error) {
+	revocation := &TokenRevocation{
+		JTI:       &jti,
+		UserID:    userId,
+		ExpiresAt: expiresAt,
+	}
+
+	result := tokenRevocationModel.db.GetClient().Omit("User").Create(revocation)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return revocation, nil
+}
+
+func (tokenRevocationModel *TokenRevocationModel) RevokeIssuedBefore(userId uint, issuedBefore time.Time, expiresAt time.Time) (*TokenRevocation, error) {
+	revocation := &TokenRevocation{
+		UserID:       userId,
+		IssuedBefore: &issuedBefore,
+		ExpiresAt:    expiresAt,
+	}
+
+	result := tokenRevocationModel.db.GetClient().Omit("User").Create(revocation)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return revocation, nil
+}
+
+func (tokenRevocationModel *TokenRevocationModel) GetActive(now time.Time) ([]TokenRevocation, error) {
+	var revocations []TokenRevocation
+	result := tokenRevocationModel.db.GetReadClient().Where("expires_at > ?", now).Find(&revocations)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return revocations, nil
+}
+
+func (tokenRevocationModel *TokenRevocationModel) DeleteExpiredBefore(expiredBefore time.Time) (int64, error) {
+	result := tokenRevocationModel.db.GetClient().Where("expires_at < ?", expiredBefore).Delete(&TokenRevocation{})
+	return result.RowsAffected, result.Error
+}
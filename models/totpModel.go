@@ -0,0 +1,140 @@
+package models
+
+import (
+	"time"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"gorm.io/gorm"
+)
+
+// UserTOTP is the TOTP secret of a user, two-factor login is only required
+// once the enrolment has been confirmed with a first code.
+type UserTOTP struct {
+	ID     uint   `gorm:"primaryKey"`
+	UserID uint   `gorm:"not null;unique"`
+	User   User   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Secret string `gorm:"not null"`
+	// LastUsedStep is the time step of the last accepted code, older codes
+	// are refused so an intercepted code can't be replayed.
+	LastUsedStep   int64 `gorm:"not null;default:0"`
+	FailedAttempts int   `gorm:"not null;default:0"`
+	ConfirmedAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type ITOTPModel interface {
+	WithDB(db database.IDatabase) ITOTPModel
+	GetByUser(userId uint) (*UserTOTP, error)
+	SaveSecret(userId uint, secret string) (*UserTOTP, error)
+	Confirm(totp *UserTOTP, step int64) (*UserTOTP, error)
+	UseStep(totp *UserTOTP, step int64) (bool, error)
+	RecordFailure(totp *UserTOTP) (int, error)
+	ResetFailures(totp *UserTOTP) error
+	DeleteByUser(userId uint) error
+}
+
+type TOTPModel struct {
+	db database.IDatabase
+}
+
+func NewTOTPModel(db database.IDatabase) ITOTPModel {
+	return &TOTPModel{
+		db: db,
+	}
+}
+
+func (totpModel *TOTPModel) WithDB(db database.IDatabase) ITOTPModel {
+	return NewTOTPModel(db)
+}
+
+func (totpModel *TOTPModel) GetByUser(userId uint) (*UserTOTP, error) {
+	totp := &UserTOTP{}
+	result := totpModel.db.GetReadClient().Where("user_id = ?", userId).First(totp)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return totp, nil
+}
+
+// SaveSecret starts a new enrolment, replacing the secret of an unconfirmed one.
+func (totpModel *TOTPModel) SaveSecret(userId uint, secret string) (*UserTOTP, error) {
+	client := totpModel.db.GetClient()
+	if err := client.Where("user_id = ?", userId).Delete(&UserTOTP{}).Error; err != nil {
+		return nil, err
+	}
+
+	newTOTP := &UserTOTP{
+		UserID: userId,
+		Secret: secret,
+	}
+	result := client.Omit("User").Create(newTOTP)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return newTOTP, nil
+}
+
+func (totpModel *TOTPModel) Confirm(totp *UserTOTP, step int64) (*UserTOTP, error) {
+	confirmedAt := time.Now()
+	result := totpModel.db.GetClient().Model(totp).Updates(map[string]interface{}{
+		"confirmed_at":    confirmedAt,
+		"last_used_step":  step,
+		"failed_attempts": 0,
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	totp.ConfirmedAt = &confirmedAt
+	totp.LastUsedStep = step
+	totp.FailedAttempts = 0
+	return totp, nil
+}
+
+// UseStep records an accepted code and clears the failed attempts, it returns
+// false when a concurrent request already used this step or a later one.
+func (totpModel *TOTPModel) UseStep(totp *UserTOTP, step int64) (bool, error) {
+	result := totpModel.db.GetClient().Model(&UserTOTP{}).
+		Where("id = ? AND last_used_step < ?", totp.ID, step).
+		Updates(map[string]interface{}{
+			"last_used_step":  step,
+			"failed_attempts": 0,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	totp.LastUsedStep = step
+	totp.FailedAttempts = 0
+	return true, nil
+}
+
+// RecordFailure counts an invalid code and returns the failures in a row.
+func (totpModel *TOTPModel) RecordFailure(totp *UserTOTP) (int, error) {
+	client := totpModel.db.GetClient()
+	result := client.Model(&UserTOTP{}).Where("id = ?", totp.ID).
+		UpdateColumn("failed_attempts", gorm.Expr("failed_attempts + 1"))
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if err := client.Model(&UserTOTP{}).Where("id = ?", totp.ID).Pluck("failed_attempts", &totp.FailedAttempts).Error; err != nil {
+		return 0, err
+	}
+	return totp.FailedAttempts, nil
+}
+
+// ResetFailures clears the failures once a recovery code has been accepted.
+func (totpModel *TOTPModel) ResetFailures(totp *UserTOTP) error {
+	result := totpModel.db.GetClient().Model(&UserTOTP{}).Where("id = ?", totp.ID).UpdateColumn("failed_attempts", 0)
+	if result.Error != nil {
+		return result.Error
+	}
+	totp.FailedAttempts = 0
+	return nil
+}
+
+func (totpModel *TOTPModel) DeleteByUser(userId uint) error {
+	return totpModel.db.GetClient().Where("user_id = ?", userId).Delete(&UserTOTP{}).Error
+}
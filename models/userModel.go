@@ -9,21 +9,35 @@ import (
 )
 
 type User struct {
-	ID        uint    `gorm:"primaryKey"`
-	Username  string  `gorm:"not null"`
-	Email     string  `gorm:"unique;not null"`
-	Password  string  `gorm:"not null"`
-	Photos    []Photo `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID              uint    `gorm:"primaryKey"`
+	Username        string  `gorm:"not null"`
+	Email           string  `gorm:"unique;not null"`
+	Password        string  `gorm:"not null"`
+	Role            string  `gorm:"size:32;not null;default:user"`
+	Photos          []Photo `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	DisabledAt      *time.Time
+	EmailVerifiedAt *time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"`
 }
 
 type IUserModel interface {
+	WithDB(db database.IDatabase) IUserModel
 	CreateUser(user *app.UserRegisterRequest) (*User, error)
 	GetByEmail(userEmail string, detailed bool) (*User, error)
 	GetById(userId uint, detailed bool) (*User, error)
 	UpdateUser(user *User, updateBody *app.UserUpdateRequest) (*User, error)
 	DeleteUser(user *User) (*User, error)
+	GetTrashedByEmail(userEmail string) (*User, error)
+	GetTrashedBefore(deletedBefore time.Time) ([]User, error)
+	RestoreUser(user *User) (*User, error)
+	PurgeUser(user *User) (*User, error)
+	SetRole(user *User, role string) (*User, error)
+	SetDisabled(user *User, disabled bool) (*User, error)
+	UpdatePassword(user *User, hashedPassword string) (*User, error)
+	RehashPassword(user *User, verifiedHash string, hashedPassword string) (bool, error)
+	MarkEmailVerified(user *User) (*User, error)
 }
 
 type UserModel struct {
@@ -36,11 +50,18 @@ func NewUserModel(db database.IDatabase) IUserModel {
 	}
 }
 
+// WithDB returns a copy of the model that runs its queries on db, pass the tx
+// given by IDatabase.WithTransaction to make the model part of the transaction.
+func (userModel *UserModel) WithDB(db database.IDatabase) IUserModel {
+	return NewUserModel(db)
+}
+
 func (userModel *UserModel) CreateUser(u *app.UserRegisterRequest) (*User, error) {
 	newUser := &User{
 		Username: u.Username,
 		Email:    u.Email,
 		Password: u.Password,
+		Role:     RoleUser,
 		Photos:   []Photo{},
 	}
 
@@ -52,7 +73,7 @@ func (userModel *UserModel) CreateUser(u *app.UserRegisterRequest) (*User, error
 }
 
 func (userModel *UserModel) GetByEmail(userEmail string, detailed bool) (*User, error) {
-	client := userModel.db.GetClient()
+	client := userModel.db.GetReadClient()
 	user := &User{}
 	var result *gorm.DB
 	if detailed {
@@ -67,7 +88,7 @@ func (userModel *UserModel) GetByEmail(userEmail string, detailed bool) (*User,
 }
 
 func (userModel *UserModel) GetById(userId uint, detailed bool) (*User, error) {
-	client := userModel.db.GetClient()
+	client := userModel.db.GetReadClient()
 	user := &User{}
 	var result *gorm.DB
 	if detailed {
@@ -84,6 +105,10 @@ func (userModel *UserModel) GetById(userId uint, detailed bool) (*User, error) {
 func (userModel *UserModel) UpdateUser(user *User, updateBody *app.UserUpdateRequest) (*User, error) {
 	client := userModel.db.GetClient()
 
+	// A new address has to be verified again
+	if user.Email != updateBody.Email {
+		user.EmailVerifiedAt = nil
+	}
 	user.Username = updateBody.Username
 	user.Email = updateBody.Email
 	user.Password = updateBody.NewPassword
@@ -97,12 +122,136 @@ func (userModel *UserModel) UpdateUser(user *User, updateBody *app.UserUpdateReq
 	return user, nil
 }
 
+// DeleteUser moves the user and their photos to the trash with the same
+// deleted_at, RestoreUser relies on it to bring back only those photos.
 func (userModel *UserModel) DeleteUser(user *User) (*User, error) {
-	client := userModel.db.GetClient()
-	result := client.Delete(user)
+	deletedAt := time.Now()
+	err := userModel.db.GetClient().Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(user).UpdateColumn("deleted_at", deletedAt)
+		if result.Error != nil {
+			return result.Error
+		}
+		return tx.Model(&Photo{}).Where("user_id = ?", user.ID).UpdateColumn("deleted_at", deletedAt).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user.DeletedAt = gorm.DeletedAt{Time: deletedAt, Valid: true}
+	return user, nil
+}
+
+func (userModel *UserModel) GetTrashedByEmail(userEmail string) (*User, error) {
+	user := &User{}
+	result := userModel.db.GetReadClient().Unscoped().
+		Where("email = ? AND deleted_at IS NOT NULL", userEmail).First(user)
 	if result.Error != nil {
 		return nil, result.Error
 	}
+	return user, nil
+}
 
+// GetTrashedBefore also loads every photo of the returned users, trashed or not.
+func (userModel *UserModel) GetTrashedBefore(deletedBefore time.Time) ([]User, error) {
+	var users []User
+	result := userModel.db.GetReadClient().Unscoped().
+		Preload("Photos", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).
+		Where("deleted_at < ?", deletedBefore).Find(&users)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return users, nil
+}
+
+// RestoreUser brings back the user and the photos that were trashed together
+// with them, photos the user had already trashed before stay in the trash.
+func (userModel *UserModel) RestoreUser(user *User) (*User, error) {
+	err := userModel.db.GetClient().Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(&Photo{}).
+			Where("user_id = ? AND deleted_at >= ?", user.ID, user.DeletedAt.Time).
+			UpdateColumn("deleted_at", nil)
+		if result.Error != nil {
+			return result.Error
+		}
+		return tx.Unscoped().Model(user).UpdateColumn("deleted_at", nil).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user.DeletedAt = gorm.DeletedAt{}
+	return user, nil
+}
+
+// PurgeUser removes the user for good, their photo rows go with them through the cascade.
+func (userModel *UserModel) PurgeUser(user *User) (*User, error) {
+	result := userModel.db.GetClient().Unscoped().Delete(user)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return user, nil
+}
+
+// SetRole changes the role of the user, it applies to the tokens issued before
+// since the role is read on every request.
+func (userModel *UserModel) SetRole(user *User, role string) (*User, error) {
+	result := userModel.db.GetClient().Model(user).Update("role", role)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	user.Role = role
+	return user, nil
+}
+
+// SetDisabled blocks or unblocks the account, a disabled user can neither log
+// in nor use a token issued before.
+func (userModel *UserModel) SetDisabled(user *User, disabled bool) (*User, error) {
+	var disabledAt *time.Time
+	if disabled {
+		now := time.Now()
+		disabledAt = &now
+	}
+	result := userModel.db.GetClient().Model(user).Update("disabled_at", disabledAt)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	user.DisabledAt = disabledAt
+	return user, nil
+}
+
+func (userModel *UserModel) UpdatePassword(user *User, hashedPassword string) (*User, error) {
+	result := userModel.db.GetClient().Model(user).Update("password", hashedPassword)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return user, nil
+}
+
+// RehashPassword replaces verifiedHash with a new hash of the same password, it
+// returns false without changing anything when the password was changed since
+// verifiedHash was read, so an old password can't be written back.
+func (userModel *UserModel) RehashPassword(user *User, verifiedHash string, hashedPassword string) (bool, error) {
+	result := userModel.db.GetClient().Model(&User{}).
+		Where("id = ? AND password = ?", user.ID, verifiedHash).
+		Update("password", hashedPassword)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	user.Password = hashedPassword
+	return true, nil
+}
+
+func (userModel *UserModel) MarkEmailVerified(user *User) (*User, error) {
+	verifiedAt := time.Now()
+	result := userModel.db.GetClient().Model(user).Update("email_verified_at", verifiedAt)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	user.EmailVerifiedAt = &verifiedAt
 	return user, nil
 }
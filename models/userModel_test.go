@@ -0,0 +1,106 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/app"
+	"gorm.io/gorm"
+)
+
+func TestUserModelCreateAndUpdate(t *testing.T) {
+	userModel := NewUserModel(newTestDB(t))
+	user := createTestUser(t, userModel, "user@example.com")
+
+	if _, err := userModel.CreateUser(&app.UserRegisterRequest{Username: "other", Email: "user@example.com", Password: "hash"}); err == nil {
+		t.Fatal("expected the email to be unique")
+	}
+
+	user, err := userModel.MarkEmailVerified(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := userModel.UpdateUser(user, &app.UserUpdateRequest{Username: "renamed", Email: "new@example.com", NewPassword: "new-hash"}); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := userModel.GetByEmail("new@example.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Username != "renamed" || updated.Password != "new-hash" {
+		t.Fatalf("expected the user to be updated, got %+v", updated)
+	}
+	if updated.EmailVerifiedAt != nil {
+		t.Fatal("expected the new email to need a verification")
+	}
+}
+
+func TestUserModelRehashPasswordComparesTheHash(t *testing.T) {
+	userModel := NewUserModel(newTestDB(t))
+	user := createTestUser(t, userModel, "user@example.com")
+
+	if swapped, err := userModel.RehashPassword(user, "other-hash", "rehashed"); err != nil || swapped {
+		t.Fatalf("expected a changed hash not to be replaced, swapped %t, err %v", swapped, err)
+	}
+	if swapped, err := userModel.RehashPassword(user, "hash", "rehashed"); err != nil || !swapped {
+		t.Fatalf("expected the verified hash to be replaced, swapped %t, err %v", swapped, err)
+	}
+}
+
+func TestUserModelTrashRestoresOnlyPhotosTrashedWithUser(t *testing.T) {
+	db := newTestDB(t)
+	userModel := NewUserModel(db)
+	photoModel := NewPhotoModel(db)
+	user := createTestUser(t, userModel, "user@example.com")
+	trashedBefore := createTestPhoto(t, photoModel, user.ID, "trashed")
+	kept := createTestPhoto(t, photoModel, user.ID, "kept")
+
+	if _, err := photoModel.DeletePhoto(trashedBefore); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if _, err := userModel.DeleteUser(user); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := userModel.GetByEmail("user@example.com", false); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected the user to be in the trash, got %v", err)
+	}
+	if _, err := photoModel.GetById(kept.ID, false); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected the photos to be trashed with the user, got %v", err)
+	}
+
+	trashed, err := userModel.GetTrashedByEmail("user@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := userModel.RestoreUser(trashed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := photoModel.GetById(kept.ID, false); err != nil {
+		t.Fatalf("expected the photo trashed with the user to be restored, got %v", err)
+	}
+	if _, err := photoModel.GetTrashedById(trashedBefore.ID); err != nil {
+		t.Fatalf("expected the photo trashed before to stay in the trash, got %v", err)
+	}
+}
+
+func TestUserModelPurgeCascadesToPhotos(t *testing.T) {
+	db := newTestDB(t)
+	userModel := NewUserModel(db)
+	photoModel := NewPhotoModel(db)
+	user := createTestUser(t, userModel, "user@example.com")
+	photo := createTestPhoto(t, photoModel, user.ID, "photo")
+
+	if _, err := userModel.PurgeUser(user); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	if err := db.GetClient().Unscoped().Model(&Photo{}).Where("id = ?", photo.ID).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatal("expected the photos to be purged with the user")
+	}
+}
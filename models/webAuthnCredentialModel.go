@@ -0,0 +1,170 @@
+package models
+
+import (
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+)
+
+// WebAuthnCredential is a passkey of a user, the public key verifies the
+// assertions of the authenticator holding the private key.
+type WebAuthnCredential struct {
+	ID     uint `gorm:"primaryKey"`
+	UserID uint `gorm:"not null;index"`
+	User   User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// CredentialID is the id chosen by the authenticator, base64url encoded.
+	CredentialID    string `gorm:"size:255;unique;not null"`
+	Name            string `gorm:"not null"`
+	PublicKey       []byte `gorm:"not null"`
+	AttestationType string
+	// Transports is a comma separated list, like usb,nfc or internal,hybrid.
+	Transports     string
+	AAGUID         []byte
+	SignCount      uint32 `gorm:"not null;default:0"`
+	UserVerified   bool   `gorm:"not null;default:false"`
+	BackupEligible bool   `gorm:"not null;default:false"`
+	BackupState    bool   `gorm:"not null;default:false"`
+	LastUsedAt     *time.Time
+	CreatedAt      time.Time
+}
+
+func (WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
+
+type IWebAuthnCredentialModel interface {
+	WithDB(db database.IDatabase) IWebAuthnCredentialModel
+	CreateCredential(userId uint, name string, credential *webauthn.Credential) (*WebAuthnCredential, error)
+	GetByUser(userId uint) ([]WebAuthnCredential, error)
+	GetByCredentialID(credentialId []byte) (*WebAuthnCredential, error)
+	GetById(credentialId uint) (*WebAuthnCredential, error)
+	UpdateAfterLogin(credential *WebAuthnCredential, authenticated *webauthn.Credential) (*WebAuthnCredential, error)
+	DeleteCredential(credential *WebAuthnCredential) (*WebAuthnCredential, error)
+}
+
+type WebAuthnCredentialModel struct {
+	db database.IDatabase
+}
+
+func NewWebAuthnCredentialModel(db database.IDatabase) IWebAuthnCredentialModel {
+	return &WebAuthnCredentialModel{
+		db: db,
+	}
+}
+
+func (webAuthnCredentialModel *WebAuthnCredentialModel) WithDB(db database.IDatabase) IWebAuthnCredentialModel {
+	return NewWebAuthnCredentialModel(db)
+}
+
+func (webAuthnCredentialModel *WebAuthnCredentialModel) CreateCredential(userId uint, name string, credential *webauthn.Credential) (*WebAuthnCredential, error) {
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	newCredential := &WebAuthnCredential{
+		UserID:          userId,
+		CredentialID:    base64.RawURLEncoding.EncodeToString(credential.ID),
+		Name:            name,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      strings.Join(transports, ","),
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		UserVerified:    credential.Flags.UserVerified,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+
+	result := webAuthnCredentialModel.db.GetClient().Omit("User").Create(newCredential)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return newCredential, nil
+}
+
+func (webAuthnCredentialModel *WebAuthnCredentialModel) GetByUser(userId uint) ([]WebAuthnCredential, error) {
+	credentials := []WebAuthnCredential{}
+	result := webAuthnCredentialModel.db.GetReadClient().Where("user_id = ?", userId).Order("id").Find(&credentials)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return credentials, nil
+}
+
+func (webAuthnCredentialModel *WebAuthnCredentialModel) GetByCredentialID(credentialId []byte) (*WebAuthnCredential, error) {
+	credential := &WebAuthnCredential{}
+	result := webAuthnCredentialModel.db.GetReadClient().
+		Where("credential_id = ?", base64.RawURLEncoding.EncodeToString(credentialId)).
+		First(credential)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return credential, nil
+}
+
+func (webAuthnCredentialModel *WebAuthnCredentialModel) GetById(credentialId uint) (*WebAuthnCredential, error) {
+	credential := &WebAuthnCredential{}
+	result := webAuthnCredentialModel.db.GetReadClient().First(credential, credentialId)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return credential, nil
+}
+
+// UpdateAfterLogin stores the sign count and backup state reported by the
+// authenticator, a lower count next time means the key may have been cloned.
+func (webAuthnCredentialModel *WebAuthnCredentialModel) UpdateAfterLogin(credential *WebAuthnCredential, authenticated *webauthn.Credential) (*WebAuthnCredential, error) {
+	usedAt := time.Now()
+	result := webAuthnCredentialModel.db.GetClient().Model(credential).Updates(map[string]interface{}{
+		"sign_count":   authenticated.Authenticator.SignCount,
+		"backup_state": authenticated.Flags.BackupState,
+		"last_used_at": usedAt,
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	credential.SignCount = authenticated.Authenticator.SignCount
+	credential.BackupState = authenticated.Flags.BackupState
+	credential.LastUsedAt = &usedAt
+	return credential, nil
+}
+
+func (webAuthnCredentialModel *WebAuthnCredentialModel) DeleteCredential(credential *WebAuthnCredential) (*WebAuthnCredential, error) {
+	result := webAuthnCredentialModel.db.GetClient().Delete(credential)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return credential, nil
+}
+
+// WebAuthn converts the stored passkey back to the credential the ceremonies
+// verify assertions with.
+func (credential *WebAuthnCredential) WebAuthn() webauthn.Credential {
+	id, _ := base64.RawURLEncoding.DecodeString(credential.CredentialID)
+	transports := []protocol.AuthenticatorTransport{}
+	if credential.Transports != "" {
+		for _, transport := range strings.Split(credential.Transports, ",") {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+	}
+	return webauthn.Credential{
+		ID:              id,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			UserVerified:   credential.UserVerified,
+			BackupEligible: credential.BackupEligible,
+			BackupState:    credential.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    credential.AAGUID,
+			SignCount: credential.SignCount,
+		},
+	}
+}
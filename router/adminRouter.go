@@ -0,0 +1,33 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/controllers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/middlewares"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/stores"
+)
+
+func AdminRouting(route *gin.Engine, db database.IDatabase, webToken helpers.IWebToken, revocationStore stores.IRevocationStore, loginThrottle stores.ILoginThrottle, resources *middlewares.OwnershipRegistry) {
+	userModel := models.NewUserModel(db)
+	apiKeyModel := models.NewAPIKeyModel(db.Primary())
+	auditLogModel := models.NewAuditLogModel(db)
+	validator := helpers.NewValidator()
+
+	adminController := controllers.NewAdminController(db, userModel, auditLogModel, validator)
+	apiKey := helpers.NewAPIKey()
+	authMW := middlewares.NewAuthMiddleware(userModel, apiKeyModel, auditLogModel, webToken, apiKey, revocationStore, resources)
+
+	adminRoute := route.Group("/admin")
+	{
+		// API key tidak membawa permission dari role sehingga seluruh route admin menolaknya
+		adminRoute.Use(authMW.Guard())
+		{
+			adminRoute.PUT("/users/:userId/role", authMW.RequirePermission(models.PermissionManageRoles), adminController.HandleSetRole())
+			adminRoute.POST("/users/:userId/unlock", authMW.RequirePermission(models.PermissionManageUsers), adminController.HandleUnlock(loginThrottle))
+			adminRoute.GET("/audit-logs", authMW.RequirePermission(models.PermissionReadAuditLog), adminController.HandleFetchAuditLogs())
+		}
+	}
+}
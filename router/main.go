@@ -2,12 +2,31 @@ package router
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/config"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/controllers"
 	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/jobs"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/mailer"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/stores"
 )
 
-func RouteApp(app *gin.Engine, database database.IDatabase) {
-	app.Static("/public", "./static/photos")
+func RouteApp(app *gin.Engine, database database.IDatabase, cfg *config.Config, webToken helpers.IWebToken, verificationToken helpers.IEmailVerificationToken, mfaChallenge helpers.IMFAChallengeToken, relyingParty *webauthn.WebAuthn, webAuthnSession helpers.IWebAuthnSessionToken, oidcProvider helpers.IOIDCProvider, oidcState helpers.IOIDCStateToken, mailSender mailer.IMailer, resetQueue jobs.IPasswordResetQueue, revocationStore stores.IRevocationStore, loginThrottle stores.ILoginThrottle) {
+	healthController := controllers.NewHealthController(database)
+	app.GET("/health", healthController.HandleHealth())
 
-	UserRouting(app, database)
-	PhotoRouting(app, database)
+	jwksController := controllers.NewJWKSController(webToken)
+	app.GET("/.well-known/jwks.json", jwksController.HandleJWKS())
+
+	resources := newOwnershipRegistry(database)
+
+	UserRouting(app, database, cfg, webToken, verificationToken, mfaChallenge, mailSender, resetQueue, revocationStore, loginThrottle, resources)
+	WebAuthnRouting(app, database, cfg, webToken, relyingParty, webAuthnSession, revocationStore, resources)
+	// Login dengan identity provider hanya tersedia apabila issuer telah dikonfigurasi
+	if oidcProvider != nil {
+		OIDCRouting(app, database, cfg, webToken, mfaChallenge, oidcProvider, oidcState, revocationStore, resources)
+	}
+	PhotoRouting(app, database, cfg, webToken, revocationStore, resources)
+	AdminRouting(app, database, webToken, revocationStore, loginThrottle, resources)
 }
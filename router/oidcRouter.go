@@ -0,0 +1,42 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/config"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/controllers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/middlewares"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/stores"
+)
+
+func OIDCRouting(route *gin.Engine, db database.IDatabase, cfg *config.Config, webToken helpers.IWebToken, mfaChallenge helpers.IMFAChallengeToken, provider helpers.IOIDCProvider, stateToken helpers.IOIDCStateToken, revocationStore stores.IRevocationStore, resources *middlewares.OwnershipRegistry) {
+	userModel := models.NewUserModel(db)
+	apiKeyModel := models.NewAPIKeyModel(db.Primary())
+	auditLogModel := models.NewAuditLogModel(db)
+	identityModel := models.NewExternalIdentityModel(db)
+	refreshTokenModel := models.NewRefreshTokenModel(db)
+	totpModel := models.NewTOTPModel(db)
+	validator := helpers.NewValidator()
+
+	oidcController := controllers.NewOIDCController(db, userModel, identityModel, refreshTokenModel, totpModel, validator)
+
+	hasher := cfg.Password.Hasher()
+	refreshToken := helpers.NewRefreshToken(cfg.JWT.RefreshExpiration)
+	apiKey := helpers.NewAPIKey()
+	authMW := middlewares.NewAuthMiddleware(userModel, apiKeyModel, auditLogModel, webToken, apiKey, revocationStore, resources)
+
+	oidcRoute := route.Group("/users/oidc")
+	{
+		oidcRoute.POST("/login/begin", oidcController.HandleBeginLogin(provider, stateToken))
+		oidcRoute.POST("/login/finish", oidcController.HandleFinishLogin(provider, stateToken, hasher, webToken, refreshToken, mfaChallenge, revocationStore, cfg.OIDC.Provision))
+		oidcRoute.Use(authMW.Guard())
+		{
+			oidcRoute.POST("/link/begin", oidcController.HandleBeginLink(provider, stateToken))
+			oidcRoute.POST("/link/finish", oidcController.HandleFinishLink(provider, stateToken, revocationStore))
+			oidcRoute.GET("/identities", oidcController.HandleFetchIdentities())
+			oidcRoute.DELETE("/identities/:identityId", oidcController.HandleDeleteIdentity())
+		}
+	}
+}
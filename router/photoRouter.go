@@ -1,49 +1,69 @@
 package router
 
 import (
-	"log"
-	"os"
-	"strconv"
-
 	"github.com/gin-gonic/gin"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/config"
 	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/controllers"
 	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
 	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
 	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/middlewares"
 	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/stores"
 )
 
-func PhotoRouting(route *gin.Engine, db database.IDatabase) {
+func PhotoRouting(route *gin.Engine, db database.IDatabase, cfg *config.Config, webToken helpers.IWebToken, revocationStore stores.IRevocationStore, resources *middlewares.OwnershipRegistry) {
 	photoModel := models.NewPhotoModel(db)
 	userModel := models.NewUserModel(db)
+	apiKeyModel := models.NewAPIKeyModel(db.Primary())
+	auditLogModel := models.NewAuditLogModel(db)
+	photoGrantModel := models.NewPhotoGrantModel(db)
 
 	validator := helpers.NewValidator()
+	storage := helpers.NewStorage(cfg.Storage.PhotoDir)
 
-	expTime, err := strconv.Atoi(os.Getenv("JWT_EXPIRATION"))
-	if err != nil {
-		log.Fatal("Error reading token expiration value from .env file")
-	}
-
-	webToken := helpers.NewWebToken(expTime, os.Getenv("JWT_SECRET"))
-
-	photoController := controllers.NewPhotoController(photoModel, validator)
-	authMW := middlewares.NewAuthMiddleware(userModel, webToken)
+	photoController := controllers.NewPhotoController(db, photoModel, validator, storage)
+	photoGrantController := controllers.NewPhotoGrantController(db, photoGrantModel, userModel, validator)
+	apiKey := helpers.NewAPIKey()
+	authMW := middlewares.NewAuthMiddleware(userModel, apiKeyModel, auditLogModel, webToken, apiKey, revocationStore, resources)
 	fileUploadMW := middlewares.NewFileUploadMiddleware()
+	// File photo hanya dapat diakses oleh pemilik dan user yang diberi akses, sama seperti photonya
+	route.GET("/public/:filename", authMW.Guard(middlewares.RequireScope(helpers.ScopePhotosRead)), photoController.ResolvePhotoFile("photoId"),
+		authMW.Authorize("photoId", middlewares.AllowAccess(models.PhotoAccessViewer, models.PhotoAccessEditor)), photoController.HandleFetchPhotoFile())
 	photoRoute := route.Group("/photos")
 	{
-		photoRoute.GET("/", photoController.HandleFetchPhotos())
-		photoRoute.Use(authMW.Guard())
+		photoRoute.GET("/", authMW.Guard(middlewares.RequireScope(helpers.ScopePhotosRead)), photoController.HandleFetchPhotos())
+		// Upload hanya untuk user yang telah memverifikasi email
+		photoRoute.POST("", authMW.Guard(middlewares.RequireVerifiedEmail(), middlewares.RequireScope(helpers.ScopePhotosWrite)), fileUploadMW.AllowMaxSizeKB("photo", 1024), fileUploadMW.AllowedExtension("photo", ".jpeg", ".jpg", ".png"),
+			photoController.HandleCreatePhoto())
+		photoRoute.GET("/trash", authMW.Guard(middlewares.RequireScope(helpers.ScopePhotosRead)), photoController.HandleFetchTrashedPhotos())
+		photoRoute.GET("/shared", authMW.Guard(middlewares.RequireScope(helpers.ScopePhotosRead)), photoGrantController.HandleFetchSharedPhotos())
+		photoRoute.GET("/:photoId", authMW.Guard(middlewares.RequireScope(helpers.ScopePhotosRead)),
+			authMW.Authorize("photoId", middlewares.AllowAccess(models.PhotoAccessViewer, models.PhotoAccessEditor)), photoController.HandleFetchPhoto())
+		photoRoute.Use(authMW.Guard(middlewares.RequireScope(helpers.ScopePhotosWrite)))
 		{
-
-			photoRoute.POST("", fileUploadMW.AllowMaxSizeKB("photo", 1024), fileUploadMW.AllowedExtension("photo", ".jpeg", ".jpg", ".png"),
-				photoController.HandleCreatePhoto())
+			trashSubRoute := photoRoute.Group("/trash/:trashedPhotoId")
+			{
+				trashSubRoute.Use(authMW.Authorize("trashedPhotoId"))
+				{
+					trashSubRoute.POST("/restore", photoController.HandleRestorePhoto())
+					trashSubRoute.DELETE("", photoController.HandlePurgePhoto())
+				}
+			}
 			idSubRoute := photoRoute.Group("/:photoId")
 			{
-				idSubRoute.Use(authMW.Authorize(photoModel))
+				// Editor dapat mengubah photo, namun hanya pemilik yang dapat menghapus dan membagikannya
+				idSubRoute.PUT("", authMW.Authorize("photoId", middlewares.AllowAccess(models.PhotoAccessEditor)),
+					fileUploadMW.AllowMaxSizeKB("photo", 1024), fileUploadMW.AllowedExtension("photo", ".jpeg", ".jpg", ".png"),
+					photoController.HandleUpdatePhoto())
+				idSubRoute.DELETE("", authMW.Authorize("photoId"), photoController.HandleDeletePhoto())
+				grantSubRoute := idSubRoute.Group("/grants")
 				{
-					idSubRoute.PUT("", fileUploadMW.AllowMaxSizeKB("photo", 1024), fileUploadMW.AllowedExtension("photo", ".jpeg", ".jpg", ".png"),
-						photoController.HandleUpdatePhoto())
-					idSubRoute.DELETE("", photoController.HandleDeletePhoto())
+					grantSubRoute.Use(authMW.Authorize("photoId"))
+					{
+						grantSubRoute.POST("", photoGrantController.HandleGrantAccess())
+						grantSubRoute.GET("", photoGrantController.HandleFetchGrants())
+						grantSubRoute.DELETE("/:userId", photoGrantController.HandleRevokeGrant())
+					}
 				}
 			}
 		}
@@ -0,0 +1,51 @@
+package router
+
+import (
+	"errors"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/middlewares"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"gorm.io/gorm"
+)
+
+// newOwnershipRegistry registers how Authorize loads each resource of the
+// routes and who owns it.
+func newOwnershipRegistry(db database.IDatabase) *middlewares.OwnershipRegistry {
+	userModel := models.NewUserModel(db)
+	photoModel := models.NewPhotoModel(db)
+	// Grant yang baru dicabut masih dapat terbaca dari replica, akses diputuskan dari database primary
+	photoGrantModel := models.NewPhotoGrantModel(db.Primary())
+
+	resources := middlewares.NewOwnershipRegistry()
+	resources.Register(middlewares.NewOwnership("userId", "user", models.PermissionManageUsers, middlewares.RequestedUser,
+		func(id uint) (*models.User, error) {
+			return userModel.GetById(id, false)
+		},
+		func(user *models.User) (uint, uint) {
+			return user.ID, user.ID
+		}))
+	resources.Register(middlewares.NewSharedOwnership("photoId", "photo", models.PermissionManagePhotos, middlewares.RequestedPhoto,
+		func(id uint) (*models.Photo, error) {
+			return photoModel.GetById(id, false)
+		},
+		func(photo *models.Photo) (uint, uint) {
+			return photo.ID, photo.UserID
+		},
+		func(photoId uint, userId uint) (string, error) {
+			grant, err := photoGrantModel.GetByPhotoAndUser(photoId, userId)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return "", nil
+			}
+			if err != nil {
+				return "", err
+			}
+			return grant.Access, nil
+		}))
+	resources.Register(middlewares.NewOwnership("trashedPhotoId", "photo", models.PermissionManagePhotos, middlewares.RequestedPhoto,
+		photoModel.GetTrashedById,
+		func(photo *models.Photo) (uint, uint) {
+			return photo.ID, photo.UserID
+		}))
+	return resources
+}
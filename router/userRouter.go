@@ -1,43 +1,89 @@
 package router
 
 import (
-	"log"
-	"os"
-	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/config"
 	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/controllers"
 	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
 	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/jobs"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/mailer"
 	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/middlewares"
 	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/stores"
 )
 
-func UserRouting(route *gin.Engine, db database.IDatabase) {
+func UserRouting(route *gin.Engine, db database.IDatabase, cfg *config.Config, webToken helpers.IWebToken, verificationToken helpers.IEmailVerificationToken, mfaChallenge helpers.IMFAChallengeToken, mailSender mailer.IMailer, resetQueue jobs.IPasswordResetQueue, revocationStore stores.IRevocationStore, loginThrottle stores.ILoginThrottle, resources *middlewares.OwnershipRegistry) {
 	userModel := models.NewUserModel(db)
 	validator := helpers.NewValidator()
 
-	userController := controllers.NewUserController(userModel, validator)
+	refreshTokenModel := models.NewRefreshTokenModel(db)
+	passwordResetModel := models.NewPasswordResetModel(db)
+	totpModel := models.NewTOTPModel(db)
+	recoveryCodeModel := models.NewRecoveryCodeModel(db)
+	apiKeyModel := models.NewAPIKeyModel(db.Primary())
+	auditLogModel := models.NewAuditLogModel(db)
 
-	hasher := helpers.NewHasher()
+	userController := controllers.NewUserController(db, userModel, refreshTokenModel, passwordResetModel, totpModel, validator)
+	twoFactorController := controllers.NewTwoFactorController(db, userModel, totpModel, recoveryCodeModel, refreshTokenModel, validator)
+	apiKeyController := controllers.NewAPIKeyController(db, apiKeyModel, validator)
 
-	expTime, err := strconv.Atoi(os.Getenv("JWT_EXPIRATION"))
-	if err != nil {
-		log.Fatal("Error reading token expiration value from .env file")
-	}
+	hasher := cfg.Password.Hasher()
 
-	webToken := helpers.NewWebToken(expTime, os.Getenv("JWT_SECRET"))
-	authMW := middlewares.NewAuthMiddleware(userModel, webToken)
+	refreshToken := helpers.NewRefreshToken(cfg.JWT.RefreshExpiration)
+	resetToken := helpers.NewOpaqueToken(cfg.Password.ResetTokenLifetime)
+	totp := helpers.NewTOTP(cfg.MFA.Issuer)
+	recoveryCode := helpers.NewRecoveryCode()
+	verifyURL := strings.TrimSuffix(cfg.Server.PublicURL, "/") + "/users/email/verify"
+	apiKey := helpers.NewAPIKey()
+	authMW := middlewares.NewAuthMiddleware(userModel, apiKeyModel, auditLogModel, webToken, apiKey, revocationStore, resources)
 
 	usersRoute := route.Group("/users")
 	{
-		usersRoute.POST("/register", userController.HandleRegister(hasher, webToken))
-		usersRoute.GET("/login", userController.HandleLogin(hasher, webToken))
+		usersRoute.POST("/register", userController.HandleRegister(hasher, webToken, refreshToken, verificationToken, mailSender, verifyURL))
+		usersRoute.GET("/login", userController.HandleLogin(hasher, webToken, refreshToken, mfaChallenge, loginThrottle))
+		usersRoute.POST("/login/mfa", twoFactorController.HandleLoginMFA(webToken, refreshToken, mfaChallenge, totp, recoveryCode, revocationStore, loginThrottle, cfg.MFA.MaxAttempts))
+		usersRoute.POST("/token/refresh", userController.HandleRefresh(webToken, refreshToken))
+		usersRoute.POST("/password/forgot", userController.HandleForgotPassword(resetQueue, loginThrottle))
+		usersRoute.POST("/password/reset", userController.HandleResetPassword(hasher, resetToken, revocationStore, loginThrottle))
+		usersRoute.GET("/email/verify", userController.HandleVerifyEmail(verificationToken))
+		usersRoute.POST("/email/verify/resend", authMW.Guard(), userController.HandleResendVerification(verificationToken, mailSender, verifyURL, loginThrottle))
+		logoutSubRoute := usersRoute.Group("/logout")
+		{
+			logoutSubRoute.Use(authMW.Guard())
+			{
+				logoutSubRoute.POST("", userController.HandleLogout(refreshToken, revocationStore))
+				logoutSubRoute.POST("/all", userController.HandleLogoutAll(revocationStore))
+			}
+		}
+		twoFactorSubRoute := usersRoute.Group("/2fa")
+		{
+			twoFactorSubRoute.Use(authMW.Guard())
+			{
+				twoFactorSubRoute.POST("/totp", twoFactorController.HandleEnrollTOTP(totp))
+				twoFactorSubRoute.POST("/totp/confirm", twoFactorController.HandleConfirmTOTP(totp, recoveryCode))
+				twoFactorSubRoute.DELETE("/totp", twoFactorController.HandleDisableTOTP(hasher, totp, recoveryCode, loginThrottle))
+				twoFactorSubRoute.POST("/recovery-codes", twoFactorController.HandleRegenerateRecoveryCodes(hasher, totp, recoveryCode, loginThrottle))
+			}
+		}
+		apiKeySubRoute := usersRoute.Group("/api-keys")
+		{
+			// API key tidak dapat membuat atau mencabut API key lain
+			apiKeySubRoute.Use(authMW.Guard())
+			{
+				apiKeySubRoute.POST("", apiKeyController.HandleCreateAPIKey(apiKey))
+				apiKeySubRoute.GET("", apiKeyController.HandleFetchAPIKeys())
+				apiKeySubRoute.DELETE("/:apiKeyId", apiKeyController.HandleRevokeAPIKey())
+			}
+		}
+		usersRoute.POST("/restore", userController.HandleRestore(hasher, loginThrottle))
 		idSubRoute := usersRoute.Group("/:userId")
 		{
-			idSubRoute.Use(authMW.Guard()).Use(authMW.Authorize(userModel))
+			idSubRoute.Use(authMW.Guard(middlewares.RequireScope(helpers.ScopeUsersWrite))).Use(authMW.Authorize("userId"))
 			{
-				idSubRoute.PUT("", userController.HandleUpdate(hasher))
+				idSubRoute.PUT("", userController.HandleUpdate(hasher, revocationStore, verificationToken, mailSender, verifyURL))
 				idSubRoute.DELETE("", userController.HandleDelete())
 			}
 		}
@@ -0,0 +1,41 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/config"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/controllers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/helpers"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/middlewares"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/stores"
+)
+
+func WebAuthnRouting(route *gin.Engine, db database.IDatabase, cfg *config.Config, webToken helpers.IWebToken, relyingParty *webauthn.WebAuthn, sessionToken helpers.IWebAuthnSessionToken, revocationStore stores.IRevocationStore, resources *middlewares.OwnershipRegistry) {
+	userModel := models.NewUserModel(db)
+	apiKeyModel := models.NewAPIKeyModel(db.Primary())
+	auditLogModel := models.NewAuditLogModel(db)
+	credentialModel := models.NewWebAuthnCredentialModel(db)
+	refreshTokenModel := models.NewRefreshTokenModel(db)
+	validator := helpers.NewValidator()
+
+	webAuthnController := controllers.NewWebAuthnController(db, userModel, credentialModel, refreshTokenModel, validator)
+
+	refreshToken := helpers.NewRefreshToken(cfg.JWT.RefreshExpiration)
+	apiKey := helpers.NewAPIKey()
+	authMW := middlewares.NewAuthMiddleware(userModel, apiKeyModel, auditLogModel, webToken, apiKey, revocationStore, resources)
+
+	webAuthnRoute := route.Group("/users/webauthn")
+	{
+		webAuthnRoute.POST("/login/begin", webAuthnController.HandleBeginLogin(relyingParty, sessionToken))
+		webAuthnRoute.POST("/login/finish", webAuthnController.HandleFinishLogin(relyingParty, sessionToken, webToken, refreshToken, revocationStore))
+		webAuthnRoute.Use(authMW.Guard())
+		{
+			webAuthnRoute.POST("/register/begin", webAuthnController.HandleBeginRegistration(relyingParty, sessionToken))
+			webAuthnRoute.POST("/register/finish", webAuthnController.HandleFinishRegistration(relyingParty, sessionToken, revocationStore))
+			webAuthnRoute.GET("/credentials", webAuthnController.HandleFetchCredentials())
+			webAuthnRoute.DELETE("/credentials/:credentialId", webAuthnController.HandleDeleteCredential())
+		}
+	}
+}
@@ -0,0 +1,395 @@
+package stores
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+)
+
+// Where LoginThrottle keeps the failed attempts.
+const (
+	LoginStoreMemory   = "memory"
+	LoginStoreDatabase = "database"
+)
+
+// ILoginAttemptBackend keeps the login attempts, models.ILoginAttemptModel
+// shares them between the instances through the database. Create and Swap
+// fail instead of overwriting an attempt changed by a concurrent login.
+type ILoginAttemptBackend interface {
+	GetByIdentifier(identifier string) (*models.LoginAttempt, error)
+	Create(attempt *models.LoginAttempt) (bool, error)
+	Swap(attempt *models.LoginAttempt, failures int) (bool, error)
+	DeleteByIdentifier(identifier string) error
+	DeleteExpiredBefore(lastFailedBefore time.Time, now time.Time) (int64, error)
+}
+
+// errLoginAttemptContended is returned when concurrent logins keep changing
+// an attempt, the login is refused rather than left uncounted.
+var errLoginAttemptContended = errors.New("login throttle: too many concurrent attempts")
+
+const loginAttemptRetries = 10
+
+type LoginThrottlePolicy struct {
+	// MaxAttempts is how many failures of an email lock it for LockoutDuration,
+	// IPMaxAttempts the same for the address the logins come from.
+	MaxAttempts     int
+	IPMaxAttempts   int
+	LockoutDuration time.Duration
+	// Before the lockout every failure blocks the next attempt for BaseDelay,
+	// doubled after each failure up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Window is how long failures are remembered after the last one.
+	Window time.Duration
+	// ResetMaxRequests is how many password resets an email, or verification
+	// emails a user, can request within Window, ResetIPMaxRequests the same for
+	// an address.
+	ResetMaxRequests   int
+	ResetIPMaxRequests int
+}
+
+func (policy *LoginThrottlePolicy) delay(failures int) time.Duration {
+	delay := policy.BaseDelay
+	for i := 1; i < failures && delay < policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, policy.MaxDelay)
+}
+
+type ILoginThrottle interface {
+	// Attempt counts a login of the email before its password is checked, so
+	// parallel logins can't all be checked before the first failure is counted.
+	// It returns how long to wait when the email or the address is blocked, the
+	// login is then refused without being counted.
+	Attempt(email string, ip string) (time.Duration, error)
+	// Failure counts a failed login against the address, which is shared by
+	// every user behind it and only blocked by the failures.
+	Failure(ip string) error
+	// Success forgets the attempts of the email.
+	Success(email string) error
+	// SecondFactorAttempt counts a code submitted for the user the same way,
+	// the user is blocked whichever password and login challenge are used.
+	SecondFactorAttempt(userId uint) (time.Duration, error)
+	// SecondFactorSuccess forgets the codes submitted for the user.
+	SecondFactorSuccess(userId uint) error
+	// ResetAttempt counts a password reset requested for the email from the
+	// address, it returns how long to wait once either made too many requests.
+	ResetAttempt(email string, ip string) (time.Duration, error)
+	// ResendAttempt counts a verification email requested by the user from the
+	// address, within the same limits as ResetAttempt.
+	ResendAttempt(userId uint, ip string) (time.Duration, error)
+	// Unlock forgets the attempts and ends the lockout of the user.
+	Unlock(userId uint, email string) error
+	Start(interval time.Duration)
+	Stop()
+}
+
+// LoginThrottle slows down and then locks out the emails and the addresses
+// guessing passwords. It tracks emails whether they belong to a user or not,
+// so its answers don't tell which accounts exist.
+type LoginThrottle struct {
+	backend ILoginAttemptBackend
+	policy  LoginThrottlePolicy
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewLoginThrottle(backend ILoginAttemptBackend, policy LoginThrottlePolicy) ILoginThrottle {
+	return &LoginThrottle{
+		backend: backend,
+		policy:  policy,
+	}
+}
+
+func emailIdentifier(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipIdentifier(ip string) string {
+	return "ip:" + ip
+}
+
+func secondFactorIdentifier(userId uint) string {
+	return "mfa:" + strconv.FormatUint(uint64(userId), 10)
+}
+
+func resetIdentifier(identifier string) string {
+	return "reset:" + identifier
+}
+
+func resendIdentifier(identifier string) string {
+	return "resend:" + identifier
+}
+
+// attemptLimit tells count how long to block an identifier after an attempt.
+type attemptLimit struct {
+	maxAttempts int
+	delay       func(failures int) time.Duration
+	lockout     time.Duration
+}
+
+// loginLimit blocks after each failure and locks out at maxAttempts, resetLimit
+// only blocks for the rest of the window once the requests reach the limit.
+func (throttle *LoginThrottle) loginLimit(maxAttempts int) attemptLimit {
+	return attemptLimit{maxAttempts: maxAttempts, delay: throttle.policy.delay, lockout: throttle.policy.LockoutDuration}
+}
+
+func (throttle *LoginThrottle) resetLimit(maxRequests int) attemptLimit {
+	return attemptLimit{maxAttempts: maxRequests, lockout: throttle.policy.Window}
+}
+
+func (throttle *LoginThrottle) Attempt(email string, ip string) (time.Duration, error) {
+	now := time.Now()
+	wait, err := throttle.blocked(ipIdentifier(ip), now)
+	if err != nil || wait > 0 {
+		return wait, err
+	}
+	wait, _, err = throttle.count(emailIdentifier(email), throttle.loginLimit(throttle.policy.MaxAttempts), now, true)
+	return wait, err
+}
+
+func (throttle *LoginThrottle) Failure(ip string) error {
+	_, _, err := throttle.count(ipIdentifier(ip), throttle.loginLimit(throttle.policy.IPMaxAttempts), time.Now(), false)
+	return err
+}
+
+func (throttle *LoginThrottle) ResetAttempt(email string, ip string) (time.Duration, error) {
+	return throttle.attempt(resetIdentifier(emailIdentifier(email)), throttle.resetLimit(throttle.policy.ResetMaxRequests), resetIdentifier(ipIdentifier(ip)), throttle.resetLimit(throttle.policy.ResetIPMaxRequests))
+}
+
+func (throttle *LoginThrottle) ResendAttempt(userId uint, ip string) (time.Duration, error) {
+	user := "user:" + strconv.FormatUint(uint64(userId), 10)
+	return throttle.attempt(resendIdentifier(user), throttle.resetLimit(throttle.policy.ResetMaxRequests), resendIdentifier(ipIdentifier(ip)), throttle.resetLimit(throttle.policy.ResetIPMaxRequests))
+}
+
+// attempt counts the address first, then the email, and takes back the count
+// of the address when the email is blocked.
+func (throttle *LoginThrottle) attempt(email string, emailLimit attemptLimit, ip string, ipLimit attemptLimit) (time.Duration, error) {
+	now := time.Now()
+	wait, counted, err := throttle.count(ip, ipLimit, now, true)
+	if err != nil || wait > 0 {
+		return wait, err
+	}
+	wait, _, err = throttle.count(email, emailLimit, now, true)
+	if err != nil || wait > 0 {
+		// The address isn't charged for an attempt that was refused
+		if err := throttle.refund(ip, counted, ipLimit.maxAttempts); err != nil {
+			log.Printf("login throttle: %s", err.Error())
+		}
+	}
+	return wait, err
+}
+
+func (throttle *LoginThrottle) Success(email string) error {
+	return throttle.backend.DeleteByIdentifier(emailIdentifier(email))
+}
+
+func (throttle *LoginThrottle) SecondFactorAttempt(userId uint) (time.Duration, error) {
+	wait, _, err := throttle.count(secondFactorIdentifier(userId), throttle.loginLimit(throttle.policy.MaxAttempts), time.Now(), true)
+	return wait, err
+}
+
+func (throttle *LoginThrottle) SecondFactorSuccess(userId uint) error {
+	return throttle.backend.DeleteByIdentifier(secondFactorIdentifier(userId))
+}
+
+func (throttle *LoginThrottle) Unlock(userId uint, email string) error {
+	if err := throttle.backend.DeleteByIdentifier(emailIdentifier(email)); err != nil {
+		return err
+	}
+	return throttle.backend.DeleteByIdentifier(secondFactorIdentifier(userId))
+}
+
+// blocked returns how long the identifier is still blocked without counting
+// an attempt.
+func (throttle *LoginThrottle) blocked(identifier string, now time.Time) (time.Duration, error) {
+	attempt, err := throttle.backend.GetByIdentifier(identifier)
+	if err != nil || attempt == nil || attempt.BlockedUntil == nil || !attempt.BlockedUntil.After(now) {
+		return 0, err
+	}
+	return attempt.BlockedUntil.Sub(now), nil
+}
+
+// count returns how long the identifier is still blocked when refuseBlocked
+// is set, or counts an attempt and blocks the next one for the delay of its
+// number, or for the lockout once it reaches the limit, and returns the number
+// of the attempt. A longer block already set is kept. The attempt is compared
+// and swapped so the attempts of several instances are counted one after
+// another.
+func (throttle *LoginThrottle) count(identifier string, limit attemptLimit, now time.Time, refuseBlocked bool) (time.Duration, int, error) {
+	for i := 0; i < loginAttemptRetries; i++ {
+		attempt, err := throttle.backend.GetByIdentifier(identifier)
+		if err != nil {
+			return 0, 0, err
+		}
+		blocked := attempt != nil && attempt.BlockedUntil != nil && attempt.BlockedUntil.After(now)
+		if blocked && refuseBlocked {
+			return attempt.BlockedUntil.Sub(now), 0, nil
+		}
+
+		// Attempts older than the window are forgotten
+		failures := 0
+		if attempt != nil && !attempt.LastFailedAt.Before(now.Add(-throttle.policy.Window)) {
+			failures = attempt.Failures
+		}
+		next := &models.LoginAttempt{
+			Identifier:   identifier,
+			Failures:     failures + 1,
+			LastFailedAt: now,
+		}
+		var block time.Duration
+		if limit.delay != nil {
+			block = limit.delay(next.Failures)
+		}
+		if next.Failures >= limit.maxAttempts {
+			block = limit.lockout
+		}
+		if block > 0 {
+			blockedUntil := now.Add(block)
+			next.BlockedUntil = &blockedUntil
+		}
+		if blocked && (next.BlockedUntil == nil || attempt.BlockedUntil.After(*next.BlockedUntil)) {
+			next.BlockedUntil = attempt.BlockedUntil
+		}
+
+		var swapped bool
+		if attempt == nil {
+			swapped, err = throttle.backend.Create(next)
+		} else {
+			swapped, err = throttle.backend.Swap(next, attempt.Failures)
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		if swapped {
+			return 0, next.Failures, nil
+		}
+	}
+	return 0, 0, errLoginAttemptContended
+}
+
+// refund takes back an attempt counted by count and the delay it set, unless
+// the identifier has been locked out since. counted is the number count gave
+// the attempt, or 0 when it isn't known, while no other attempt has been
+// counted since the block is the one of the attempt, even a lockout.
+func (throttle *LoginThrottle) refund(identifier string, counted int, maxAttempts int) error {
+	for i := 0; i < loginAttemptRetries; i++ {
+		attempt, err := throttle.backend.GetByIdentifier(identifier)
+		if err != nil || attempt == nil || attempt.Failures == 0 {
+			return err
+		}
+
+		next := *attempt
+		next.Failures--
+		if attempt.Failures < maxAttempts || attempt.Failures == counted {
+			next.BlockedUntil = nil
+		}
+		swapped, err := throttle.backend.Swap(&next, attempt.Failures)
+		if err != nil || swapped {
+			return err
+		}
+	}
+	return errLoginAttemptContended
+}
+
+// Start removes the attempts that are neither remembered nor blocked anymore
+// every interval.
+func (throttle *LoginThrottle) Start(interval time.Duration) {
+	throttle.stop = make(chan struct{})
+	throttle.done = make(chan struct{})
+	go func() {
+		defer close(throttle.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-throttle.stop:
+				return
+			case <-ticker.C:
+				now := time.Now()
+				if _, err := throttle.backend.DeleteExpiredBefore(now.Add(-throttle.policy.Window), now); err != nil {
+					log.Printf("login throttle: %s", err.Error())
+				}
+			}
+		}
+	}()
+}
+
+func (throttle *LoginThrottle) Stop() {
+	if throttle.stop == nil {
+		return
+	}
+	close(throttle.stop)
+	<-throttle.done
+	throttle.stop = nil
+}
+
+// MemoryLoginAttempts keeps the attempts of a single instance, they are lost
+// on restart.
+type MemoryLoginAttempts struct {
+	mu       sync.Mutex
+	attempts map[string]models.LoginAttempt
+}
+
+func NewMemoryLoginAttempts() ILoginAttemptBackend {
+	return &MemoryLoginAttempts{
+		attempts: map[string]models.LoginAttempt{},
+	}
+}
+
+func (memory *MemoryLoginAttempts) GetByIdentifier(identifier string) (*models.LoginAttempt, error) {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+	attempt, ok := memory.attempts[identifier]
+	if !ok {
+		return nil, nil
+	}
+	return &attempt, nil
+}
+
+func (memory *MemoryLoginAttempts) Create(attempt *models.LoginAttempt) (bool, error) {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+	if _, ok := memory.attempts[attempt.Identifier]; ok {
+		return false, nil
+	}
+	memory.attempts[attempt.Identifier] = *attempt
+	return true, nil
+}
+
+func (memory *MemoryLoginAttempts) Swap(attempt *models.LoginAttempt, failures int) (bool, error) {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+	current, ok := memory.attempts[attempt.Identifier]
+	if !ok || current.Failures != failures {
+		return false, nil
+	}
+	memory.attempts[attempt.Identifier] = *attempt
+	return true, nil
+}
+
+func (memory *MemoryLoginAttempts) DeleteByIdentifier(identifier string) error {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+	delete(memory.attempts, identifier)
+	return nil
+}
+
+func (memory *MemoryLoginAttempts) DeleteExpiredBefore(lastFailedBefore time.Time, now time.Time) (int64, error) {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+	var deleted int64
+	for identifier, attempt := range memory.attempts {
+		if attempt.LastFailedAt.Before(lastFailedBefore) && (attempt.BlockedUntil == nil || attempt.BlockedUntil.Before(now)) {
+			delete(memory.attempts, identifier)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,120 @@
+package stores
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestLoginThrottle() ILoginThrottle {
+	return NewLoginThrottle(NewMemoryLoginAttempts(), LoginThrottlePolicy{
+		MaxAttempts:     3,
+		IPMaxAttempts:   10,
+		LockoutDuration: time.Hour,
+		BaseDelay:       time.Minute,
+		MaxDelay:        time.Minute,
+		Window:          time.Hour,
+	})
+}
+
+func TestLoginThrottleLetsOneOfParallelAttemptsThrough(t *testing.T) {
+	throttle := newTestLoginThrottle()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wait, err := throttle.Attempt("user@example.com", "10.0.0.1")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if wait == 0 {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != 1 {
+		t.Fatalf("expected a single attempt before the delay, got %d", allowed)
+	}
+}
+
+func TestLoginThrottleSuccessForgetsTheAttempts(t *testing.T) {
+	throttle := newTestLoginThrottle()
+
+	if wait, err := throttle.Attempt("user@example.com", "10.0.0.1"); err != nil || wait != 0 {
+		t.Fatalf("first attempt: wait %s, err %v", wait, err)
+	}
+	if err := throttle.Success("user@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if wait, err := throttle.Attempt("User@Example.com", "10.0.0.1"); err != nil || wait != 0 {
+		t.Fatalf("attempt after a success: wait %s, err %v", wait, err)
+	}
+}
+
+func TestLoginThrottleRefusedAttemptIsNotCharged(t *testing.T) {
+	throttle := newTestLoginThrottle()
+
+	if _, err := throttle.Attempt("user@example.com", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	// The email is blocked, the other address isn't charged for the refused attempt
+	if wait, err := throttle.Attempt("user@example.com", "10.0.0.2"); err != nil || wait == 0 {
+		t.Fatalf("expected the email to be blocked, wait %s, err %v", wait, err)
+	}
+	if wait, err := throttle.Attempt("other@example.com", "10.0.0.2"); err != nil || wait != 0 {
+		t.Fatalf("expected the address to be free, wait %s, err %v", wait, err)
+	}
+}
+
+func TestLoginThrottleOnlyBlocksAddressAfterFailure(t *testing.T) {
+	throttle := newTestLoginThrottle()
+
+	// Users behind the same address aren't slowed down by each other's logins
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		if wait, err := throttle.Attempt(email, "10.0.0.1"); err != nil || wait != 0 {
+			t.Fatalf("%s: wait %s, err %v", email, wait, err)
+		}
+	}
+
+	if err := throttle.Failure("10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if wait, err := throttle.Attempt("carol@example.com", "10.0.0.1"); err != nil || wait == 0 {
+		t.Fatalf("expected the address to be blocked after a failure, wait %s, err %v", wait, err)
+	}
+}
+
+func TestLoginThrottleLocksOutSecondFactor(t *testing.T) {
+	throttle := NewLoginThrottle(NewMemoryLoginAttempts(), LoginThrottlePolicy{
+		MaxAttempts:     3,
+		IPMaxAttempts:   10,
+		LockoutDuration: time.Hour,
+		Window:          time.Hour,
+	})
+
+	for i := 0; i < 3; i++ {
+		if wait, err := throttle.SecondFactorAttempt(1); err != nil || wait != 0 {
+			t.Fatalf("attempt %d: wait %s, err %v", i+1, wait, err)
+		}
+	}
+	wait, err := throttle.SecondFactorAttempt(1)
+	if err != nil || wait < 59*time.Minute {
+		t.Fatalf("expected a lockout, wait %s, err %v", wait, err)
+	}
+
+	if err := throttle.Unlock(1, "user@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if wait, err := throttle.SecondFactorAttempt(1); err != nil || wait != 0 {
+		t.Fatalf("attempt after unlock: wait %s, err %v", wait, err)
+	}
+}
@@ -0,0 +1,157 @@
+package stores
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+)
+
+type IRevocationStore interface {
+	Revoke(jti string, userId uint, expiresAt time.Time) error
+	RevokeAllForUser(userId uint) error
+	IsRevoked(jti string, userId uint, issuedAt time.Time) bool
+	Load() error
+	Start(interval time.Duration)
+	Stop()
+}
+
+// RevocationStore answers IsRevoked from memory so Guard doesn't hit the
+// database on every request. Revocations are written to the database first,
+// Load picks up the ones made by other instances or by the CLI, so those take
+// up to one sync interval to be enforced here.
+type RevocationStore struct {
+	model         models.ITokenRevocationModel
+	tokenLifetime time.Duration
+	leeway        time.Duration
+
+	mu           sync.RWMutex
+	tokens       map[string]time.Time
+	issuedBefore map[uint]time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewRevocationStore needs the lifetime of the access tokens to know how long
+// a "revoke every token of this user" entry has to be kept, and the leeway the
+// tokens are verified with since they're still accepted for that long after
+// they expire.
+func NewRevocationStore(model models.ITokenRevocationModel, tokenLifetime time.Duration, leeway time.Duration) IRevocationStore {
+	return &RevocationStore{
+		model:         model,
+		tokenLifetime: tokenLifetime,
+		leeway:        leeway,
+		tokens:        map[string]time.Time{},
+		issuedBefore:  map[uint]time.Time{},
+	}
+}
+
+func (store *RevocationStore) Revoke(jti string, userId uint, expiresAt time.Time) error {
+	if _, err := store.model.RevokeToken(jti, userId, expiresAt); err != nil {
+		return err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.tokens[jti] = expiresAt
+	return nil
+}
+
+// RevokeAllForUser revokes the tokens issued up to now. Tokens carry issuedAt
+// with a precision of one second, so every token of the current second is
+// revoked too, including one issued right after by a new login which then has
+// to be made again.
+func (store *RevocationStore) RevokeAllForUser(userId uint) error {
+	cutoff := time.Now()
+	if _, err := store.model.RevokeIssuedBefore(userId, cutoff, cutoff.Add(store.tokenLifetime)); err != nil {
+		return err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if cutoff.After(store.issuedBefore[userId]) {
+		store.issuedBefore[userId] = cutoff
+	}
+	return nil
+}
+
+func (store *RevocationStore) IsRevoked(jti string, userId uint, issuedAt time.Time) bool {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	if jti != "" {
+		if _, ok := store.tokens[jti]; ok {
+			return true
+		}
+	}
+	cutoff, ok := store.issuedBefore[userId]
+	return ok && !issuedAt.After(cutoff)
+}
+
+// Load adds the revocations that haven't expired yet to the cache and drops
+// the expired ones, a revocation expires once the leeway has passed after the
+// tokens it revokes. The cache isn't replaced, a revocation made here while
+// the database was being read would be lost until the next sync.
+func (store *RevocationStore) Load() error {
+	now := time.Now()
+	revocations, err := store.model.GetActive(now.Add(-store.leeway))
+	if err != nil {
+		return err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for _, revocation := range revocations {
+		if revocation.JTI != nil {
+			store.tokens[*revocation.JTI] = revocation.ExpiresAt
+			continue
+		}
+		if revocation.IssuedBefore != nil && revocation.IssuedBefore.After(store.issuedBefore[revocation.UserID]) {
+			store.issuedBefore[revocation.UserID] = *revocation.IssuedBefore
+		}
+	}
+
+	// Token yang dicabut telah kedaluwarsa, begitu juga seluruh token yang diterbitkan sebelum cutoff
+	for jti, expiresAt := range store.tokens {
+		if !expiresAt.Add(store.leeway).After(now) {
+			delete(store.tokens, jti)
+		}
+	}
+	for userId, cutoff := range store.issuedBefore {
+		if !cutoff.Add(store.tokenLifetime + store.leeway).After(now) {
+			delete(store.issuedBefore, userId)
+		}
+	}
+	return nil
+}
+
+func (store *RevocationStore) Start(interval time.Duration) {
+	store.stop = make(chan struct{})
+	store.done = make(chan struct{})
+	go func() {
+		defer close(store.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-store.stop:
+				return
+			case <-ticker.C:
+				if err := store.Load(); err != nil {
+					log.Printf("revocation store: %s", err.Error())
+				}
+			}
+		}
+	}()
+}
+
+func (store *RevocationStore) Stop() {
+	if store.stop == nil {
+		return
+	}
+	close(store.stop)
+	<-store.done
+	store.stop = nil
+}
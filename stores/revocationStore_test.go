@@ -0,0 +1,87 @@
+package stores
+
+import (
+	"testing"
+	"time"
+
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/database"
+	"github.com/thenewsatria/task-5-vix-btpns-rangga-adi/models"
+)
+
+// staticRevocations stands in for the database, GetActive returns what it
+// holds when Load reads it.
+type staticRevocations struct {
+	active []models.TokenRevocation
+}
+
+func (static *staticRevocations) WithDB(db database.IDatabase) models.ITokenRevocationModel {
+	return static
+}
+
+func (static *staticRevocations) RevokeToken(jti string, userId uint, expiresAt time.Time) (*models.TokenRevocation, error) {
+	return &models.TokenRevocation{JTI: &jti, UserID: userId, ExpiresAt: expiresAt}, nil
+}
+
+func (static *staticRevocations) RevokeIssuedBefore(userId uint, issuedBefore time.Time, expiresAt time.Time) (*models.TokenRevocation, error) {
+	return &models.TokenRevocation{UserID: userId, IssuedBefore: &issuedBefore, ExpiresAt: expiresAt}, nil
+}
+
+func (static *staticRevocations) GetActive(now time.Time) ([]models.TokenRevocation, error) {
+	return static.active, nil
+}
+
+func (static *staticRevocations) DeleteExpiredBefore(expiredBefore time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestRevokeAllRevokesTokensOfTheSameSecond(t *testing.T) {
+	store := NewRevocationStore(&staticRevocations{}, time.Hour, 0)
+	if err := store.RevokeAllForUser(1); err != nil {
+		t.Fatal(err)
+	}
+
+	// iat only has a precision of one second, a token issued earlier within the second has the second as iat
+	cutoff := store.(*RevocationStore).issuedBefore[1]
+	if !store.IsRevoked("", 1, cutoff.Truncate(time.Second)) {
+		t.Fatal("expected a token issued within the second of the revocation to be revoked")
+	}
+	if store.IsRevoked("", 1, cutoff.Truncate(time.Second).Add(time.Second)) {
+		t.Fatal("expected a token issued in the next second to be valid")
+	}
+}
+
+func TestLoadKeepsRevocationsMadeMeanwhile(t *testing.T) {
+	// The database is read before the tokens below are revoked
+	store := NewRevocationStore(&staticRevocations{}, time.Hour, 0)
+	if err := store.Revoke("local", 1, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Revoke("expired", 1, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if !store.IsRevoked("local", 1, time.Now()) {
+		t.Fatal("expected the revocation made before the sync to be kept")
+	}
+	if store.IsRevoked("expired", 1, time.Now()) {
+		t.Fatal("expected the expired revocation to be dropped")
+	}
+}
+
+func TestLoadKeepsRevocationsWithinTheLeeway(t *testing.T) {
+	store := NewRevocationStore(&staticRevocations{}, time.Hour, 30*time.Second)
+	// The token expired but is still accepted within the leeway
+	if err := store.Revoke("expired", 1, time.Now().Add(-10*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if !store.IsRevoked("expired", 1, time.Now()) {
+		t.Fatal("expected the revocation to be kept until the leeway has passed")
+	}
+}